@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/alifoo/blog-aggregator/internal/htmltext"
+	"github.com/alifoo/blog-aggregator/internal/readability"
+	"golang.org/x/net/html/charset"
+)
+
+// fetchArticle downloads the page at link and returns its main article as
+// sanitized HTML, for feeds that only publish teasers.
+func fetchArticle(ctx context.Context, f *fetcher, link string) (string, error) {
+	resp, err := f.get(ctx, link)
+	if err != nil {
+		return "", err
+	}
+	if resp.status < 200 || resp.status > 299 {
+		return "", networkError("error fetching article: %v\n unexpected status %v", link, resp.statusMsg)
+	}
+
+	page, err := charset.NewReader(bytes.NewReader(resp.body), resp.contentType)
+	if err != nil {
+		return "", fmt.Errorf("error decoding article: %v\n %w", link, err)
+	}
+	article, err := readability.Extract(page)
+	if err != nil {
+		return "", fmt.Errorf("error extracting article: %v\n %w", link, err)
+	}
+	return htmltext.Sanitize(article, link), nil
+}
+
+// storeArticle fetches the full article of a new post and stores it with
+// the post. Articles that cannot be fetched are reported and skipped, so
+// the post keeps only its description.
+func storeArticle(s *state, f *fetcher, post database.Post) error {
+	article, err := fetchArticle(context.Background(), f, post.Url)
+	if err != nil {
+		fmt.Printf("Could not fetch the full content of %v: %v\n", post.Title, err)
+		return nil
+	}
+
+	err = s.db.SetPostContent(context.Background(), database.SetPostContentParams{
+		ID:      post.ID,
+		Content: sql.NullString{String: article, Valid: true},
+	})
+	if err != nil {
+		return databaseError("error storing full content: %w", err)
+	}
+	fmt.Printf("Stored the full content of %v.\n", post.Title)
+	return nil
+}
+
+// postBody returns the HTML to read a post with: the full article if it
+// was fetched, its description otherwise.
+func postBody(p database.GetReaderItemsRow) string {
+	if p.Content.Valid {
+		return p.Content.String
+	}
+	return p.Description.String
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+)
+
+func TestFullContent(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+	url := server.feedURL("teaser.rss")
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Teaser", url)
+
+	_, err := runCommand(t, s, "fullcontent", url, "maybe")
+	assertKind(t, err, errValidation)
+	mustRun(t, s, "register", "bob")
+	_, err = runCommand(t, s, "fullcontent", url, "on")
+	assertKind(t, err, errPermission)
+
+	mustRun(t, s, "login", "alice")
+	mustRun(t, s, "fullcontent", url, "on")
+	if out := mustRun(t, s, "feedinfo", url); !strings.Contains(out, "full articles are fetched") {
+		t.Errorf("feedinfo does not show the option:\n%s", out)
+	}
+
+	// The missing article is reported without failing the fetch.
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+	if hits := server.hits("article.html"); hits != 1 {
+		t.Errorf("article was fetched %d times, want 1", hits)
+	}
+
+	user, err := s.db.GetUser(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := s.db.GetReaderItems(context.Background(), database.GetReaderItemsParams{UserID: user.ID, MaxItems: 10})
+	if err != nil || len(items) != 2 {
+		t.Fatalf("reader items: %+v, %v", items, err)
+	}
+	byTitle := map[string]database.GetReaderItemsRow{}
+	for _, item := range items {
+		byTitle[item.Title] = item
+	}
+
+	article := byTitle["A long read"]
+	if article.Url != server.feedURL("article.html") {
+		t.Errorf("relative post link was stored as %q", article.Url)
+	}
+	if !strings.Contains(article.Content.String, "without a connection") ||
+		!strings.Contains(article.Content.String, `src="`+server.feedURL("images/chart.png")+`"`) {
+		t.Errorf("full content is %q", article.Content.String)
+	}
+	if strings.Contains(article.Content.String, "<script") || strings.Contains(article.Content.String, "newsletter") {
+		t.Errorf("full content was not cleaned: %q", article.Content.String)
+	}
+	if got := postBody(article); got != article.Content.String {
+		t.Errorf("post body is the description %q", got)
+	}
+
+	missing := byTitle["A missing page"]
+	if missing.Content.Valid {
+		t.Errorf("missing page has content %q", missing.Content.String)
+	}
+	if got := postBody(missing); got != "This page does not exist." {
+		t.Errorf("post body without content is %q", got)
+	}
+
+	// Articles are only fetched for new posts.
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds again: %v", err)
+	}
+	if hits := server.hits("article.html"); hits != 1 {
+		t.Errorf("article was fetched %d times, want 1", hits)
+	}
+
+	mustRun(t, s, "fullcontent", url, "off")
+	feed, err := s.db.GetFeedByURL(context.Background(), url)
+	if err != nil || feed.FetchFullContent {
+		t.Errorf("feed after turning the option off: %+v, %v", feed, err)
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+type command struct {
+	name      string
+	arguments []string
+	flags     *flag.FlagSet
+}
+
+// commandSpec describes a registered command: how it is invoked, what it
+// does and how many positional arguments it takes once flags are parsed.
+type commandSpec struct {
+	name        string
+	usage       string
+	description string
+	minArgs     int
+	// maxArgs is the largest number of positional arguments accepted, or
+	// -1 for no limit.
+	maxArgs int
+	// setFlags declares the command's flags, if it has any.
+	setFlags func(fs *flag.FlagSet)
+	// complete returns shell completion candidates for the positional
+	// argument at the given index, if the command can suggest any.
+	complete func(s *state, position int) []string
+	// hidden commands are left out of help and completion.
+	hidden bool
+	// noSchemaCheck lets the command run before the database schema is
+	// up to date.
+	noSchemaCheck bool
+	// noDatabase commands never open the database, so they keep working
+	// when db_url is missing or wrong.
+	noDatabase bool
+	handler    func(*state, command) error
+}
+
+type commands struct {
+	handlers map[string]commandSpec
+}
+
+func (c *commands) register(spec commandSpec) {
+	c.handlers[spec.name] = spec
+}
+
+func (c *commands) run(s *state, cmd command) error {
+	spec, exists := c.handlers[cmd.name]
+	if !exists {
+		return validationError("command %s not found, run '%s help' for a list of commands", cmd.name, programName())
+	}
+
+	fs := spec.flagSet()
+	arguments, err := parseInterspersed(fs, cmd.arguments)
+	if errors.Is(err, flag.ErrHelp) {
+		return c.printCommandHelp(os.Stdout, spec)
+	}
+	if err != nil {
+		return validationError("%w, run '%s help %s' for usage", err, programName(), spec.name)
+	}
+
+	if len(arguments) < spec.minArgs || (spec.maxArgs >= 0 && len(arguments) > spec.maxArgs) {
+		return validationError("wrong number of arguments\nusage: %s %s", programName(), spec.usageLine())
+	}
+
+	if !spec.noDatabase {
+		if err := openDatabase(s); err != nil {
+			return err
+		}
+	}
+	if !spec.noDatabase && !spec.noSchemaCheck {
+		if err := checkSchema(s); err != nil {
+			return err
+		}
+	}
+
+	return spec.handler(s, command{
+		name:      cmd.name,
+		arguments: arguments,
+		flags:     fs,
+	})
+}
+
+// globalOptions are the options shared by every command, which may appear
+// anywhere on the command line.
+type globalOptions struct {
+	output     outputFormat
+	configPath string
+	profile    string
+}
+
+// parseGlobalOptions pulls the global options out of the raw arguments and
+// returns the remaining ones. Everything after a "--" is left alone.
+func parseGlobalOptions(args []string) (globalOptions, []string, error) {
+	opts := globalOptions{output: outputPlain}
+	targets := map[string]*string{}
+	var output string
+	for _, name := range []string{"--output", "-o"} {
+		targets[name] = &output
+	}
+	targets["--config"] = &opts.configPath
+	targets["--profile"] = &opts.profile
+
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		name, value, hasValue := strings.Cut(arg, "=")
+		target, isOption := targets[name]
+		if !isOption {
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return globalOptions{}, nil, validationError("%s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		*target = value
+	}
+
+	if output != "" {
+		format, err := parseOutputFormat(output)
+		if err != nil {
+			return globalOptions{}, nil, err
+		}
+		opts.output = format
+	}
+	return opts, rest, nil
+}
+
+func (spec commandSpec) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(spec.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if spec.setFlags != nil {
+		spec.setFlags(fs)
+	}
+	return fs
+}
+
+func (spec commandSpec) hasFlags() bool {
+	hasFlags := false
+	spec.flagSet().VisitAll(func(*flag.Flag) { hasFlags = true })
+	return hasFlags
+}
+
+func (spec commandSpec) usageLine() string {
+	parts := []string{spec.name}
+	if spec.hasFlags() {
+		parts = append(parts, "[flags]")
+	}
+	if spec.usage != "" {
+		parts = append(parts, spec.usage)
+	}
+	return strings.Join(parts, " ")
+}
+
+// parseInterspersed lets flags appear before, between or after positional
+// arguments, which the flag package alone does not allow. Everything after
+// a "--" is treated as positional.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			break
+		}
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			positional = append(positional, rest...)
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+	return positional, nil
+}
+
+func (cmd command) intFlag(name string) int {
+	return cmd.flags.Lookup(name).Value.(flag.Getter).Get().(int)
+}
+
+func (cmd command) stringFlag(name string) string {
+	return cmd.flags.Lookup(name).Value.(flag.Getter).Get().(string)
+}
+
+func (cmd command) boolFlag(name string) bool {
+	return cmd.flags.Lookup(name).Value.(flag.Getter).Get().(bool)
+}
+
+func (c *commands) handlerHelp(s *state, cmd command) error {
+	if len(cmd.arguments) > 0 {
+		spec, exists := c.handlers[cmd.arguments[0]]
+		if !exists {
+			return notFoundError("command %s not found, run '%s help' for a list of commands", cmd.arguments[0], programName())
+		}
+		return c.printCommandHelp(os.Stdout, spec)
+	}
+	return c.printHelp(os.Stdout)
+}
+
+func (c *commands) printHelp(w io.Writer) error {
+	fmt.Fprintf(w, "Usage: %s [--output table|json|csv|plain] [--config path] [--profile name] <command> [arguments]\n\n", programName())
+	fmt.Fprintln(w, "Commands:")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, name := range c.visibleNames() {
+		fmt.Fprintf(tw, "  %s\t%s\n", name, c.handlers[name].description)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nRun '%s help <command>' for details on a command.\n", programName())
+	return nil
+}
+
+func (c *commands) printCommandHelp(w io.Writer, spec commandSpec) error {
+	fmt.Fprintf(w, "Usage: %s %s\n\n%s\n", programName(), spec.usageLine(), spec.description)
+
+	if spec.hasFlags() {
+		fs := spec.flagSet()
+		fs.SetOutput(w)
+		fmt.Fprintln(w, "\nFlags:")
+		fs.PrintDefaults()
+	}
+	return nil
+}
+
+func (c *commands) names() []string {
+	names := make([]string, 0, len(c.handlers))
+	for name := range c.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func programName() string {
+	return filepath.Base(os.Args[0])
+}
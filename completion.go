@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var durationExamples = []string{"30s", "1m", "5m", "15m", "30m", "1h"}
+
+var completionShells = []string{"bash", "zsh", "fish"}
+
+func (c *commands) handlerCompletion(s *state, cmd command) error {
+	switch shell := cmd.arguments[0]; shell {
+	case "bash":
+		return c.writeBashCompletion(os.Stdout)
+	case "zsh":
+		return c.writeZshCompletion(os.Stdout)
+	case "fish":
+		return c.writeFishCompletion(os.Stdout)
+	default:
+		return validationError("unknown shell %v, expected one of: %v", shell, strings.Join(completionShells, ", "))
+	}
+}
+
+// handlerComplete is called by the generated scripts with the command name,
+// the words typed so far and the word being completed, and prints matching
+// candidates one per line. Lookup failures print nothing, so a missing
+// database never breaks the user's shell.
+//
+// It runs without a database so that command names and flags complete
+// anyway, and only opens it for the candidates of commands that need one.
+func (c *commands) handlerComplete(s *state, cmd command) error {
+	words := cmd.arguments
+	if len(words) == 0 {
+		return nil
+	}
+	current := words[len(words)-1]
+
+	var candidates []string
+	if len(words) == 1 {
+		candidates = c.visibleNames()
+	} else if spec, exists := c.handlers[words[0]]; exists {
+		if strings.HasPrefix(current, "-") {
+			spec.flagSet().VisitAll(func(f *flag.Flag) {
+				candidates = append(candidates, "--"+f.Name)
+			})
+		} else if spec.complete != nil && (spec.noDatabase || openDatabase(s) == nil) {
+			candidates = spec.complete(s, countPositional(spec.flagSet(), words[1:len(words)-1]))
+		}
+	}
+
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) {
+			fmt.Println(candidate)
+		}
+	}
+	return nil
+}
+
+// countPositional counts the positional arguments among words, skipping
+// flags and the values of flags that take one.
+func countPositional(fs *flag.FlagSet, words []string) int {
+	position := 0
+	for i := 0; i < len(words); i++ {
+		if !strings.HasPrefix(words[i], "-") {
+			position++
+			continue
+		}
+		name := strings.TrimLeft(words[i], "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		if f := fs.Lookup(name); f != nil {
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+				i++
+			}
+		}
+	}
+	return position
+}
+
+func completeUsernames(s *state, position int) []string {
+	if position > 0 {
+		return nil
+	}
+	users, err := s.db.GetUsers(context.Background())
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, u := range users {
+		names = append(names, u.Name)
+	}
+	return names
+}
+
+func completeFeedURLs(s *state, position int) []string {
+	if position > 0 {
+		return nil
+	}
+	feeds, err := s.db.GetFeeds(context.Background())
+	if err != nil {
+		return nil
+	}
+	var urls []string
+	for _, f := range feeds {
+		urls = append(urls, f.Url)
+	}
+	return urls
+}
+
+func completeFollowedFeedURLs(s *state, position int) []string {
+	if position > 0 {
+		return nil
+	}
+	user, err := s.db.GetUser(context.Background(), s.configPointer.CurrentUserName)
+	if err != nil {
+		return nil
+	}
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return nil
+	}
+	var urls []string
+	for _, f := range follows {
+		urls = append(urls, f.FeedUrl)
+	}
+	return urls
+}
+
+// completeWords completes the first positional argument from a fixed list.
+func completeWords(words ...string) func(*state, int) []string {
+	return func(s *state, position int) []string {
+		if position > 0 {
+			return nil
+		}
+		return words
+	}
+}
+
+func (c *commands) visibleNames() []string {
+	var names []string
+	for _, name := range c.names() {
+		if !c.handlers[name].hidden {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+var nonIdentifier = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// completionFunction is the shell function name used for the binary, which
+// may contain characters such as '-' that are not valid in identifiers.
+func completionFunction() string {
+	return "_" + nonIdentifier.ReplaceAllString(programName(), "_")
+}
+
+func (c *commands) writeBashCompletion(w io.Writer) error {
+	name := programName()
+	fn := completionFunction()
+
+	_, err := fmt.Fprintf(w, `# bash completion for %[1]s
+# Load it with: source <(%[1]s completion bash)
+
+%[2]s() {
+    # URLs contain ':', which bash splits words on, so words are taken
+    # from bash-completion with ':' kept, or split on blanks by hand.
+    local cur words cword
+    if declare -F _get_comp_words_by_ref >/dev/null; then
+        _get_comp_words_by_ref -n : cur words cword
+    else
+        local line="${COMP_LINE:0:COMP_POINT}"
+        read -ra words <<< "$line"
+        if [[ -z "$line" || "$line" == *[[:blank:]] ]]; then
+            words+=("")
+        fi
+        cword=$(( ${#words[@]} - 1 ))
+        cur="${words[cword]}"
+    fi
+
+    if [ "$cword" -eq 1 ]; then
+        COMPREPLY=( $(compgen -W "%[3]s" -- "$cur") )
+        return
+    fi
+
+    local IFS=$'\n'
+    COMPREPLY=( $(%[1]s __complete -- "${words[@]:1:cword-1}" "$cur" 2>/dev/null) )
+    if declare -F __ltrim_colon_completions >/dev/null; then
+        __ltrim_colon_completions "$cur"
+    elif [[ "$cur" == *:* && "$COMP_WORDBREAKS" == *:* ]]; then
+        local prefix="${cur%%"${cur##*:}"}" i
+        for i in "${!COMPREPLY[@]}"; do
+            COMPREPLY[i]="${COMPREPLY[i]#"$prefix"}"
+        done
+    fi
+}
+
+complete -o default -F %[2]s %[1]s
+`, name, fn, strings.Join(c.visibleNames(), " "))
+	return err
+}
+
+func (c *commands) writeZshCompletion(w io.Writer) error {
+	name := programName()
+	fn := completionFunction()
+
+	var described []string
+	for _, cmd := range c.visibleNames() {
+		described = append(described, fmt.Sprintf("        %s", shellQuote(cmd+":"+c.handlers[cmd].description)))
+	}
+
+	_, err := fmt.Fprintf(w, `#compdef %[1]s
+# zsh completion for %[1]s
+# Load it with: source <(%[1]s completion zsh)
+
+%[2]s() {
+    local -a commands candidates
+    commands=(
+%[3]s
+    )
+
+    if (( CURRENT == 2 )); then
+        _describe 'command' commands
+        return
+    fi
+
+    candidates=(${(f)"$(%[1]s __complete -- ${words[2,CURRENT-1]} "${words[CURRENT]}" 2>/dev/null)"})
+    if (( ${#candidates} )); then
+        compadd -a candidates
+    else
+        _files
+    fi
+}
+
+compdef %[2]s %[1]s
+`, name, fn, strings.Join(described, "\n"))
+	return err
+}
+
+func (c *commands) writeFishCompletion(w io.Writer) error {
+	name := programName()
+
+	fmt.Fprintf(w, "# fish completion for %[1]s\n# Load it with: %[1]s completion fish | source\n\n", name)
+	fmt.Fprintf(w, "complete -c %s -f\n", name)
+	for _, cmd := range c.visibleNames() {
+		_, err := fmt.Fprintf(w, "complete -c %s -n __fish_use_subcommand -a %s -d %s\n",
+			name, cmd, fishQuote(c.handlers[cmd].description))
+		if err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "complete -c %[1]s -n 'not __fish_use_subcommand' -a '(%[1]s __complete -- (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)'\n", name)
+	return err
+}
+
+// shellQuote wraps s in single quotes for bash and zsh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote wraps s in single quotes for fish, which escapes quotes inside
+// them with a backslash instead of closing and reopening the string.
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "addfeed", "Example", "https://example.com/rss")
+	mustRun(t, s, "addfeed", "Other", "https://other.example.org/feed.xml")
+
+	for _, c := range []struct {
+		words []string
+		want  []string
+	}{
+		{[]string{"fol"}, []string{"folders", "follow", "following"}},
+		{[]string{"__comp"}, nil},
+		{[]string{"login", ""}, []string{"alice", "bob"}},
+		{[]string{"login", "b"}, []string{"bob"}},
+		{[]string{"login", "alice", ""}, nil},
+		{[]string{"follow", "https://ex"}, []string{"https://example.com/rss"}},
+		{[]string{"fullcontent", "https://example.com/rss", ""}, []string{"on", "off"}},
+		{[]string{"deletefeed", "--yes", "https://o"}, []string{"https://other.example.org/feed.xml"}},
+		{[]string{"deletefeed", "--y"}, []string{"--yes"}},
+		{[]string{"agg", ""}, durationExamples},
+		{[]string{"completion", "z"}, []string{"zsh"}},
+		{[]string{"nope", ""}, nil},
+	} {
+		out := mustRun(t, s, append([]string{"__complete", "--"}, c.words...)...)
+		got := strings.Fields(out)
+		if strings.Join(got, " ") != strings.Join(c.want, " ") {
+			t.Errorf("completing %q: got %v, want %v", c.words, got, c.want)
+		}
+	}
+}
+
+func TestCompletionScripts(t *testing.T) {
+	s := newTestState(t)
+	name := programName()
+
+	for shell, want := range map[string][]string{
+		"bash": {"_get_comp_words_by_ref -n : cur words cword", `"${words[@]:1:cword-1}"`, `__ltrim_colon_completions "$cur"`, "complete -o default -F " + completionFunction() + " " + name},
+		"zsh":  {"#compdef " + name, "compdef " + completionFunction() + " " + name, "'follow:Follow an existing feed.'"},
+		"fish": {"complete -c " + name + " -n __fish_use_subcommand -a follow -d 'Follow an existing feed.'", name + " __complete --"},
+	} {
+		out := mustRun(t, s, "completion", shell)
+		for _, w := range want {
+			if !strings.Contains(out, w) {
+				t.Errorf("%v completion lacks %q:\n%s", shell, w, out)
+			}
+		}
+		if strings.Contains(out, "__complete -- __complete") || strings.Contains(out, "%!") {
+			t.Errorf("%v completion is malformed:\n%s", shell, out)
+		}
+	}
+	_, err := runCommand(t, s, "completion", "powershell")
+	assertKind(t, err, errValidation)
+}
+
+// TestBashCompletionColons runs the bash script without bash-completion
+// loaded, with a stand-in for the binary, and checks a URL is passed whole
+// and completed relative to the part after its last colon.
+func TestBashCompletionColons(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not installed")
+	}
+
+	var script bytes.Buffer
+	if err := newCommands().writeBashCompletion(&script); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	args := filepath.Join(dir, "args")
+	line := programName() + " follow --yes https://exa"
+	script.WriteString(`
+` + programName() + `() {
+    printf '%s\n' "$@" > '` + args + `'
+    echo https://example.com/rss
+}
+COMP_LINE='` + line + `'
+COMP_POINT=${#COMP_LINE}
+` + completionFunction() + `
+printf '%s\n' "${COMPREPLY[@]}"
+`)
+	path := filepath.Join(dir, "completion.bash")
+	if err := os.WriteFile(path, script.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command(bash, path).CombinedOutput()
+	if err != nil {
+		t.Fatalf("bash: %v\n%s", err, out)
+	}
+	if got := strings.TrimSpace(string(out)); got != "//example.com/rss" {
+		t.Errorf("COMPREPLY is %q, want the candidate without its scheme", got)
+	}
+	b, err := os.ReadFile(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Fields(string(b)); strings.Join(got, " ") != "__complete -- follow --yes https://exa" {
+		t.Errorf("__complete was called with %q", got)
+	}
+}
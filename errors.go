@@ -0,0 +1,148 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/alifoo/blog-aggregator/internal/storage"
+)
+
+// errorKind classifies command failures so that main can report them with a
+// matching exit code instead of handlers exiting on their own.
+type errorKind int
+
+const (
+	errGeneral errorKind = iota
+	errValidation
+	errNotFound
+	errConflict
+	errDatabase
+	errNetwork
+	errPermission
+)
+
+// Exit codes returned by the binary for each kind of error.
+const (
+	exitGeneral    = 1
+	exitValidation = 2
+	exitNotFound   = 3
+	exitConflict   = 4
+	exitDatabase   = 5
+	exitNetwork    = 6
+	exitPermission = 7
+)
+
+type kindError struct {
+	kind errorKind
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+func newKindError(kind errorKind, format string, args ...any) error {
+	return &kindError{kind: kind, err: fmt.Errorf(format, args...)}
+}
+
+// validationError reports bad input: wrong arguments, flags or values.
+func validationError(format string, args ...any) error {
+	return newKindError(errValidation, format, args...)
+}
+
+// notFoundError reports that a user, feed or post does not exist.
+func notFoundError(format string, args ...any) error {
+	return newKindError(errNotFound, format, args...)
+}
+
+// conflictError reports that something being created already exists.
+func conflictError(format string, args ...any) error {
+	return newKindError(errConflict, format, args...)
+}
+
+// databaseError reports that the database could not be queried.
+func databaseError(format string, args ...any) error {
+	return newKindError(errDatabase, format, args...)
+}
+
+// networkError reports that a remote server could not be reached.
+func networkError(format string, args ...any) error {
+	return newKindError(errNetwork, format, args...)
+}
+
+// permissionError reports that the current user may not change something
+// that belongs to another user.
+func permissionError(format string, args ...any) error {
+	return newKindError(errPermission, format, args...)
+}
+
+// kindOf returns the kind of err, classifying well-known errors that were
+// returned without one.
+func kindOf(err error) errorKind {
+	var ke *kindError
+	if errors.As(err, &ke) {
+		return ke.kind
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return errNotFound
+	}
+	if storage.IsUniqueViolation(err) {
+		return errConflict
+	}
+	if storage.IsDriverError(err) {
+		return errDatabase
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return errNetwork
+	}
+	return errGeneral
+}
+
+func (k errorKind) exitCode() int {
+	switch k {
+	case errValidation:
+		return exitValidation
+	case errNotFound:
+		return exitNotFound
+	case errConflict:
+		return exitConflict
+	case errDatabase:
+		return exitDatabase
+	case errNetwork:
+		return exitNetwork
+	case errPermission:
+		return exitPermission
+	}
+	return exitGeneral
+}
+
+// hint is an extra line telling the user what to check for kinds of errors
+// whose cause is usually outside gator.
+func (k errorKind) hint() string {
+	switch k {
+	case errDatabase:
+		return fmt.Sprintf("Check that the database is reachable and that db_url is correct (see '%s config list').", programName())
+	case errNetwork:
+		return "Check your network connection and that the feed URL is reachable."
+	}
+	return ""
+}
+
+// exitWithError prints err for the user and exits with the code matching its
+// kind. It is only called from main, after deferred cleanup has run.
+func exitWithError(err error) {
+	kind := kindOf(err)
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	if hint := kind.hint(); hint != "" {
+		fmt.Fprintln(os.Stderr, hint)
+	}
+	os.Exit(kind.exitCode())
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+)
+
+// pubDateLayouts are the date formats accepted for posts: RSS uses RFC 1123
+// dates, with a numeric or named zone, and Atom uses RFC 3339.
+var pubDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+}
+
+// parseFeed decodes an RSS or Atom document. Atom feeds are converted to
+// the RSS structure the rest of gator works with. The document is first
+// converted to UTF-8 from the charset named by its byte order mark, the
+// Content-Type header or its XML declaration. Documents that are not
+// well-formed are repaired and parsed again leniently before giving up.
+func parseFeed(body []byte, contentType string) (*RSSFeed, error) {
+	body = toUTF8(body, contentType)
+
+	feed, err := decodeFeed(body, true)
+	if err == nil {
+		return feed, nil
+	}
+	if repaired, lenientErr := decodeFeed(repairXML(body), false); lenientErr == nil {
+		return repaired, nil
+	}
+	return nil, err
+}
+
+func decodeFeed(body []byte, strict bool) (*RSSFeed, error) {
+	root, err := rootElement(body, strict)
+	if err != nil {
+		return nil, err
+	}
+
+	switch root.Local {
+	case "rss":
+		var rssFeed RSSFeed
+		if err := newXMLDecoder(body, strict).Decode(&rssFeed); err != nil {
+			return nil, err
+		}
+		return &rssFeed, nil
+	case "feed":
+		var atomFeed AtomFeed
+		if err := newXMLDecoder(body, strict).Decode(&atomFeed); err != nil {
+			return nil, err
+		}
+		return rssFromAtom(atomFeed), nil
+	}
+	return nil, fmt.Errorf("unsupported feed format <%v>, expected RSS or Atom", root.Local)
+}
+
+// newXMLDecoder returns a decoder for a document already converted to
+// UTF-8. A lenient decoder also accepts HTML entities such as &nbsp; and
+// unquoted attributes. HTML auto-closing is not enabled because it treats
+// <link>, which RSS uses for the item URL, as an empty element.
+func newXMLDecoder(body []byte, strict bool) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	// The XML declaration may still name the original charset.
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	if !strict {
+		decoder.Strict = false
+		decoder.Entity = xml.HTMLEntity
+	}
+	return decoder
+}
+
+func rootElement(body []byte, strict bool) (xml.Name, error) {
+	decoder := newXMLDecoder(body, strict)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return xml.Name{}, errors.New("document has no root element")
+		}
+		if err != nil {
+			return xml.Name{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+
+	xmlEncoding = regexp.MustCompile(`^\s*<\?xml[^>]*\sencoding\s*=\s*["']([A-Za-z0-9._:-]+)["']`)
+)
+
+// toUTF8 converts body to UTF-8. The charset is taken from the byte order
+// mark, then the charset parameter of contentType, then the encoding in
+// the XML declaration. Unknown charsets leave body as it is.
+func toUTF8(body []byte, contentType string) []byte {
+	var label string
+	switch {
+	case bytes.HasPrefix(body, utf8BOM):
+		return body[len(utf8BOM):]
+	case bytes.HasPrefix(body, utf16BEBOM):
+		label = "utf-16be"
+	case bytes.HasPrefix(body, utf16LEBOM):
+		label = "utf-16le"
+	}
+	if label == "" {
+		if _, params, err := mime.ParseMediaType(contentType); err == nil {
+			label = params["charset"]
+		}
+	}
+	if label == "" {
+		if match := xmlEncoding.FindSubmatch(body[:min(len(body), 1024)]); match != nil {
+			label = string(match[1])
+		}
+	}
+
+	if label == "" || strings.EqualFold(label, "utf-8") {
+		return body
+	}
+	reader, err := charset.NewReaderLabel(label, bytes.NewReader(body))
+	if err != nil {
+		return body
+	}
+	converted, err := io.ReadAll(reader)
+	if err != nil {
+		return body
+	}
+	return bytes.TrimPrefix(converted, utf8BOM)
+}
+
+// entityRef matches the references XML allows after an ampersand.
+var entityRef = regexp.MustCompile(`^&(#[0-9]+|#[xX][0-9a-fA-F]+|[A-Za-z_][A-Za-z0-9._-]*);`)
+
+// repairXML fixes the mistakes that most often make feeds unparsable:
+// invalid UTF-8, control characters XML forbids and ampersands that do not
+// start an entity reference. CDATA sections are left untouched.
+func repairXML(body []byte) []byte {
+	text := strings.ToValidUTF8(string(body), "\uFFFD")
+
+	var out strings.Builder
+	out.Grow(len(text))
+	for i := 0; i < len(text); {
+		if strings.HasPrefix(text[i:], "<![CDATA[") {
+			end := strings.Index(text[i:], "]]>")
+			if end < 0 {
+				end = len(text) - i
+			} else {
+				end += len("]]>")
+			}
+			out.WriteString(text[i : i+end])
+			i += end
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == '&' && !entityRef.MatchString(text[i:]):
+			out.WriteString("&amp;")
+		case !isXMLChar(r):
+		default:
+			out.WriteString(text[i : i+size])
+		}
+		i += size
+	}
+	return []byte(out.String())
+}
+
+// isXMLChar reports whether r may appear in an XML 1.0 document.
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= 0x10FFFF
+}
+
+func rssFromAtom(atomFeed AtomFeed) *RSSFeed {
+	var feed RSSFeed
+	feed.Channel.Title = atomFeed.Title
+	feed.Channel.Link = atomLink(atomFeed.Link)
+
+	for _, entry := range atomFeed.Entry {
+		description := entry.Summary.Body
+		if description == "" && entry.Content != nil {
+			description = entry.Content.Body
+		}
+		published := entry.Published
+		if published == "" {
+			published = entry.Updated
+		}
+
+		// Entries without an author are written by the author of the feed.
+		author := atomFeed.Author.Name
+		if entry.Author != nil && entry.Author.Name != "" {
+			author = entry.Author.Name
+		}
+		var categories []string
+		for _, category := range entry.Category {
+			categories = append(categories, category.Term)
+		}
+
+		feed.Channel.Item = append(feed.Channel.Item, RSSItem{
+			Title:       entry.Title,
+			Link:        atomLink(entry.Link),
+			Description: description,
+			PubDate:     published,
+			Creator:     author,
+			Category:    categories,
+		})
+	}
+	return &feed
+}
+
+// atomLink returns the alternate link, the one pointing at the web page.
+func atomLink(links []AtomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+func parsePubDate(value string) (time.Time, error) {
+	var err error
+	for _, layout := range pubDateLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	body, err := os.ReadFile(filepath.Join("testdata", "feeds", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestParseFeedCharsets(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		contentType string
+		title       string
+		description string
+	}{
+		{"latin1.rss", "application/rss+xml", "Café à Paris", "Crème brûlée"},
+		{"shift_jis.rss", "", "日本語のブログ", "こんにちは"},
+		// The header wins over the declaration, which claims UTF-8.
+		{"windows1252.rss", "text/xml; charset=windows-1252", "Smart “quotes”", "Price: 5€"},
+		{"example.rss", "text/xml; charset=UTF-8", "Example & Co Blog", "Posts from the example blog"},
+	}
+	for _, tt := range tests {
+		feed, err := parseFeed(readFixture(t, tt.fixture), tt.contentType)
+		if err != nil {
+			t.Errorf("%v: %v", tt.fixture, err)
+			continue
+		}
+		if feed.Channel.Title != tt.title || feed.Channel.Description != tt.description {
+			t.Errorf("%v: got title %q and description %q, want %q and %q",
+				tt.fixture, feed.Channel.Title, feed.Channel.Description, tt.title, tt.description)
+		}
+	}
+}
+
+func TestParseFeedRepairsMalformedXML(t *testing.T) {
+	feed, err := parseFeed(readFixture(t, "malformed.rss"), "")
+	if err != nil {
+		t.Fatalf("malformed feed was not repaired: %v", err)
+	}
+
+	if got, want := feed.Channel.Title, "Tom & Jerry's blog"; got != want {
+		t.Errorf("title is %q, want %q", got, want)
+	}
+	if got, want := feed.Channel.Link, "https://example.com/?a=1&b=2"; got != want {
+		t.Errorf("link is %q, want %q", got, want)
+	}
+	if got, want := feed.Channel.Description, "Cats & mice"; got != want {
+		t.Errorf("description is %q, want %q", got, want)
+	}
+	if len(feed.Channel.Item) != 1 {
+		t.Fatalf("got %d items, want 1", len(feed.Channel.Item))
+	}
+	item := feed.Channel.Item[0]
+	if item.Title != "Q&A time" || item.Link != "https://example.com/qa?x=1&y=2" {
+		t.Errorf("item is %+v", item)
+	}
+	if want := "Keep <b>this</b> & that"; item.Description != want {
+		t.Errorf("CDATA description is %q, want %q", item.Description, want)
+	}
+}
+
+func TestParseFeedAuthorsAndCategories(t *testing.T) {
+	tests := []struct {
+		fixture    string
+		authors    []string
+		categories [][]string
+	}{
+		// dc:creator wins over the email address in author.
+		{"categories.rss", []string{"Jane Doe", "ads@tagged.example.com (Ads Team)", "John Roe"},
+			[][]string{{"Releases", "Go"}, {"Sponsored"}, nil}},
+		// Entries without an author are written by the author of the feed.
+		{"example.atom", []string{"Ann Author", "Atom Staff"}, [][]string{{"news", "atom"}, nil}},
+	}
+	for _, tt := range tests {
+		feed, err := parseFeed(readFixture(t, tt.fixture), "")
+		if err != nil {
+			t.Errorf("%v: %v", tt.fixture, err)
+			continue
+		}
+		if len(feed.Channel.Item) != len(tt.authors) {
+			t.Errorf("%v: got %d items, want %d", tt.fixture, len(feed.Channel.Item), len(tt.authors))
+			continue
+		}
+		for i, item := range feed.Channel.Item {
+			if got := item.author(); got != tt.authors[i] {
+				t.Errorf("%v: author of %q is %q, want %q", tt.fixture, item.Title, got, tt.authors[i])
+			}
+			if !slices.Equal(item.Category, tt.categories[i]) {
+				t.Errorf("%v: categories of %q are %q, want %q", tt.fixture, item.Title, item.Category, tt.categories[i])
+			}
+		}
+	}
+}
+
+func TestToUTF8ByteOrderMarks(t *testing.T) {
+	tests := []struct {
+		name string
+		body []byte
+	}{
+		{"utf-8", []byte("\xEF\xBB\xBF<a>é</a>")},
+		{"utf-16be", []byte("\xFE\xFF\x00<\x00a\x00>\x00\xE9\x00<\x00/\x00a\x00>")},
+		{"utf-16le", []byte("\xFF\xFE<\x00a\x00>\x00\xE9\x00<\x00/\x00a\x00>\x00")},
+	}
+	for _, tt := range tests {
+		// The byte order mark wins over a wrong Content-Type charset.
+		if got := string(toUTF8(tt.body, "text/xml; charset=iso-8859-1")); got != "<a>é</a>" {
+			t.Errorf("%v: got %q", tt.name, got)
+		}
+	}
+}
+
+func TestRepairXML(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"<a>AT&T &amp; &#38; &#x26; &nbsp;</a>", "<a>AT&amp;T &amp; &#38; &#x26; &nbsp;</a>"},
+		{"<a>bell\x07 tab\t</a>", "<a>bell tab\t</a>"},
+		{"<a>\xff</a>", "<a>\uFFFD</a>"},
+		{"<a><![CDATA[a & b]]> & c</a>", "<a><![CDATA[a & b]]> &amp; c</a>"},
+	}
+	for _, tt := range tests {
+		if got := string(repairXML([]byte(tt.in))); got != tt.want {
+			t.Errorf("repairXML(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseFeedErrors(t *testing.T) {
+	for _, body := range []string{
+		"",
+		"not xml at all",
+		`<?xml version="1.0"?><html><body>Not a feed</body></html>`,
+	} {
+		if _, err := parseFeed([]byte(body), ""); err == nil {
+			t.Errorf("parseFeed(%q) succeeded", body)
+		}
+	}
+}
+
+func TestParsePubDate(t *testing.T) {
+	want := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	for _, value := range []string{
+		"Mon, 06 Jan 2025 09:00:00 +0000",
+		"Mon, 06 Jan 2025 09:00:00 UTC",
+		"2025-01-06T09:00:00Z",
+	} {
+		got, err := parsePubDate(value)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parsePubDate(%q) = %v, %v", value, got, err)
+		}
+	}
+	if _, err := parsePubDate("yesterday"); err == nil {
+		t.Error("parsePubDate accepted an invalid date")
+	}
+}
+
+func TestScrapeUsesContentTypeCharset(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+	server.handle("cp1252.rss", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml; charset=windows-1252")
+		w.Write(readFixture(t, "windows1252.rss"))
+	})
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Quotes", server.feedURL("cp1252.rss"))
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+
+	user, err := s.db.GetUser(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := s.db.GetReaderItems(context.Background(), database.GetReaderItemsParams{UserID: user.ID, MaxItems: 10})
+	if err != nil || len(items) != 1 {
+		t.Fatalf("reader items: %+v, %v", items, err)
+	}
+	if items[0].Title != "Smart “quotes”" {
+		t.Errorf("post title is %q", items[0].Title)
+	}
+}
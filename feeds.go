@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/alifoo/blog-aggregator/internal/storage"
+	"github.com/google/uuid"
+)
+
+// Feed health as shown by feedinfo.
+const (
+	feedHealthy      = "ok"
+	feedFailing      = "failing"
+	feedNeverFetched = "never fetched"
+	// feedDead feeds answered 410 Gone and are not fetched any more.
+	feedDead = "dead"
+)
+
+func handlerRenameFeed(s *state, cmd command, user database.User) error {
+	feed, err := getOwnFeed(s, user, cmd.arguments[0], "rename")
+	if err != nil {
+		return err
+	}
+
+	renamed, err := s.db.RenameFeed(context.Background(), database.RenameFeedParams{ID: feed.ID, Name: cmd.arguments[1]})
+	if err != nil {
+		return databaseError("error renaming feed: %w", err)
+	}
+	fmt.Printf("Renamed feed %v to %v.\n", feed.Name, renamed.Name)
+	return nil
+}
+
+func handlerSetFeedURL(s *state, cmd command, user database.User) error {
+	newURL := cmd.arguments[1]
+	if err := checkFeedURL(newURL); err != nil {
+		return err
+	}
+
+	feed, err := getOwnFeed(s, user, cmd.arguments[0], "change")
+	if err != nil {
+		return err
+	}
+	if feed.Url == newURL {
+		fmt.Printf("Feed %v already has url %v.\n", feed.Name, newURL)
+		return nil
+	}
+
+	_, err = s.db.SetFeedURL(context.Background(), database.SetFeedURLParams{ID: feed.ID, Url: newURL})
+	if storage.IsUniqueViolation(err) {
+		return conflictError("a feed with url %v already exists", newURL)
+	}
+	if err != nil {
+		return databaseError("error changing feed url: %w", err)
+	}
+	fmt.Printf("Feed %v now fetches from %v.\n", feed.Name, newURL)
+	return nil
+}
+
+// handlerFullContent turns fetching the full article of new posts on or
+// off for a feed whose items only carry a teaser.
+func handlerFullContent(s *state, cmd command, user database.User) error {
+	var enabled bool
+	switch cmd.arguments[1] {
+	case "on":
+		enabled = true
+	case "off":
+	default:
+		return validationError("invalid value %v, expected on or off", cmd.arguments[1])
+	}
+
+	feed, err := getOwnFeed(s, user, cmd.arguments[0], "change")
+	if err != nil {
+		return err
+	}
+	_, err = s.db.SetFeedFullContent(context.Background(), database.SetFeedFullContentParams{ID: feed.ID, FetchFullContent: enabled})
+	if err != nil {
+		return databaseError("error changing feed option: %w", err)
+	}
+
+	if enabled {
+		fmt.Printf("The full content of new posts of %v will be fetched.\n", feed.Name)
+	} else {
+		fmt.Printf("Only the feed content of new posts of %v will be stored.\n", feed.Name)
+	}
+	return nil
+}
+
+// handlerDeleteFeed deletes a feed with its follows and posts, which cannot
+// be read any more once the feed is gone.
+func handlerDeleteFeed(s *state, cmd command, user database.User) error {
+	feed, err := getOwnFeed(s, user, cmd.arguments[0], "delete")
+	if err != nil {
+		return err
+	}
+	stats, err := s.db.GetFeedStats(context.Background(), feed.ID)
+	if err != nil {
+		return databaseError("error counting what to delete: %w", err)
+	}
+
+	plan := resetPlan{
+		target: "feed " + feed.Name,
+		counts: []resetCount{
+			{1, "feed"},
+			{stats.Followers, "follow"},
+			{stats.Posts, "post"},
+		},
+		run: func(ctx context.Context) error {
+			return s.db.DeleteFeed(ctx, feed.ID)
+		},
+	}
+	// Kept posts lose their feed, so they are no longer listed, until the
+	// feed is added again and takes them back with their read and starred
+	// states.
+	if cmd.boolFlag("keep-posts") {
+		plan.target += " (keeping its posts)"
+		plan.counts = plan.counts[:2]
+		plan.run = func(ctx context.Context) error {
+			if err := s.db.DetachFeedPosts(ctx, uuid.NullUUID{UUID: feed.ID, Valid: true}); err != nil {
+				return err
+			}
+			return s.db.DeleteFeed(ctx, feed.ID)
+		}
+	}
+	return runReset(s, cmd, plan)
+}
+
+func handlerFeedInfo(s *state, cmd command) error {
+	feed, err := getFeed(s, cmd.arguments[0])
+	if err != nil {
+		return err
+	}
+	owner, err := s.db.GetUserById(context.Background(), feed.UserID)
+	if err != nil {
+		return databaseError("error getting user info by id: %w", err)
+	}
+	stats, err := s.db.GetFeedStats(context.Background(), feed.ID)
+	if err != nil {
+		return databaseError("error getting feed stats: %w", err)
+	}
+
+	record := feedInfoRecord{
+		ID:             feed.ID,
+		Name:           feed.Name,
+		URL:            feed.Url,
+		User:           owner.Name,
+		CreatedAt:      feed.CreatedAt,
+		Followers:      stats.Followers,
+		Posts:          stats.Posts,
+		Health:         feedHealth(feed),
+		LastFetchError: feed.LastFetchError.String,
+		FullContent:    feed.FetchFullContent,
+	}
+	if feed.LastFetchedAt.Valid {
+		record.LastFetchedAt = &feed.LastFetchedAt.Time
+	}
+	if s.output != outputPlain {
+		return writeRecords(os.Stdout, s.output, []feedInfoRecord{record})
+	}
+
+	lastFetched := "never"
+	if record.LastFetchedAt != nil {
+		lastFetched = record.LastFetchedAt.Format("2006-01-02 15:04")
+	}
+	fmt.Println(record.Name)
+	fmt.Printf("URL:        %v\n", record.URL)
+	fmt.Printf("Added by:   %v on %v\n", record.User, record.CreatedAt.Format("2006-01-02"))
+	fmt.Printf("Followers:  %v\n", record.Followers)
+	fmt.Printf("Posts:      %v\n", record.Posts)
+	fmt.Printf("Last fetch: %v\n", lastFetched)
+	fmt.Printf("Health:     %v\n", record.Health)
+	if record.FullContent {
+		fmt.Println("Content:    full articles are fetched")
+	}
+	if record.LastFetchError != "" {
+		fmt.Printf("Last error: %v\n", record.LastFetchError)
+	}
+	return nil
+}
+
+func feedHealth(feed database.Feed) string {
+	switch {
+	case feed.DeadAt.Valid:
+		return feedDead
+	case !feed.LastFetchedAt.Valid:
+		return feedNeverFetched
+	case feed.LastFetchError.Valid:
+		return feedFailing
+	}
+	return feedHealthy
+}
+
+func getFeed(s *state, feedURL string) (database.Feed, error) {
+	feed, err := s.db.GetFeedByURL(context.Background(), feedURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Feed{}, notFoundError("no feed with url %v", feedURL)
+	}
+	if err != nil {
+		return database.Feed{}, databaseError("error getting feed by url: %w", err)
+	}
+	return feed, nil
+}
+
+// getOwnFeed returns the feed at feedURL if user added it: feeds are shared
+// by everyone following them, so only their owner may change them.
+func getOwnFeed(s *state, user database.User, feedURL, action string) (database.Feed, error) {
+	feed, err := getFeed(s, feedURL)
+	if err != nil {
+		return database.Feed{}, err
+	}
+	if feed.UserID != user.ID {
+		return database.Feed{}, permissionError("feed %v was added by another user, only they can %v it", feed.Name, action)
+	}
+	return feed, nil
+}
+
+func checkFeedURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return validationError("invalid feed url: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return validationError("invalid feed url %v, expected an http or https url", raw)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRenameFeedAndSetFeedURL(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+	rssURL, atomURL := server.feedURL("example.rss"), server.feedURL("example.atom")
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", rssURL)
+	mustRun(t, s, "addfeed", "Atom", atomURL)
+
+	mustRun(t, s, "renamefeed", rssURL, "Renamed")
+	feed, err := s.db.GetFeedByURL(context.Background(), rssURL)
+	if err != nil || feed.Name != "Renamed" {
+		t.Fatalf("feed after renaming: %+v, %v", feed, err)
+	}
+
+	_, err = runCommand(t, s, "setfeedurl", rssURL, atomURL)
+	assertKind(t, err, errConflict)
+	_, err = runCommand(t, s, "setfeedurl", rssURL, "ftp://example.com/feed")
+	assertKind(t, err, errValidation)
+
+	movedURL := server.feedURL("moved.rss")
+	mustRun(t, s, "setfeedurl", rssURL, movedURL)
+	if _, err := s.db.GetFeedByURL(context.Background(), movedURL); err != nil {
+		t.Fatalf("feed not found at its new url: %v", err)
+	}
+
+	mustRun(t, s, "register", "bob")
+	_, err = runCommand(t, s, "renamefeed", movedURL, "Mine now")
+	assertKind(t, err, errPermission)
+	_, err = runCommand(t, s, "setfeedurl", movedURL, rssURL)
+	assertKind(t, err, errPermission)
+	_, err = runCommand(t, s, "renamefeed", server.feedURL("missing.rss"), "Missing")
+	assertKind(t, err, errNotFound)
+}
+
+func TestDeleteFeed(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+	url := server.feedURL("example.rss")
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", url)
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatal(err)
+	}
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "follow", url)
+
+	_, err := runCommand(t, s, "deletefeed", url, "--yes")
+	assertKind(t, err, errPermission)
+
+	mustRun(t, s, "login", "alice")
+	out := mustRun(t, s, "deletefeed", url, "--dry-run")
+	if want := "would delete 1 feed, 2 follows and 2 posts"; !strings.Contains(out, want) {
+		t.Errorf("dry run output %q does not contain %q", out, want)
+	}
+
+	mustRun(t, s, "deletefeed", url, "--yes")
+	_, err = runCommand(t, s, "feedinfo", url)
+	assertKind(t, err, errNotFound)
+	if _, err := s.db.GetPostByURL(context.Background(), "https://example.com/posts/first"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("post of the deleted feed: %v, want sql.ErrNoRows", err)
+	}
+
+	mustRun(t, s, "login", "bob")
+	out = mustRun(t, s, "following")
+	if strings.Contains(out, "Example") {
+		t.Errorf("bob still follows the deleted feed:\n%s", out)
+	}
+}
+
+func TestDeleteFeedKeepPosts(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+	url := server.feedURL("example.rss")
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", url)
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatal(err)
+	}
+	mustRun(t, s, "tag", "https://example.com/posts/first", "keep")
+
+	out := mustRun(t, s, "deletefeed", "--keep-posts", "--dry-run", url)
+	if want := "Removing feed Example (keeping its posts) would delete 1 feed and 1 follow."; !strings.Contains(out, want) {
+		t.Errorf("dry run output %q does not contain %q", out, want)
+	}
+	mustRun(t, s, "deletefeed", "--keep-posts", "--yes", url)
+	post, err := s.db.GetPostByURL(context.Background(), "https://example.com/posts/first")
+	if err != nil || post.FeedID.Valid {
+		t.Fatalf("kept post: %+v, %v", post, err)
+	}
+	if out := mustRun(t, s, "browse"); strings.Contains(out, "First post") {
+		t.Errorf("browse lists a post without a feed:\n%s", out)
+	}
+
+	// Adding the feed again brings the posts back with their tags.
+	mustRun(t, s, "addfeed", "Example", url)
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatal(err)
+	}
+	if out := mustRun(t, s, "tags", "https://example.com/posts/first"); !strings.Contains(out, "keep") {
+		t.Errorf("tags of the post after adding the feed again:\n%s", out)
+	}
+}
+
+func TestFeedInfo(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+	url := server.feedURL("example.rss")
+	missingURL := server.feedURL("missing.rss")
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", url)
+	mustRun(t, s, "addfeed", "Missing", missingURL)
+
+	out := mustRun(t, s, "feedinfo", url)
+	for _, want := range []string{"Added by:   alice", "Followers:  1", "Posts:      0", "Last fetch: never", "Health:     never fetched"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("feedinfo output does not contain %q:\n%s", want, out)
+		}
+	}
+
+	// Both feeds are fetched once, and the missing one fails.
+	scrapeFeeds(s)
+	scrapeFeeds(s)
+
+	out = mustRun(t, s, "feedinfo", url)
+	for _, want := range []string{"Posts:      2", "Health:     ok"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("feedinfo output does not contain %q:\n%s", want, out)
+		}
+	}
+	out = mustRun(t, s, "feedinfo", missingURL)
+	if !strings.Contains(out, "Health:     failing") || !strings.Contains(out, "Last error:") {
+		t.Errorf("feedinfo does not report the failing fetch:\n%s", out)
+	}
+}
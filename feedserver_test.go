@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// feedServer serves the fixture documents in testdata/feeds, so feeds can be
+// fetched without going out to the network. A fixture is served at
+// /<file name>, e.g. /example.rss, unless a handler was set for that path.
+type feedServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests map[string]int
+	handlers map[string]http.HandlerFunc
+}
+
+func newFeedServer(t *testing.T) *feedServer {
+	t.Helper()
+
+	fs := &feedServer{requests: map[string]int{}, handlers: map[string]http.HandlerFunc{}}
+	fs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fs.mu.Lock()
+		fs.requests[r.URL.Path]++
+		handler := fs.handlers[r.URL.Path]
+		fs.mu.Unlock()
+
+		if handler != nil {
+			handler(w, r)
+			return
+		}
+
+		body, err := os.ReadFile(filepath.Join("testdata", "feeds", filepath.Base(r.URL.Path)))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write(body)
+	}))
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+// feedURL returns the URL the fixture with the given file name is served at.
+func (fs *feedServer) feedURL(name string) string {
+	return fs.URL + "/" + name
+}
+
+// hits returns how many times the fixture was requested.
+func (fs *feedServer) hits(name string) int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.requests["/"+name]
+}
+
+// handle makes the server answer requests for name with handler.
+func (fs *feedServer) handle(name string, handler http.HandlerFunc) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.handlers["/"+name] = handler
+}
+
+// redirect makes the server redirect requests for name to the fixture
+// target with the given status code.
+func (fs *feedServer) redirect(name, target string, code int) {
+	fs.handle(name, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, fs.feedURL(target), code)
+	})
+}
+
+// status makes the server answer requests for name with an empty response
+// and the given status code.
+func (fs *feedServer) status(name string, code int) {
+	fs.handle(name, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	})
+}
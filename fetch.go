@@ -0,0 +1,385 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alifoo/blog-aggregator/internal/config"
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+// maxRedirects matches the limit of the default HTTP client.
+const maxRedirects = 10
+
+// Defaults of the fetch settings, used when they are not configured.
+const (
+	defaultFetchTimeout       = 30 * time.Second
+	defaultConnectTimeout     = 10 * time.Second
+	defaultMaxFeedSize        = 10 << 20
+	defaultUserAgent          = "gator"
+	defaultMaxHostConnections = 2
+)
+
+// fetchOptions configure the HTTP client that fetches feeds.
+type fetchOptions struct {
+	// timeout bounds a whole request, from connecting to reading the last
+	// byte of the body.
+	timeout        time.Duration
+	connectTimeout time.Duration
+	maxFeedSize    int64
+	// proxy is the URL of an HTTP proxy. When empty, the usual
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables apply.
+	proxy     string
+	userAgent string
+	// maxHostConnections limits how many requests run at once against
+	// the same host. Each redirect counts against the host it goes to.
+	maxHostConnections int
+}
+
+// fetcher is the HTTP client used for feeds. It is shared by everything a
+// command fetches so that the per-host limit holds across requests.
+type fetcher struct {
+	client  *http.Client
+	options fetchOptions
+}
+
+// hostLimiter is the transport of the fetcher. Every request it sends,
+// redirects included, takes a slot of its host, which is given back once
+// the response body is closed.
+type hostLimiter struct {
+	next http.RoundTripper
+	max  int
+
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+}
+
+// fetchResponse is a fetched document and the redirects that led to it.
+type fetchResponse struct {
+	status      int
+	statusMsg   string
+	contentType string
+	body        []byte
+	redirects   redirectChain
+}
+
+// loadFetchOptions reads the fetch settings from cfg, falling back to the
+// defaults for those that are not set.
+func loadFetchOptions(cfg *config.Config) (fetchOptions, error) {
+	opts := fetchOptions{
+		timeout:            defaultFetchTimeout,
+		connectTimeout:     defaultConnectTimeout,
+		maxFeedSize:        defaultMaxFeedSize,
+		userAgent:          defaultUserAgent,
+		maxHostConnections: defaultMaxHostConnections,
+	}
+	for _, key := range []string{
+		config.KeyFetchTimeout,
+		config.KeyConnectTimeout,
+		config.KeyMaxFeedSize,
+		config.KeyHTTPProxy,
+		config.KeyUserAgent,
+		config.KeyMaxHostConnections,
+	} {
+		value, err := cfg.Get(key)
+		if err != nil {
+			return fetchOptions{}, err
+		}
+		if value == "" {
+			continue
+		}
+		if err := opts.set(key, value); err != nil {
+			return fetchOptions{}, validationError("invalid %v %q: %w", key, value, err)
+		}
+	}
+	return opts, nil
+}
+
+// set parses value into the option for key.
+func (opts *fetchOptions) set(key, value string) error {
+	var err error
+	switch key {
+	case config.KeyFetchTimeout:
+		opts.timeout, err = parsePositiveDuration(value)
+	case config.KeyConnectTimeout:
+		opts.connectTimeout, err = parsePositiveDuration(value)
+	case config.KeyMaxFeedSize:
+		opts.maxFeedSize, err = parseSize(value)
+	case config.KeyHTTPProxy:
+		var u *url.URL
+		u, err = url.Parse(value)
+		if err == nil && (u.Scheme == "" || u.Host == "") {
+			err = errors.New("expected a URL such as http://proxy:3128")
+		}
+		opts.proxy = value
+	case config.KeyUserAgent:
+		opts.userAgent = value
+	case config.KeyMaxHostConnections:
+		opts.maxHostConnections, err = strconv.Atoi(value)
+		if err == nil && opts.maxHostConnections < 1 {
+			err = errors.New("must be at least 1")
+		}
+	}
+	return err
+}
+
+// checkFetchSetting reports whether value is valid for key, if key is one
+// of the fetch settings.
+func checkFetchSetting(key, value string) error {
+	var opts fetchOptions
+	if value == "" {
+		return nil
+	}
+	if err := opts.set(key, value); err != nil {
+		return validationError("invalid %v %q: %w", key, value, err)
+	}
+	return nil
+}
+
+func parsePositiveDuration(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err == nil && d <= 0 {
+		err = errors.New("must be positive")
+	}
+	return d, err
+}
+
+// parseSize parses a number of bytes, optionally with a KB, MB or GB
+// suffix in powers of 1024, e.g. 512KB or 10MB.
+func parseSize(value string) (int64, error) {
+	units := []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}}
+
+	number, multiplier := strings.ToUpper(strings.TrimSpace(value)), int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(number, unit.suffix) {
+			number, multiplier = strings.TrimSpace(strings.TrimSuffix(number, unit.suffix)), unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n < 1 {
+		return 0, errors.New("expected a positive size such as 512KB or 10MB")
+	}
+	return n * multiplier, nil
+}
+
+func newFetcher(opts fetchOptions) *fetcher {
+	proxy := http.ProxyFromEnvironment
+	if opts.proxy != "" {
+		proxyURL, _ := url.Parse(opts.proxy)
+		proxy = http.ProxyURL(proxyURL)
+	}
+	dialer := &net.Dialer{Timeout: opts.connectTimeout, KeepAlive: 30 * time.Second}
+
+	return &fetcher{
+		client: &http.Client{
+			Timeout: opts.timeout,
+			Transport: &hostLimiter{
+				next: &http.Transport{
+					Proxy:               proxy,
+					DialContext:         dialer.DialContext,
+					TLSHandshakeTimeout: opts.connectTimeout,
+					ForceAttemptHTTP2:   true,
+					MaxIdleConns:        100,
+					IdleConnTimeout:     90 * time.Second,
+				},
+				max:   opts.maxHostConnections,
+				hosts: map[string]chan struct{}{},
+			},
+		},
+		options: opts,
+	}
+}
+
+// feedFetcher returns the fetcher of s, creating it from the config on
+// first use.
+func (s *state) feedFetcher() (*fetcher, error) {
+	if s.fetcher != nil {
+		return s.fetcher, nil
+	}
+	opts, err := loadFetchOptions(s.configPointer)
+	if err != nil {
+		return nil, err
+	}
+	s.fetcher = newFetcher(opts)
+	return s.fetcher, nil
+}
+
+// get downloads rawURL, waiting for a free slot whenever a host it goes to
+// already has as many requests running as allowed. Bodies larger than the
+// configured maximum are rejected rather than read into memory.
+func (f *fetcher) get(ctx context.Context, rawURL string) (*fetchResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, validationError("error creating request for url: %v\n %w", rawURL, err)
+	}
+	req.Header.Set("User-Agent", f.options.userAgent)
+
+	var redirects redirectChain
+	client := *f.client
+	client.CheckRedirect = redirects.check
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, networkError("error fetching url: %v\n %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ContentLength > f.options.maxFeedSize {
+		return nil, networkError("error fetching url: %v\n response of %d bytes is larger than %v %d", rawURL, resp.ContentLength, config.KeyMaxFeedSize, f.options.maxFeedSize)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.options.maxFeedSize+1))
+	if err != nil {
+		return nil, networkError("error reading body: %v\n %w", rawURL, err)
+	}
+	if int64(len(body)) > f.options.maxFeedSize {
+		return nil, networkError("error reading body: %v\n response is larger than %v %d", rawURL, config.KeyMaxFeedSize, f.options.maxFeedSize)
+	}
+
+	return &fetchResponse{
+		status:      resp.StatusCode,
+		statusMsg:   resp.Status,
+		contentType: resp.Header.Get("Content-Type"),
+		body:        body,
+		redirects:   redirects,
+	}, nil
+}
+
+func (l *hostLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := l.acquire(req.Context(), req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := l.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// acquire takes one of the request slots of host, and returns the
+// function that gives it back.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	l.mu.Lock()
+	slots, ok := l.hosts[host]
+	if !ok {
+		slots = make(chan struct{}, l.max)
+		l.hosts[host] = slots
+	}
+	l.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// releasingBody gives the slot of its request back when it is closed, which
+// the client does before following a redirect.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// errFeedGone is returned by fetchFeed when the server answers 410 Gone:
+// the feed was removed on purpose and fetching it again is pointless.
+var errFeedGone = errors.New("feed is gone")
+
+type redirect struct {
+	status int
+	to     string
+}
+
+// redirectChain records the redirects followed while fetching a feed.
+type redirectChain []redirect
+
+// check is used as the CheckRedirect function of the HTTP client.
+func (c *redirectChain) check(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	*c = append(*c, redirect{status: req.Response.StatusCode, to: req.URL.String()})
+	return nil
+}
+
+// permanentURL returns the URL the feed moved to for good: where the
+// leading 301 and 308 redirects end up. Redirects after a temporary one
+// may change at any time, so they are ignored. It returns "" if the first
+// redirect is temporary or there are none.
+func (c redirectChain) permanentURL() string {
+	moved := ""
+	for _, r := range c {
+		if r.status != http.StatusMovedPermanently && r.status != http.StatusPermanentRedirect {
+			break
+		}
+		moved = r.to
+	}
+	return moved
+}
+
+// moveFeed points feed at newURL after a permanent redirect. If another
+// feed already has that URL, the two are merged: follows and posts move to
+// the existing feed and feed is deleted. Each step can be repeated, so a
+// merge that fails half way is finished on the next fetch. It returns the
+// feed that now has newURL.
+func moveFeed(s *state, feed database.Feed, newURL string) (database.Feed, error) {
+	ctx := context.Background()
+
+	existing, err := s.db.GetFeedByURL(ctx, newURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		moved, err := s.db.SetFeedURL(ctx, database.SetFeedURLParams{ID: feed.ID, Url: newURL})
+		if err != nil {
+			return database.Feed{}, databaseError("error updating feed url: %w", err)
+		}
+		fmt.Printf("Feed %v moved permanently to %v, its url was updated.\n", feed.Name, newURL)
+		return moved, nil
+	}
+	if err != nil {
+		return database.Feed{}, databaseError("error getting feed by url: %w", err)
+	}
+
+	move := database.MoveFeedFollowsParams{FromFeedID: feed.ID, ToFeedID: existing.ID}
+	if err := s.db.MoveFeedFollows(ctx, move); err != nil {
+		return database.Feed{}, databaseError("error moving follows of %v: %w", feed.Name, err)
+	}
+	err = s.db.MovePosts(ctx, database.MovePostsParams{
+		FromFeedID: uuid.NullUUID{UUID: feed.ID, Valid: true},
+		ToFeedID:   uuid.NullUUID{UUID: existing.ID, Valid: true},
+	})
+	if err != nil {
+		return database.Feed{}, databaseError("error moving posts of %v: %w", feed.Name, err)
+	}
+	err = s.db.MoveFeedRules(ctx, database.MoveFeedRulesParams{FromFeedID: feed.ID, ToFeedID: existing.ID})
+	if err != nil {
+		return database.Feed{}, databaseError("error moving rules of %v: %w", feed.Name, err)
+	}
+	if err := s.db.DeleteFeed(ctx, feed.ID); err != nil {
+		return database.Feed{}, databaseError("error deleting feed %v: %w", feed.Name, err)
+	}
+	fmt.Printf("Feed %v moved permanently to %v, which is feed %v: merged its follows and posts into it.\n", feed.Name, newURL, existing.Name)
+	return existing, nil
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alifoo/blog-aggregator/internal/config"
+)
+
+func TestPermanentURL(t *testing.T) {
+	tests := []struct {
+		name  string
+		chain redirectChain
+		want  string
+	}{
+		{"no redirects", nil, ""},
+		{"permanent", redirectChain{{301, "b"}}, "b"},
+		{"permanent chain", redirectChain{{301, "b"}, {308, "c"}}, "c"},
+		{"temporary", redirectChain{{302, "b"}}, ""},
+		{"temporary after permanent", redirectChain{{308, "b"}, {307, "c"}, {301, "d"}}, "b"},
+	}
+	for _, tt := range tests {
+		if got := tt.chain.permanentURL(); got != tt.want {
+			t.Errorf("%v: permanentURL() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScrapeFollowsPermanentRedirect(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+	oldURL := server.feedURL("old.rss")
+	server.redirect("old.rss", "example.rss", http.StatusMovedPermanently)
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", oldURL)
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+
+	if _, err := s.db.GetFeedByURL(context.Background(), oldURL); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("feed is still stored with its old url: %v", err)
+	}
+	feed, err := s.db.GetFeedByURL(context.Background(), server.feedURL("example.rss"))
+	if err != nil {
+		t.Fatalf("feed is not stored with its new url: %v", err)
+	}
+	stats, err := s.db.GetFeedStats(context.Background(), feed.ID)
+	if err != nil || stats.Posts != 2 || stats.Followers != 1 {
+		t.Fatalf("feed stats after the move: %+v, %v", stats, err)
+	}
+}
+
+func TestScrapeIgnoresTemporaryRedirect(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+	url := server.feedURL("elsewhere.rss")
+	server.redirect("elsewhere.rss", "example.rss", http.StatusFound)
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", url)
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+	if _, err := s.db.GetFeedByURL(context.Background(), url); err != nil {
+		t.Fatalf("temporary redirect changed the feed url: %v", err)
+	}
+}
+
+func TestScrapeMergesMovedFeed(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+	oldURL, newURL := server.feedURL("old.rss"), server.feedURL("example.rss")
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Old", oldURL)
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "follow", oldURL)
+	mustRun(t, s, "addfeed", "New", newURL)
+
+	// The old feed first serves its own copy, then moves to the new one.
+	server.redirect("old.rss", "example.atom", http.StatusFound)
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+	server.redirect("old.rss", "example.rss", http.StatusPermanentRedirect)
+	// The new feed is fetched first, as it never was.
+	for range 2 {
+		if err := scrapeFeeds(s); err != nil {
+			t.Fatalf("scrapeFeeds: %v", err)
+		}
+	}
+
+	if _, err := s.db.GetFeedByURL(context.Background(), oldURL); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("old feed was not merged: %v", err)
+	}
+	feed, err := s.db.GetFeedByURL(context.Background(), newURL)
+	if err != nil || feed.Name != "New" {
+		t.Fatalf("merged feed: %+v, %v", feed, err)
+	}
+	stats, err := s.db.GetFeedStats(context.Background(), feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// bob follows both feeds, alice only the old one; the Atom posts moved.
+	if stats.Followers != 2 || stats.Posts != 4 {
+		t.Fatalf("merged feed stats are %+v, want 2 followers and 4 posts", stats)
+	}
+
+	mustRun(t, s, "login", "alice")
+	out := mustRun(t, s, "following")
+	if !strings.Contains(out, "New") {
+		t.Errorf("alice does not follow the merged feed:\n%s", out)
+	}
+}
+
+func TestScrapeMarksGoneFeedDead(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+	url := server.feedURL("gone.rss")
+	server.status("gone.rss", http.StatusGone)
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Gone", url)
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+	out := mustRun(t, s, "feedinfo", url)
+	if !strings.Contains(out, "Health:     dead") {
+		t.Errorf("feedinfo does not show the feed as dead:\n%s", out)
+	}
+	if err := scrapeFeeds(s); kindOf(err) != errNotFound {
+		t.Fatalf("dead feed is still fetched: %v", err)
+	}
+	if hits := server.hits("gone.rss"); hits != 1 {
+		t.Errorf("dead feed was requested %d times, want 1", hits)
+	}
+
+	// Giving the feed a new url brings it back.
+	mustRun(t, s, "setfeedurl", url, server.feedURL("example.rss"))
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds after setfeedurl: %v", err)
+	}
+}
+
+func TestScrapeRejectsErrorStatus(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+	server.status("broken.rss", http.StatusInternalServerError)
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Broken", server.feedURL("broken.rss"))
+	err := scrapeFeeds(s)
+	assertKind(t, err, errNetwork)
+	if !strings.Contains(err.Error(), "500") {
+		t.Errorf("error does not mention the status: %v", err)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"1024":  1024,
+		"512KB": 512 << 10,
+		"10MB":  10 << 20,
+		"1 gb":  1 << 30,
+		"100B":  100,
+	}
+	for value, want := range tests {
+		got, err := parseSize(value)
+		if err != nil || got != want {
+			t.Errorf("parseSize(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "0", "-1MB", "ten", "10TB"} {
+		if _, err := parseSize(value); err == nil {
+			t.Errorf("parseSize(%q) succeeded", value)
+		}
+	}
+}
+
+func TestLoadFetchOptions(t *testing.T) {
+	s := newTestState(t)
+
+	opts, err := loadFetchOptions(s.configPointer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.timeout != defaultFetchTimeout || opts.userAgent != defaultUserAgent || opts.maxFeedSize != defaultMaxFeedSize {
+		t.Errorf("defaults not applied: %+v", opts)
+	}
+
+	mustRun(t, s, "config", "set", config.KeyFetchTimeout, "5s")
+	mustRun(t, s, "config", "set", config.KeyMaxFeedSize, "1MB")
+	t.Setenv(config.EnvName(config.KeyUserAgent), "gator-test/1.0")
+	opts, err = loadFetchOptions(s.configPointer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.timeout != 5*time.Second || opts.maxFeedSize != 1<<20 || opts.userAgent != "gator-test/1.0" {
+		t.Errorf("settings not applied: %+v", opts)
+	}
+
+	for key, value := range map[string]string{
+		config.KeyFetchTimeout:       "soon",
+		config.KeyConnectTimeout:     "-1s",
+		config.KeyMaxHostConnections: "0",
+		config.KeyHTTPProxy:          "proxy",
+	} {
+		_, err := runCommand(t, s, "config", "set", key, value)
+		assertKind(t, err, errValidation)
+	}
+}
+
+func TestFetcherSendsUserAgent(t *testing.T) {
+	var userAgent atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent.Store(r.UserAgent())
+	}))
+	defer server.Close()
+
+	opts := testFetchOptions()
+	opts.userAgent = "gator-test/1.0"
+	if _, err := newFetcher(opts).get(context.Background(), server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if got := userAgent.Load(); got != "gator-test/1.0" {
+		t.Errorf("server saw user agent %q", got)
+	}
+}
+
+func TestFetcherTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	opts := testFetchOptions()
+	opts.timeout = 50 * time.Millisecond
+	_, err := newFetcher(opts).get(context.Background(), server.URL)
+	assertKind(t, err, errNetwork)
+}
+
+func TestFetcherMaxFeedSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Flushing first leaves out the Content-Length header, so the
+		// limit has to catch the body while it is read.
+		w.(http.Flusher).Flush()
+		w.Write([]byte(strings.Repeat("x", 2048)))
+	}))
+	defer server.Close()
+
+	opts := testFetchOptions()
+	opts.maxFeedSize = 1024
+	_, err := newFetcher(opts).get(context.Background(), server.URL)
+	assertKind(t, err, errNetwork)
+	if !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("error does not mention the size limit: %v", err)
+	}
+
+	opts.maxFeedSize = 2048
+	if _, err := newFetcher(opts).get(context.Background(), server.URL); err != nil {
+		t.Errorf("body of exactly the limit was rejected: %v", err)
+	}
+}
+
+func TestFetcherProxy(t *testing.T) {
+	var proxied atomic.Value
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied.Store(r.URL.String())
+	}))
+	defer proxy.Close()
+
+	opts := testFetchOptions()
+	opts.proxy = proxy.URL
+	if _, err := newFetcher(opts).get(context.Background(), "http://feeds.example.com/rss"); err != nil {
+		t.Fatal(err)
+	}
+	if got := proxied.Load(); got != "http://feeds.example.com/rss" {
+		t.Errorf("proxy saw request for %v", got)
+	}
+}
+
+func TestFetcherLimitsRequestsPerHost(t *testing.T) {
+	var running, most atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := most.Load()
+			if n <= m || most.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	opts := testFetchOptions()
+	opts.maxHostConnections = 2
+	f := newFetcher(opts)
+
+	var wg sync.WaitGroup
+	for range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := f.get(context.Background(), server.URL); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := most.Load(); got != 2 {
+		t.Errorf("at most %d requests ran at once, want 2", got)
+	}
+}
+
+func TestFetcherLimitsRedirectedRequests(t *testing.T) {
+	var running, most atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := most.Load()
+			if n <= m || most.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer target.Close()
+	redirector := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirector.Close()
+
+	opts := testFetchOptions()
+	opts.maxHostConnections = 1
+	f := newFetcher(opts)
+
+	// Requests redirected to the target share its slot with those sent to
+	// it directly.
+	var wg sync.WaitGroup
+	for i := range 6 {
+		url := target.URL
+		if i%2 == 0 {
+			url = redirector.URL
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := f.get(context.Background(), url); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := most.Load(); got != 1 {
+		t.Errorf("at most %d requests ran at once on the target, want 1", got)
+	}
+}
+
+func testFetchOptions() fetchOptions {
+	return fetchOptions{
+		timeout:            5 * time.Second,
+		connectTimeout:     time.Second,
+		maxFeedSize:        defaultMaxFeedSize,
+		userAgent:          defaultUserAgent,
+		maxHostConnections: defaultMaxHostConnections,
+	}
+}
+
+// TestScrapeFeedBatchLimitsRequestsPerHost fetches several feeds of the
+// same server at once, as agg does, and checks they are fetched in parallel
+// but no more at a time than the per-host limit allows.
+func TestScrapeFeedBatchLimitsRequestsPerHost(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+	example, err := os.ReadFile(filepath.Join("testdata", "feeds", "example.rss"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var running, most atomic.Int32
+	names := []string{"one.rss", "two.rss", "three.rss", "four.rss"}
+	mustRun(t, s, "register", "alice")
+	for _, name := range names {
+		server.handle(name, func(w http.ResponseWriter, r *http.Request) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				m := most.Load()
+				if n <= m || most.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(50 * time.Millisecond)
+			w.Write(example)
+		})
+		mustRun(t, s, "addfeed", name, server.feedURL(name))
+	}
+
+	opts := testFetchOptions()
+	opts.maxHostConnections = 2
+	s.fetcher = newFetcher(opts)
+
+	// A batch larger than the number of feeds fetches each of them once.
+	if err := scrapeFeedBatch(s, 10); err != nil {
+		t.Fatalf("scrapeFeedBatch: %v", err)
+	}
+	for _, name := range names {
+		if hits := server.hits(name); hits != 1 {
+			t.Errorf("%v was fetched %d times, want 1", name, hits)
+		}
+	}
+	if got := most.Load(); got != 2 {
+		t.Errorf("at most %d feeds were fetched at once, want 2", got)
+	}
+
+	_, err = runCommand(t, s, "agg", "--parallel", "0", "1m")
+	assertKind(t, err, errValidation)
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/alifoo/blog-aggregator/internal/storage"
+	"github.com/google/uuid"
+)
+
+// handlerFolders manages the folders the current user sorts the feeds they
+// follow into. Folders are private to their user.
+func handlerFolders(s *state, cmd command, user database.User) error {
+	action, args := cmd.arguments[0], cmd.arguments[1:]
+	wrongArgs := func(usage string) error {
+		return validationError("wrong number of arguments\nusage: %s folders %s", programName(), usage)
+	}
+
+	switch action {
+	case "list":
+		if len(args) != 0 {
+			return wrongArgs("list")
+		}
+		return listFolders(s, user)
+	case "create":
+		if len(args) != 1 {
+			return wrongArgs("create <name>")
+		}
+		return createFolder(s, user, args[0])
+	case "rename":
+		if len(args) != 2 {
+			return wrongArgs("rename <name> <new_name>")
+		}
+		return renameFolder(s, user, args[0], args[1])
+	case "delete":
+		if len(args) != 1 {
+			return wrongArgs("delete <name>")
+		}
+		return deleteFolder(s, user, args[0])
+	case "move":
+		if len(args) < 1 || len(args) > 2 {
+			return wrongArgs("move <feed_url> [folder]")
+		}
+		folder := ""
+		if len(args) == 2 {
+			folder = args[1]
+		}
+		return moveToFolder(s, user, args[0], folder)
+	}
+	return validationError("unknown folders action %v, expected list, create, rename, delete or move", action)
+}
+
+func listFolders(s *state, user database.User) error {
+	folders, err := s.db.GetFoldersForUser(context.Background(), user.ID)
+	if err != nil {
+		return databaseError("error getting folders: %w", err)
+	}
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return databaseError("error getting feed follows: %w", err)
+	}
+	unread, err := unreadCounts(s, user)
+	if err != nil {
+		return err
+	}
+
+	feeds := map[uuid.UUID]int{}
+	folderUnread := map[uuid.UUID]int64{}
+	for _, f := range follows {
+		if f.FolderID.Valid {
+			feeds[f.FolderID.UUID]++
+			folderUnread[f.FolderID.UUID] += unread[f.FeedID]
+		}
+	}
+
+	if s.output != outputPlain {
+		var records []folderRecord
+		for _, f := range folders {
+			records = append(records, folderRecord{
+				ID:        f.ID,
+				Name:      f.Name,
+				Feeds:     feeds[f.ID],
+				Unread:    folderUnread[f.ID],
+				CreatedAt: f.CreatedAt,
+			})
+		}
+		return writeRecords(os.Stdout, s.output, records)
+	}
+
+	if len(folders) == 0 {
+		fmt.Printf("No folders, create one with '%s folders create'.\n", programName())
+		return nil
+	}
+	for _, f := range folders {
+		fmt.Printf("%v: %v, %v unread\n", f.Name, countFeeds(feeds[f.ID]), folderUnread[f.ID])
+	}
+	return nil
+}
+
+func createFolder(s *state, user database.User, name string) error {
+	name, err := checkFolderName(name)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	_, err = s.db.CreateFolder(context.Background(), database.CreateFolderParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    user.ID,
+		Name:      name,
+	})
+	if storage.IsUniqueViolation(err) {
+		return conflictError("folder %v already exists", name)
+	}
+	if err != nil {
+		return databaseError("error creating folder: %w", err)
+	}
+	fmt.Printf("Created folder %v.\n", name)
+	return nil
+}
+
+func renameFolder(s *state, user database.User, name, newName string) error {
+	newName, err := checkFolderName(newName)
+	if err != nil {
+		return err
+	}
+	folder, err := getFolder(s, user, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.RenameFolder(context.Background(), database.RenameFolderParams{ID: folder.ID, Name: newName})
+	if storage.IsUniqueViolation(err) {
+		return conflictError("folder %v already exists", newName)
+	}
+	if err != nil {
+		return databaseError("error renaming folder: %w", err)
+	}
+	fmt.Printf("Renamed folder %v to %v.\n", folder.Name, newName)
+	return nil
+}
+
+// deleteFolder deletes a folder but not the feeds in it, which the user
+// keeps following outside of any folder.
+func deleteFolder(s *state, user database.User, name string) error {
+	folder, err := getFolder(s, user, name)
+	if err != nil {
+		return err
+	}
+	if err := s.db.DeleteFolder(context.Background(), folder.ID); err != nil {
+		return databaseError("error deleting folder: %w", err)
+	}
+	fmt.Printf("Deleted folder %v, the feeds in it are still followed.\n", folder.Name)
+	return nil
+}
+
+// moveToFolder moves a followed feed into a folder, or out of its folder
+// when folderName is empty.
+func moveToFolder(s *state, user database.User, feedURL, folderName string) error {
+	feed, err := getFeed(s, feedURL)
+	if err != nil {
+		return err
+	}
+	var folderID uuid.NullUUID
+	if folderName != "" {
+		folder, err := getFolder(s, user, folderName)
+		if err != nil {
+			return err
+		}
+		folderID = uuid.NullUUID{UUID: folder.ID, Valid: true}
+	}
+
+	_, err = s.db.SetFeedFollowFolder(context.Background(), database.SetFeedFollowFolderParams{
+		FolderID: folderID,
+		UserID:   user.ID,
+		FeedID:   feed.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFoundError("you do not follow %v, use follow to follow it", feedURL)
+	}
+	if err != nil {
+		return databaseError("error moving feed: %w", err)
+	}
+	if folderName == "" {
+		fmt.Printf("Moved %v out of its folder.\n", feed.Name)
+	} else {
+		fmt.Printf("Moved %v to folder %v.\n", feed.Name, folderName)
+	}
+	return nil
+}
+
+// getFolder returns the folder of user with the given name.
+func getFolder(s *state, user database.User, name string) (database.Folder, error) {
+	folder, err := s.db.GetFolderByName(context.Background(), database.GetFolderByNameParams{UserID: user.ID, Name: name})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Folder{}, notFoundError("no folder named %v, see '%s folders list'", name, programName())
+	}
+	if err != nil {
+		return database.Folder{}, databaseError("error getting folder: %w", err)
+	}
+	return folder, nil
+}
+
+func checkFolderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", validationError("folder name is empty")
+	}
+	return name, nil
+}
+
+// unreadCounts returns the number of unread posts of user by feed.
+func unreadCounts(s *state, user database.User) (map[uuid.UUID]int64, error) {
+	counts, err := s.db.GetUnreadCountsForUser(context.Background(), user.ID)
+	if err != nil {
+		return nil, databaseError("error getting unread counts: %w", err)
+	}
+	unread := map[uuid.UUID]int64{}
+	for _, c := range counts {
+		unread[c.FeedID.UUID] = c.Unread
+	}
+	return unread, nil
+}
+
+func countFeeds(n int) string {
+	if n == 1 {
+		return "1 feed"
+	}
+	return fmt.Sprintf("%d feeds", n)
+}
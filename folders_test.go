@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFolders(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+	example, atom := server.feedURL("example.rss"), server.feedURL("example.atom")
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", example)
+	mustRun(t, s, "addfeed", "Atom", atom)
+
+	for _, args := range [][]string{
+		{"folders", "create", " "},
+		{"folders", "create"},
+		{"folders", "rename", "News"},
+		{"folders", "list", "News"},
+		{"folders", "sort"},
+	} {
+		_, err := runCommand(t, s, args...)
+		assertKind(t, err, errValidation)
+	}
+	if out := mustRun(t, s, "folders", "list"); !strings.Contains(out, "No folders") {
+		t.Errorf("folders list without folders:\n%s", out)
+	}
+
+	mustRun(t, s, "folders", "create", "News")
+	mustRun(t, s, "folders", "create", "Tech")
+	_, err := runCommand(t, s, "folders", "create", "News")
+	assertKind(t, err, errConflict)
+	_, err = runCommand(t, s, "folders", "rename", "Tech", "News")
+	assertKind(t, err, errConflict)
+	_, err = runCommand(t, s, "folders", "move", example, "Sports")
+	assertKind(t, err, errNotFound)
+
+	mustRun(t, s, "folders", "move", example, "Tech")
+	mustRun(t, s, "folders", "rename", "Tech", "Blogs")
+
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+
+	out := mustRun(t, s, "following")
+	want := "Current user feeds:\nAtom (2 unread)\nBlogs:\n  Example (2 unread)\n"
+	if out != want {
+		t.Errorf("following:\n%s\nwant:\n%s", out, want)
+	}
+	out = mustRun(t, s, "folders", "list")
+	if want := "Blogs: 1 feed, 2 unread\nNews: 0 feeds, 0 unread\n"; out != want {
+		t.Errorf("folders list:\n%s\nwant:\n%s", out, want)
+	}
+	out = mustRun(t, s, "following", "--folder", "Blogs")
+	if want := "Feeds in Blogs:\nExample (2 unread)\n"; out != want {
+		t.Errorf("following --folder:\n%s\nwant:\n%s", out, want)
+	}
+
+	out = mustRun(t, s, "browse", "--folder", "Blogs", "10")
+	if !strings.Contains(out, "First post") || strings.Contains(out, "Atom entry") {
+		t.Errorf("browse --folder shows posts of other folders:\n%s", out)
+	}
+	if out := mustRun(t, s, "browse", "--folder", "News", "10"); out != "Current user posts:\n" {
+		t.Errorf("browse --folder of an empty folder:\n%s", out)
+	}
+	_, err = runCommand(t, s, "browse", "--folder", "Tech")
+	assertKind(t, err, errNotFound)
+
+	// Folders are private to their user.
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "follow", example)
+	_, err = runCommand(t, s, "folders", "move", example, "Blogs")
+	assertKind(t, err, errNotFound)
+	_, err = runCommand(t, s, "folders", "move", atom)
+	assertKind(t, err, errNotFound)
+	if out := mustRun(t, s, "following"); !strings.Contains(out, "\nExample (2 unread)\n") {
+		t.Errorf("following of bob:\n%s", out)
+	}
+
+	// Deleting a folder keeps its feeds, outside of any folder.
+	mustRun(t, s, "login", "alice")
+	mustRun(t, s, "folders", "delete", "Blogs")
+	out = mustRun(t, s, "following")
+	if want := "Current user feeds:\nExample (2 unread)\nAtom (2 unread)\n"; out != want {
+		t.Errorf("following after deleting the folder:\n%s\nwant:\n%s", out, want)
+	}
+
+	mustRun(t, s, "folders", "move", atom, "News")
+	mustRun(t, s, "folders", "move", atom)
+	if out := mustRun(t, s, "following", "--folder", "News"); out != "Feeds in News:\n" {
+		t.Errorf("feed was not moved out of its folder:\n%s", out)
+	}
+}
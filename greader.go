@@ -0,0 +1,601 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Stream and state identifiers used by Google Reader API clients.
+const (
+	greaderReadingList = "user/-/state/com.google/reading-list"
+	greaderRead        = "user/-/state/com.google/read"
+	greaderStarred     = "user/-/state/com.google/starred"
+	greaderItemPrefix  = "tag:google.com,2005:reader/item/"
+	greaderFeedPrefix  = "feed/"
+	// Folders are shown to clients as labels.
+	greaderLabelPrefix = "user/-/label/"
+	greaderMaxItems    = 1000
+)
+
+type greaderServer struct {
+	s *state
+}
+
+type greaderSubscription struct {
+	ID         string            `json:"id"`
+	Title      string            `json:"title"`
+	Categories []greaderCategory `json:"categories"`
+	URL        string            `json:"url"`
+	HTMLURL    string            `json:"htmlUrl"`
+	IconURL    string            `json:"iconUrl"`
+}
+
+type greaderCategory struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+type greaderTag struct {
+	ID   string `json:"id"`
+	Type string `json:"type,omitempty"`
+}
+
+type greaderLink struct {
+	Href string `json:"href"`
+	Type string `json:"type,omitempty"`
+}
+
+type greaderContent struct {
+	Direction string `json:"direction"`
+	Content   string `json:"content"`
+}
+
+type greaderOrigin struct {
+	StreamID string `json:"streamId"`
+	Title    string `json:"title"`
+	HTMLURL  string `json:"htmlUrl"`
+}
+
+type greaderItem struct {
+	ID            string         `json:"id"`
+	CrawlTimeMsec string         `json:"crawlTimeMsec"`
+	TimestampUsec string         `json:"timestampUsec"`
+	Published     int64          `json:"published"`
+	Updated       int64          `json:"updated"`
+	Title         string         `json:"title"`
+	Canonical     []greaderLink  `json:"canonical"`
+	Alternate     []greaderLink  `json:"alternate"`
+	Summary       greaderContent `json:"summary"`
+	// Content holds the full article, when it was fetched.
+	Content    *greaderContent `json:"content,omitempty"`
+	Categories []string        `json:"categories"`
+	Origin     greaderOrigin   `json:"origin"`
+}
+
+type greaderStream struct {
+	ID           string        `json:"id"`
+	Updated      int64         `json:"updated"`
+	Items        []greaderItem `json:"items"`
+	Continuation string        `json:"continuation,omitempty"`
+}
+
+type greaderItemRef struct {
+	ID string `json:"id"`
+}
+
+type greaderUnreadCount struct {
+	ID                      string `json:"id"`
+	Count                   int64  `json:"count"`
+	NewestItemTimestampUsec string `json:"newestItemTimestampUsec"`
+}
+
+// greaderQuery holds the filters shared by the stream endpoints.
+type greaderQuery struct {
+	streamID string
+	params   database.GetReaderItemsParams
+}
+
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func handlerAPIToken(s *state, cmd command, user database.User) error {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Errorf("error generating token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	params := database.SetUserAPITokenHashParams{
+		ID:           user.ID,
+		ApiTokenHash: sql.NullString{String: hashAPIToken(token), Valid: true},
+	}
+	err := s.db.SetUserAPITokenHash(context.Background(), params)
+	if err != nil {
+		return databaseError("error saving api token: %w", err)
+	}
+
+	fmt.Printf("New API token for %v (any previous token is now invalid):\n", user.Name)
+	fmt.Println(token)
+	fmt.Println("Use your username and this token as the password in Google Reader API clients.")
+
+	return nil
+}
+
+func (g *greaderServer) register(mux *http.ServeMux) {
+	mux.HandleFunc("POST /accounts/ClientLogin", g.handleClientLogin)
+	mux.HandleFunc("GET /reader/api/0/token", g.authenticated(g.handleToken))
+	mux.HandleFunc("GET /reader/api/0/user-info", g.authenticated(g.handleUserInfo))
+	mux.HandleFunc("GET /reader/api/0/subscription/list", g.authenticated(g.handleSubscriptionList))
+	mux.HandleFunc("GET /reader/api/0/tag/list", g.authenticated(g.handleTagList))
+	mux.HandleFunc("GET /reader/api/0/unread-count", g.authenticated(g.handleUnreadCount))
+	mux.HandleFunc("GET /reader/api/0/stream/contents/{stream...}", g.authenticated(g.handleStreamContents))
+	mux.HandleFunc("GET /reader/api/0/stream/items/ids", g.authenticated(g.handleStreamItemIDs))
+	mux.HandleFunc("POST /reader/api/0/stream/items/contents", g.authenticated(g.handleStreamItemContents))
+	mux.HandleFunc("POST /reader/api/0/edit-tag", g.authenticated(g.handleEditTag))
+}
+
+func (g *greaderServer) authenticated(handler func(w http.ResponseWriter, r *http.Request, user database.User)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "GoogleLogin auth=")
+		if !found || token == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		hash := sql.NullString{String: hashAPIToken(token), Valid: true}
+		user, err := g.s.db.GetUserByAPITokenHash(r.Context(), hash)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler(w, r, user)
+	}
+}
+
+func (g *greaderServer) handleClientLogin(w http.ResponseWriter, r *http.Request) {
+	username := r.FormValue("Email")
+	token := r.FormValue("Passwd")
+
+	user, err := g.s.db.GetUser(r.Context(), username)
+	if err != nil || !user.ApiTokenHash.Valid ||
+		subtle.ConstantTimeCompare([]byte(user.ApiTokenHash.String), []byte(hashAPIToken(token))) != 1 {
+		http.Error(w, "Error=BadAuthentication", http.StatusUnauthorized)
+		return
+	}
+
+	if r.FormValue("output") == "json" {
+		writeJSON(w, map[string]string{"SID": token, "LSID": token, "Auth": token})
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "SID=%s\nLSID=%s\nAuth=%s\n", token, token, token)
+}
+
+func (g *greaderServer) handleToken(w http.ResponseWriter, r *http.Request, user database.User) {
+	// Edits are authorized through the Authorization header, so the action
+	// token only has to be stable for clients that insist on sending one.
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, strings.ReplaceAll(user.ID.String(), "-", ""))
+}
+
+func (g *greaderServer) handleUserInfo(w http.ResponseWriter, r *http.Request, user database.User) {
+	writeJSON(w, map[string]string{
+		"userId":        user.ID.String(),
+		"userName":      user.Name,
+		"userProfileId": user.ID.String(),
+		"userEmail":     "",
+	})
+}
+
+func (g *greaderServer) handleSubscriptionList(w http.ResponseWriter, r *http.Request, user database.User) {
+	follows, err := g.s.db.GetFeedFollowsForUser(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "error getting subscriptions", http.StatusInternalServerError)
+		return
+	}
+
+	subscriptions := []greaderSubscription{}
+	for _, f := range follows {
+		categories := []greaderCategory{}
+		if f.FolderName.Valid {
+			categories = append(categories, greaderCategory{ID: greaderLabelPrefix + f.FolderName.String, Label: f.FolderName.String})
+		}
+		subscriptions = append(subscriptions, greaderSubscription{
+			ID:         greaderFeedPrefix + f.FeedID.String(),
+			Title:      f.FeedName,
+			Categories: categories,
+			URL:        f.FeedUrl,
+			HTMLURL:    f.FeedUrl,
+		})
+	}
+
+	writeJSON(w, map[string][]greaderSubscription{"subscriptions": subscriptions})
+}
+
+func (g *greaderServer) handleTagList(w http.ResponseWriter, r *http.Request, user database.User) {
+	folders, err := g.s.db.GetFoldersForUser(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "error getting folders", http.StatusInternalServerError)
+		return
+	}
+
+	tags := []greaderTag{{ID: greaderStarred}}
+	for _, f := range folders {
+		tags = append(tags, greaderTag{ID: greaderLabelPrefix + f.Name, Type: "folder"})
+	}
+	writeJSON(w, map[string][]greaderTag{"tags": tags})
+}
+
+func (g *greaderServer) handleUnreadCount(w http.ResponseWriter, r *http.Request, user database.User) {
+	counts, err := g.s.db.GetUnreadCountsForUser(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "error getting unread counts", http.StatusInternalServerError)
+		return
+	}
+	follows, err := g.s.db.GetFeedFollowsForUser(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "error getting subscriptions", http.StatusInternalServerError)
+		return
+	}
+	folderOf := map[uuid.UUID]string{}
+	for _, f := range follows {
+		if f.FolderName.Valid {
+			folderOf[f.FeedID] = f.FolderName.String
+		}
+	}
+
+	var total int64
+	var newest time.Time
+	unreadCounts := []greaderUnreadCount{}
+	var folders []string
+	folderUnread := map[string]int64{}
+	folderNewest := map[string]time.Time{}
+	for _, c := range counts {
+		total += c.Unread
+		if c.Newest.After(newest) {
+			newest = c.Newest
+		}
+		unreadCounts = append(unreadCounts, greaderUnreadCount{
+			ID:                      greaderFeedPrefix + c.FeedID.UUID.String(),
+			Count:                   c.Unread,
+			NewestItemTimestampUsec: strconv.FormatInt(c.Newest.UnixMicro(), 10),
+		})
+
+		folder, ok := folderOf[c.FeedID.UUID]
+		if !ok {
+			continue
+		}
+		if _, seen := folderUnread[folder]; !seen {
+			folders = append(folders, folder)
+		}
+		folderUnread[folder] += c.Unread
+		if c.Newest.After(folderNewest[folder]) {
+			folderNewest[folder] = c.Newest
+		}
+	}
+	for _, folder := range folders {
+		unreadCounts = append(unreadCounts, greaderUnreadCount{
+			ID:                      greaderLabelPrefix + folder,
+			Count:                   folderUnread[folder],
+			NewestItemTimestampUsec: strconv.FormatInt(folderNewest[folder].UnixMicro(), 10),
+		})
+	}
+	unreadCounts = append(unreadCounts, greaderUnreadCount{
+		ID:                      greaderReadingList,
+		Count:                   total,
+		NewestItemTimestampUsec: strconv.FormatInt(newest.UnixMicro(), 10),
+	})
+
+	writeJSON(w, map[string]any{"max": greaderMaxItems, "unreadcounts": unreadCounts})
+}
+
+func (g *greaderServer) handleStreamContents(w http.ResponseWriter, r *http.Request, user database.User) {
+	streamID := r.PathValue("stream")
+	if streamID == "" {
+		streamID = r.FormValue("s")
+	}
+
+	query, err := g.parseQuery(r, user, streamID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	posts, err := g.s.db.GetReaderItems(r.Context(), query.params)
+	if err != nil {
+		http.Error(w, "error getting items", http.StatusInternalServerError)
+		return
+	}
+
+	stream := greaderStream{
+		ID:      query.streamID,
+		Updated: time.Now().Unix(),
+		Items:   []greaderItem{},
+	}
+	for _, p := range posts {
+		stream.Items = append(stream.Items, newGreaderItem(p))
+	}
+	if len(posts) == int(query.params.MaxItems) {
+		stream.Continuation = strconv.Itoa(int(query.params.SkipItems) + len(posts))
+	}
+
+	writeJSON(w, stream)
+}
+
+func (g *greaderServer) handleStreamItemIDs(w http.ResponseWriter, r *http.Request, user database.User) {
+	query, err := g.parseQuery(r, user, r.FormValue("s"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	posts, err := g.s.db.GetReaderItems(r.Context(), query.params)
+	if err != nil {
+		http.Error(w, "error getting items", http.StatusInternalServerError)
+		return
+	}
+
+	refs := []greaderItemRef{}
+	for _, p := range posts {
+		refs = append(refs, greaderItemRef{ID: strconv.FormatInt(p.Seq, 10)})
+	}
+
+	response := map[string]any{"itemRefs": refs}
+	if len(posts) == int(query.params.MaxItems) {
+		response["continuation"] = strconv.Itoa(int(query.params.SkipItems) + len(posts))
+	}
+	writeJSON(w, response)
+}
+
+func (g *greaderServer) handleStreamItemContents(w http.ResponseWriter, r *http.Request, user database.User) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	seqs, err := parseGreaderItemIDs(r.Form["i"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params := database.GetReaderItemsBySeqParams{
+		UserID: user.ID,
+		Seqs:   seqs,
+	}
+	posts, err := g.s.db.GetReaderItemsBySeq(r.Context(), params)
+	if err != nil {
+		http.Error(w, "error getting items", http.StatusInternalServerError)
+		return
+	}
+
+	stream := greaderStream{
+		ID:      greaderReadingList,
+		Updated: time.Now().Unix(),
+		Items:   []greaderItem{},
+	}
+	for _, p := range posts {
+		stream.Items = append(stream.Items, newGreaderItem(database.GetReaderItemsRow(p)))
+	}
+
+	writeJSON(w, stream)
+}
+
+func (g *greaderServer) handleEditTag(w http.ResponseWriter, r *http.Request, user database.User) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	seqs, err := parseGreaderItemIDs(r.Form["i"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params := database.GetReaderItemsBySeqParams{
+		UserID: user.ID,
+		Seqs:   seqs,
+	}
+	posts, err := g.s.db.GetReaderItemsBySeq(r.Context(), params)
+	if err != nil {
+		http.Error(w, "error getting items", http.StatusInternalServerError)
+		return
+	}
+
+	for _, p := range posts {
+		for _, tag := range r.Form["a"] {
+			if err := g.setState(r.Context(), user.ID, p.ID, tag, true); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		for _, tag := range r.Form["r"] {
+			if err := g.setState(r.Context(), user.ID, p.ID, tag, false); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, "OK")
+}
+
+func (g *greaderServer) setState(ctx context.Context, userID, postID uuid.UUID, tag string, value bool) error {
+	switch normalizeGreaderStream(tag) {
+	case greaderRead:
+		return g.s.db.SetPostRead(ctx, database.SetPostReadParams{UserID: userID, PostID: postID, Read: value})
+	case greaderStarred:
+		return g.s.db.SetPostStarred(ctx, database.SetPostStarredParams{UserID: userID, PostID: postID, Starred: value})
+	}
+	// Labels and other states are not tracked by gator, so they are ignored.
+	return nil
+}
+
+func newGreaderItem(p database.GetReaderItemsRow) greaderItem {
+	categories := []string{greaderReadingList}
+	if p.Read {
+		categories = append(categories, greaderRead)
+	}
+	if p.Starred {
+		categories = append(categories, greaderStarred)
+	}
+
+	item := greaderItem{
+		ID:            fmt.Sprintf("%s%016x", greaderItemPrefix, p.Seq),
+		CrawlTimeMsec: strconv.FormatInt(p.CreatedAt.UnixMilli(), 10),
+		TimestampUsec: strconv.FormatInt(p.PublishedAt.UnixMicro(), 10),
+		Published:     p.PublishedAt.Unix(),
+		Updated:       p.UpdatedAt.Unix(),
+		Title:         p.Title,
+		Canonical:     []greaderLink{{Href: p.Url}},
+		Alternate:     []greaderLink{{Href: p.Url, Type: "text/html"}},
+		Summary:       greaderContent{Direction: "ltr", Content: p.Description.String},
+		Categories:    categories,
+		Origin: greaderOrigin{
+			StreamID: greaderFeedPrefix + p.FeedID.UUID.String(),
+			Title:    p.FeedName,
+			HTMLURL:  p.FeedUrl,
+		},
+	}
+	if p.Content.Valid {
+		item.Content = &greaderContent{Direction: "ltr", Content: p.Content.String}
+	}
+	return item
+}
+
+func (g *greaderServer) parseQuery(r *http.Request, user database.User, streamID string) (greaderQuery, error) {
+	query := greaderQuery{
+		streamID: streamID,
+		params: database.GetReaderItemsParams{
+			UserID:   user.ID,
+			MaxItems: 20,
+		},
+	}
+
+	switch stream := normalizeGreaderStream(streamID); {
+	case stream == "" || stream == greaderReadingList:
+		query.streamID = greaderReadingList
+	case stream == greaderStarred:
+		query.params.OnlyStarred = true
+	case stream == greaderRead:
+		query.params.OnlyRead = true
+	case strings.HasPrefix(stream, greaderFeedPrefix):
+		feedID, err := uuid.Parse(strings.TrimPrefix(stream, greaderFeedPrefix))
+		if err != nil {
+			return greaderQuery{}, fmt.Errorf("unknown stream: %v", streamID)
+		}
+		query.params.FeedID = uuid.NullUUID{UUID: feedID, Valid: true}
+	case strings.HasPrefix(stream, greaderLabelPrefix):
+		name := strings.TrimPrefix(stream, greaderLabelPrefix)
+		folder, err := g.s.db.GetFolderByName(r.Context(), database.GetFolderByNameParams{UserID: user.ID, Name: name})
+		if err != nil {
+			return greaderQuery{}, fmt.Errorf("unknown stream: %v", streamID)
+		}
+		query.params.FolderID = uuid.NullUUID{UUID: folder.ID, Valid: true}
+	default:
+		return greaderQuery{}, fmt.Errorf("unknown stream: %v", streamID)
+	}
+
+	if n := r.FormValue("n"); n != "" {
+		count, err := strconv.Atoi(n)
+		if err != nil || count < 1 {
+			return greaderQuery{}, fmt.Errorf("invalid item count: %v", n)
+		}
+		query.params.MaxItems = int32(min(count, greaderMaxItems))
+	}
+	if c := r.FormValue("c"); c != "" {
+		offset, err := strconv.Atoi(c)
+		if err != nil || offset < 0 {
+			return greaderQuery{}, fmt.Errorf("invalid continuation: %v", c)
+		}
+		query.params.SkipItems = int32(offset)
+	}
+	if ot := r.FormValue("ot"); ot != "" {
+		seconds, err := strconv.ParseInt(ot, 10, 64)
+		if err != nil {
+			return greaderQuery{}, fmt.Errorf("invalid start time: %v", ot)
+		}
+		query.params.NewerThan = sql.NullTime{Time: time.Unix(seconds, 0), Valid: true}
+	}
+	if nt := r.FormValue("nt"); nt != "" {
+		seconds, err := strconv.ParseInt(nt, 10, 64)
+		if err != nil {
+			return greaderQuery{}, fmt.Errorf("invalid end time: %v", nt)
+		}
+		query.params.OlderThan = sql.NullTime{Time: time.Unix(seconds, 0), Valid: true}
+	}
+
+	query.params.OldestFirst = r.FormValue("r") == "o"
+	if normalizeGreaderStream(r.FormValue("xt")) == greaderRead {
+		query.params.ExcludeRead = true
+	}
+	if normalizeGreaderStream(r.FormValue("it")) == greaderStarred {
+		query.params.OnlyStarred = true
+	}
+
+	return query, nil
+}
+
+// normalizeGreaderStream rewrites user/<id>/... stream ids to the user/-/...
+// form so callers only need to compare against one spelling.
+func normalizeGreaderStream(streamID string) string {
+	rest, found := strings.CutPrefix(streamID, "user/")
+	if !found {
+		return streamID
+	}
+	_, state, found := strings.Cut(rest, "/")
+	if !found {
+		return streamID
+	}
+	return "user/-/" + state
+}
+
+// parseGreaderItemIDs accepts both the long tag:google.com form (hex) and the
+// short decimal form that clients send interchangeably.
+func parseGreaderItemIDs(ids []string) ([]int64, error) {
+	if len(ids) == 0 {
+		return nil, errors.New("no item ids passed")
+	}
+
+	seqs := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		var seq uint64
+		var err error
+		if hexID, found := strings.CutPrefix(id, greaderItemPrefix); found {
+			seq, err = strconv.ParseUint(hexID, 16, 64)
+		} else {
+			seq, err = strconv.ParseUint(id, 10, 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid item id: %v", id)
+		}
+		seqs = append(seqs, int64(seq))
+	}
+
+	return seqs, nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "error encoding response", http.StatusInternalServerError)
+	}
+}
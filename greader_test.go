@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// greaderClient talks to the Google Reader API of a test state, as the
+// user whose API token it holds.
+type greaderClient struct {
+	t      *testing.T
+	server *httptest.Server
+	token  string
+}
+
+// newGreaderClient serves the Google Reader API of s and creates an API
+// token for its current user.
+func newGreaderClient(t *testing.T, s *state) *greaderClient {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	greader := greaderServer{s: s}
+	greader.register(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	out := mustRun(t, s, "apitoken")
+	lines := strings.Split(out, "\n")
+	if len(lines) < 2 || lines[1] == "" {
+		t.Fatalf("apitoken printed no token:\n%s", out)
+	}
+	return &greaderClient{t: t, server: server, token: lines[1]}
+}
+
+// do sends a request with the client's token, or with no Authorization
+// header when token is empty, and returns the status and body.
+func (c *greaderClient) do(method, path string, form url.Values, token string) (int, string) {
+	c.t.Helper()
+
+	var body io.Reader
+	target := c.server.URL + path
+	if method == http.MethodPost {
+		body = strings.NewReader(form.Encode())
+	} else if form != nil {
+		target += "?" + form.Encode()
+	}
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "GoogleLogin auth="+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return resp.StatusCode, string(b)
+}
+
+// stream fetches stream contents and decodes them.
+func (c *greaderClient) stream(streamID string, form url.Values) greaderStream {
+	c.t.Helper()
+
+	status, body := c.do(http.MethodGet, "/reader/api/0/stream/contents/"+streamID, form, c.token)
+	if status != http.StatusOK {
+		c.t.Fatalf("stream contents of %v: %v %s", streamID, status, body)
+	}
+	var stream greaderStream
+	if err := json.Unmarshal([]byte(body), &stream); err != nil {
+		c.t.Fatalf("decoding stream contents: %v\n%s", err, body)
+	}
+	return stream
+}
+
+func TestGreaderAuthentication(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "alice")
+	client := newGreaderClient(t, s)
+
+	for _, form := range []url.Values{
+		{"Email": {"alice"}, "Passwd": {"wrong"}},
+		{"Email": {"bob"}, "Passwd": {client.token}},
+		{"Email": {"alice"}},
+	} {
+		status, body := client.do(http.MethodPost, "/accounts/ClientLogin", form, "")
+		if status != http.StatusUnauthorized || !strings.Contains(body, "BadAuthentication") {
+			t.Errorf("ClientLogin with %v: %v %s", form, status, body)
+		}
+	}
+	status, body := client.do(http.MethodPost, "/accounts/ClientLogin", url.Values{"Email": {"alice"}, "Passwd": {client.token}}, "")
+	if status != http.StatusOK || !strings.Contains(body, "Auth="+client.token+"\n") {
+		t.Errorf("ClientLogin: %v %s", status, body)
+	}
+
+	for _, token := range []string{"", "not-a-token"} {
+		if status, _ := client.do(http.MethodGet, "/reader/api/0/subscription/list", nil, token); status != http.StatusUnauthorized {
+			t.Errorf("subscription/list with token %q returned %v, want 401", token, status)
+		}
+	}
+	if status, body := client.do(http.MethodGet, "/reader/api/0/subscription/list", nil, client.token); status != http.StatusOK {
+		t.Errorf("subscription/list: %v %s", status, body)
+	}
+
+	// A new token replaces the previous one.
+	old := client.token
+	client = newGreaderClient(t, s)
+	if status, _ := client.do(http.MethodGet, "/reader/api/0/user-info", nil, old); status != http.StatusUnauthorized {
+		t.Errorf("user-info with a replaced token returned %v, want 401", status)
+	}
+}
+
+func TestGreaderStreamContentsContinuation(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", server.feedURL("example.rss"))
+	mustRun(t, s, "addfeed", "Tagged", server.feedURL("categories.rss"))
+	for range 2 {
+		if err := scrapeFeeds(s); err != nil {
+			t.Fatalf("scrapeFeeds: %v", err)
+		}
+	}
+	client := newGreaderClient(t, s)
+
+	seen := map[string]bool{}
+	var pages []int
+	form := url.Values{"n": {"2"}}
+	for {
+		stream := client.stream(greaderReadingList, form)
+		pages = append(pages, len(stream.Items))
+		for _, item := range stream.Items {
+			if seen[item.ID] {
+				t.Errorf("item %v is on two pages", item.ID)
+			}
+			seen[item.ID] = true
+		}
+		if stream.Continuation == "" {
+			break
+		}
+		if len(pages) > 5 {
+			t.Fatal("the stream does not end")
+		}
+		form.Set("c", stream.Continuation)
+	}
+	if len(seen) != 5 || len(pages) != 3 || pages[2] != 1 {
+		t.Errorf("got pages of %v items, want 2, 2 and 1", pages)
+	}
+
+	stream := client.stream(greaderReadingList, url.Values{"n": {"5"}, "c": {"4"}})
+	if len(stream.Items) != 1 || stream.Continuation != "" {
+		t.Errorf("last page: %d items, continuation %q", len(stream.Items), stream.Continuation)
+	}
+
+	for _, form := range []url.Values{{"n": {"0"}}, {"c": {"-1"}}} {
+		status, _ := client.do(http.MethodGet, "/reader/api/0/stream/contents/"+greaderReadingList, form, client.token)
+		if status != http.StatusBadRequest {
+			t.Errorf("stream contents with %v returned %v, want 400", form, status)
+		}
+	}
+	status, _ := client.do(http.MethodGet, "/reader/api/0/stream/contents/feed/not-a-feed", nil, client.token)
+	if status != http.StatusBadRequest {
+		t.Errorf("stream contents of an unknown stream returned %v, want 400", status)
+	}
+}
+
+func TestGreaderEditTag(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", server.feedURL("example.rss"))
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+	client := newGreaderClient(t, s)
+
+	items := client.stream(greaderReadingList, nil).Items
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+	id := items[0].ID
+
+	editTag := func(form url.Values) {
+		t.Helper()
+		form.Set("i", id)
+		status, body := client.do(http.MethodPost, "/reader/api/0/edit-tag", form, client.token)
+		if status != http.StatusOK || body != "OK" {
+			t.Fatalf("edit-tag %v: %v %s", form, status, body)
+		}
+	}
+
+	editTag(url.Values{"a": {greaderRead}})
+	unread := client.stream(greaderReadingList, url.Values{"xt": {greaderRead}}).Items
+	if len(unread) != 1 || unread[0].ID == id {
+		t.Errorf("unread items after marking %v read: %+v", id, unread)
+	}
+	if read := client.stream(greaderRead, nil).Items; len(read) != 1 || read[0].ID != id {
+		t.Errorf("read items: %+v", read)
+	}
+
+	// Clients send the user id instead of "-" in state names too.
+	editTag(url.Values{"a": {"user/1234/state/com.google/starred"}, "r": {greaderRead}})
+	starred := client.stream(greaderStarred, nil).Items
+	if len(starred) != 1 || starred[0].ID != id {
+		t.Fatalf("starred items: %+v", starred)
+	}
+	if categories := starred[0].Categories; !slices.Contains(categories, greaderStarred) || slices.Contains(categories, greaderRead) {
+		t.Errorf("categories of the starred item: %v", categories)
+	}
+
+	editTag(url.Values{"r": {greaderStarred}})
+	if starred := client.stream(greaderStarred, nil).Items; len(starred) != 0 {
+		t.Errorf("starred items after unstarring: %+v", starred)
+	}
+
+	status, _ := client.do(http.MethodPost, "/reader/api/0/edit-tag", url.Values{"i": {id}, "a": {greaderRead}}, "")
+	if status != http.StatusUnauthorized {
+		t.Errorf("edit-tag without a token returned %v, want 401", status)
+	}
+	status, _ = client.do(http.MethodPost, "/reader/api/0/edit-tag", url.Values{"i": {"nope"}, "a": {greaderRead}}, client.token)
+	if status != http.StatusBadRequest {
+		t.Errorf("edit-tag of an invalid item returned %v, want 400", status)
+	}
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alifoo/blog-aggregator/internal/config"
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/alifoo/blog-aggregator/internal/storage/storagetest"
+)
+
+// newTestState returns a state backed by an empty in-memory store and a
+// config file in a temporary directory.
+func newTestState(t *testing.T) *state {
+	t.Helper()
+
+	for _, key := range config.Keys {
+		t.Setenv(config.EnvName(key), "")
+	}
+	t.Setenv("GATOR_CONFIG", "")
+	t.Setenv("GATOR_PROFILE", "")
+
+	cfg, err := config.Load(config.Options{Path: filepath.Join(t.TempDir(), "config.json")})
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+	store := storagetest.NewMemory()
+	t.Cleanup(func() { store.Close() })
+
+	// The memory store has no schema to check.
+	return &state{db: store, configPointer: &cfg, output: outputPlain, schemaChecked: true}
+}
+
+// newStateWithFeed returns a state where alice, the current user, added a
+// feed named Example serving fixture and fetched it once, along with the
+// server serving it.
+func newStateWithFeed(t *testing.T, fixture string) (*state, *feedServer) {
+	t.Helper()
+
+	s := newTestState(t)
+	server := newFeedServer(t)
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", server.feedURL(fixture))
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+	return s, server
+}
+
+// runCommand runs a command line against s and returns what it printed.
+func runCommand(t *testing.T, s *state, args ...string) (string, error) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		output <- string(b)
+	}()
+
+	err = newCommands().run(s, command{name: args[0], arguments: args[1:]})
+	w.Close()
+	return <-output, err
+}
+
+// mustRun runs a command line that is expected to succeed.
+func mustRun(t *testing.T, s *state, args ...string) string {
+	t.Helper()
+
+	out, err := runCommand(t, s, args...)
+	if err != nil {
+		t.Fatalf("%v: %v", strings.Join(args, " "), err)
+	}
+	return out
+}
+
+func assertKind(t *testing.T, err error, want errorKind) {
+	t.Helper()
+
+	if err == nil {
+		t.Fatalf("expected an error of kind %v, got nil", want)
+	}
+	if got := kindOf(err); got != want {
+		t.Fatalf("expected an error of kind %v, got %v: %v", want, got, err)
+	}
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	s := newTestState(t)
+
+	mustRun(t, s, "register", "alice")
+	if s.configPointer.CurrentUserName != "alice" {
+		t.Fatalf("current user is %q after register, want alice", s.configPointer.CurrentUserName)
+	}
+
+	_, err := runCommand(t, s, "register", "alice")
+	assertKind(t, err, errConflict)
+
+	_, err = runCommand(t, s, "login", "bob")
+	assertKind(t, err, errNotFound)
+
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "login", "alice")
+	if s.configPointer.CurrentUserName != "alice" {
+		t.Fatalf("current user is %q after login, want alice", s.configPointer.CurrentUserName)
+	}
+
+	out := mustRun(t, s, "users")
+	if !strings.Contains(out, "alice (current)") || !strings.Contains(out, "bob") {
+		t.Errorf("users output is missing a user:\n%s", out)
+	}
+}
+
+func TestAddFeedFollowsIt(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+	url := server.feedURL("example.rss")
+
+	_, err := runCommand(t, s, "addfeed", "Example", url)
+	assertKind(t, err, errNotFound)
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", url)
+
+	_, err = runCommand(t, s, "addfeed", "Example again", url)
+	assertKind(t, err, errConflict)
+
+	out := mustRun(t, s, "following")
+	if !strings.Contains(out, "Example") {
+		t.Errorf("following does not list the added feed:\n%s", out)
+	}
+	out = mustRun(t, s, "feeds")
+	if !strings.Contains(out, "alice") {
+		t.Errorf("feeds does not show who added the feed:\n%s", out)
+	}
+}
+
+func TestFollowAndUnfollow(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+	url := server.feedURL("example.rss")
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", url)
+	mustRun(t, s, "register", "bob")
+
+	_, err := runCommand(t, s, "follow", server.feedURL("missing.rss"))
+	assertKind(t, err, errNotFound)
+
+	mustRun(t, s, "follow", url)
+	_, err = runCommand(t, s, "follow", url)
+	assertKind(t, err, errConflict)
+
+	mustRun(t, s, "unfollow", url)
+	out := mustRun(t, s, "following")
+	if strings.Contains(out, "Example") {
+		t.Errorf("feed is still followed after unfollow:\n%s", out)
+	}
+}
+
+func TestScrapeFeedsAndBrowse(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+
+	mustRun(t, s, "register", "alice")
+	if err := scrapeFeeds(s); kindOf(err) != errNotFound {
+		t.Fatalf("scrapeFeeds without feeds returned %v, want a not found error", err)
+	}
+
+	mustRun(t, s, "addfeed", "Example", server.feedURL("example.rss"))
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+	// A second fetch finds the same posts, which must not be stored twice.
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds again: %v", err)
+	}
+	if hits := server.hits("example.rss"); hits != 2 {
+		t.Errorf("feed was fetched %d times, want 2", hits)
+	}
+
+	user, err := s.db.GetUser(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := s.db.GetReaderItems(context.Background(), database.GetReaderItemsParams{UserID: user.ID, MaxItems: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("got %d posts, want 2", len(items))
+	}
+	if items[0].Title != "Second post" || items[0].FeedName != "Example" {
+		t.Errorf("newest post is %q from %q, want Second post from Example", items[0].Title, items[0].FeedName)
+	}
+
+	out := mustRun(t, s, "browse")
+	if !strings.Contains(out, "Second post") || !strings.Contains(out, "First post") {
+		t.Errorf("browse does not list both posts:\n%s", out)
+	}
+	out = mustRun(t, s, "browse", "1")
+	if strings.Contains(out, "First post") {
+		t.Errorf("browse 1 lists more than one post:\n%s", out)
+	}
+
+	_, err = runCommand(t, s, "browse", "zero")
+	assertKind(t, err, errValidation)
+}
+
+// TestScrapeStoresPostsAfterKnownOnes checks a fetch goes past the posts an
+// earlier fetch stored and stores the ones published since.
+func TestScrapeStoresPostsAfterKnownOnes(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+
+	example, err := os.ReadFile(filepath.Join("testdata", "feeds", "example.rss"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first fetch only finds the first post.
+	second := strings.Index(string(example), "<item>\n      <title>Second post")
+	body := string(example[:second]) + "</channel>\n</rss>\n"
+	server.handle("growing.rss", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	})
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", server.feedURL("growing.rss"))
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+	if out := mustRun(t, s, "browse", "10"); strings.Contains(out, "Second post") || !strings.Contains(out, "First post") {
+		t.Fatalf("browse after the first fetch:\n%s", out)
+	}
+
+	body = string(example)
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds again: %v", err)
+	}
+	if out := mustRun(t, s, "browse", "10"); !strings.Contains(out, "Second post") || strings.Count(out, "First post") != 1 {
+		t.Errorf("browse after the second fetch:\n%s", out)
+	}
+}
+
+func TestScrapeAtomFeed(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Atom", server.feedURL("example.atom"))
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+
+	user, err := s.db.GetUser(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := s.db.GetReaderItems(context.Background(), database.GetReaderItemsParams{UserID: user.ID, MaxItems: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("got %d posts, want 2", len(items))
+	}
+
+	byURL := map[string]string{}
+	for _, item := range items {
+		byURL[item.Url] = item.Description.String
+	}
+	if got := byURL["https://atom.example.com/entries/1"]; got != "A summary of the entry." {
+		t.Errorf("entry 1 description is %q", got)
+	}
+	if got := byURL["https://atom.example.com/entries/2"]; got != "Full <b>content</b>." {
+		t.Errorf("entry 2 description is %q", got)
+	}
+}
+
+func TestScrapeFeedsUnreachable(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+	url := server.feedURL("example.rss")
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", url)
+	server.Close()
+
+	err := scrapeFeeds(s)
+	assertKind(t, err, errNetwork)
+}
+
+func TestUnknownCommand(t *testing.T) {
+	s := newTestState(t)
+
+	_, err := runCommand(t, s, "frobnicate")
+	assertKind(t, err, errValidation)
+
+	_, err = runCommand(t, s, "help", "frobnicate")
+	assertKind(t, err, errNotFound)
+}
+
+func TestCommandsWithoutDatabase(t *testing.T) {
+	s := newTestState(t)
+	s.db.Close()
+	s.db = nil
+	s.schemaChecked = false
+	if err := s.configPointer.Set(config.KeyDbUrl, "sqlite:"); err != nil {
+		t.Fatal(err)
+	}
+	s.configPointer.DbUrl = "sqlite:"
+
+	// The database is only opened by commands that use it, so a broken
+	// db_url can still be looked at and fixed.
+	mustRun(t, s, "help")
+	mustRun(t, s, "completion", "bash")
+	if out := mustRun(t, s, "__complete", "--", "login", ""); out != "" {
+		t.Errorf("__complete printed %q without a database", out)
+	}
+	if out := mustRun(t, s, "config", "get", "db_url"); strings.TrimSpace(out) != "sqlite:" {
+		t.Errorf("config get db_url printed %q", out)
+	}
+	_, err := runCommand(t, s, "users")
+	assertKind(t, err, errDatabase)
+	if s.db != nil {
+		t.Fatal("a failed open left a store behind")
+	}
+
+	dbURL := "sqlite:" + filepath.Join(t.TempDir(), "gator.db")
+	mustRun(t, s, "config", "set", "db_url", dbURL)
+	cfg, err := config.Load(config.Options{Path: s.configPointer.Path()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.configPointer = &cfg
+	// A new database has to be migrated before other commands use it.
+	if _, err := runCommand(t, s, "users"); err == nil || !strings.Contains(err.Error(), "migrate up") {
+		t.Errorf("users on an empty database returned %v, want a schema error", err)
+	}
+	mustRun(t, s, "migrate", "up")
+	t.Cleanup(func() { s.db.Close() })
+	mustRun(t, s, "register", "alice")
+	if out := mustRun(t, s, "__complete", "--", "login", ""); strings.TrimSpace(out) != "alice" {
+		t.Errorf("__complete printed %q", out)
+	}
+}
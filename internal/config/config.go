@@ -2,59 +2,358 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 )
 
-const configFileName = ".gatorconfig.json"
+const (
+	configFileName = ".gatorconfig.json"
+	xdgDirName     = "gator"
+	xdgFileName    = "config.json"
 
+	// DefaultProfile names the values stored at the top level of the file,
+	// where they were kept before profiles existed.
+	DefaultProfile = "default"
+
+	envPrefix  = "GATOR_"
+	envConfig  = envPrefix + "CONFIG"
+	envProfile = envPrefix + "PROFILE"
+)
+
+// Keys that can be read and written with Get and Set. Each can also be
+// overridden with the GATOR_ environment variable of the same name in
+// upper case, e.g. GATOR_DB_URL.
+const (
+	KeyDbUrl           = "db_url"
+	KeyCurrentUserName = "current_user_name"
+	// KeyProfile is the profile used when neither --profile nor
+	// GATOR_PROFILE is given. It is stored once per file, not per profile.
+	KeyProfile = "profile"
+
+	// Settings of the HTTP client that fetches feeds. Empty values leave
+	// the defaults of the fetcher in place.
+	KeyFetchTimeout       = "fetch_timeout"
+	KeyConnectTimeout     = "connect_timeout"
+	KeyMaxFeedSize        = "max_feed_size"
+	KeyHTTPProxy          = "http_proxy"
+	KeyUserAgent          = "user_agent"
+	KeyMaxHostConnections = "max_host_connections"
+)
+
+// profileKeys are the keys stored per profile, in listing order.
+var profileKeys = []string{
+	KeyDbUrl,
+	KeyCurrentUserName,
+	KeyFetchTimeout,
+	KeyConnectTimeout,
+	KeyMaxFeedSize,
+	KeyHTTPProxy,
+	KeyUserAgent,
+	KeyMaxHostConnections,
+}
+
+// Keys lists every key accepted by Get and Set.
+var Keys = append(append([]string(nil), profileKeys...), KeyProfile)
+
+// ErrUnknownKey is returned by Get and Set for keys that do not exist.
+var ErrUnknownKey = errors.New("unknown config key")
+
+// Config holds the values in effect for one run: those of the selected
+// profile, with environment variables applied on top.
 type Config struct {
-	DbUrl string `json:"db_url"`
-	CurrentUserName string `json:"current_user_name"`
+	DbUrl           string
+	CurrentUserName string
+
+	path          string
+	profile       string
+	profileSource string
+	file          fileConfig
 }
 
-func (c *Config) SetUser(username string) error {
-	c.CurrentUserName = username
-	return write(*c)
+// Options select which config file and profile to load. Empty fields fall
+// back to the environment and then to the defaults.
+type Options struct {
+	Path    string
+	Profile string
 }
 
-func write(cfg Config) error {
-	filePath, err := getConfigFilePath()
-	if err != nil {
-        return err
-    }
+// Setting is one key of the effective configuration and where its value
+// came from.
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+}
 
-	data, err := json.Marshal(cfg)
-	if err != nil {
-        return err
-    }
+type profileConfig struct {
+	DbUrl              string `json:"db_url"`
+	CurrentUserName    string `json:"current_user_name"`
+	FetchTimeout       string `json:"fetch_timeout,omitempty"`
+	ConnectTimeout     string `json:"connect_timeout,omitempty"`
+	MaxFeedSize        string `json:"max_feed_size,omitempty"`
+	HTTPProxy          string `json:"http_proxy,omitempty"`
+	UserAgent          string `json:"user_agent,omitempty"`
+	MaxHostConnections string `json:"max_host_connections,omitempty"`
+}
 
-	return os.WriteFile(filePath, data, 0644)
+// field returns the stored value of key, or nil if key is not stored per
+// profile.
+func (p *profileConfig) field(key string) *string {
+	switch key {
+	case KeyDbUrl:
+		return &p.DbUrl
+	case KeyCurrentUserName:
+		return &p.CurrentUserName
+	case KeyFetchTimeout:
+		return &p.FetchTimeout
+	case KeyConnectTimeout:
+		return &p.ConnectTimeout
+	case KeyMaxFeedSize:
+		return &p.MaxFeedSize
+	case KeyHTTPProxy:
+		return &p.HTTPProxy
+	case KeyUserAgent:
+		return &p.UserAgent
+	case KeyMaxHostConnections:
+		return &p.MaxHostConnections
+	}
+	return nil
 }
 
-func getConfigFilePath() (string, error) {
-	dirname, err := os.UserHomeDir()
-    if err != nil {
-        return "", err
-    }
-	return filepath.Join(dirname, configFileName), nil
+type fileConfig struct {
+	profileConfig
+	CurrentProfile string                   `json:"current_profile,omitempty"`
+	Profiles       map[string]profileConfig `json:"profiles,omitempty"`
 }
 
+// Read loads the config using only the environment and the defaults.
 func Read() (Config, error) {
-	filePath, err := getConfigFilePath()
+	return Load(Options{})
+}
+
+// Load reads the config file chosen by opts, GATOR_CONFIG or the default
+// locations. A missing file is not an error, so that a config can be
+// given entirely through the environment or created with Set.
+func Load(opts Options) (Config, error) {
+	filePath, err := resolvePath(opts.Path)
 	if err != nil {
-        return Config{}, err
-    }
+		return Config{}, err
+	}
 
+	var file fileConfig
 	data, err := os.ReadFile(filePath)
-	if err != nil {
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return Config{}, err
 	}
+	if err == nil {
+		if err := json.Unmarshal(data, &file); err != nil {
+			return Config{}, fmt.Errorf("error parsing %v: %w", filePath, err)
+		}
+	}
+
+	cfg := Config{path: filePath, file: file}
+	switch {
+	case opts.Profile != "":
+		cfg.profile, cfg.profileSource = opts.Profile, "flag"
+	case os.Getenv(envProfile) != "":
+		cfg.profile, cfg.profileSource = os.Getenv(envProfile), "env "+envProfile
+	case file.CurrentProfile != "":
+		cfg.profile, cfg.profileSource = file.CurrentProfile, "file"
+	default:
+		cfg.profile, cfg.profileSource = DefaultProfile, "default"
+	}
+
+	values := cfg.stored()
+	cfg.DbUrl = firstNonEmpty(os.Getenv(EnvName(KeyDbUrl)), values.DbUrl)
+	cfg.CurrentUserName = firstNonEmpty(os.Getenv(EnvName(KeyCurrentUserName)), values.CurrentUserName)
+	return cfg, nil
+}
+
+// Path returns the location of the config file, whether or not it exists.
+func (c *Config) Path() string {
+	return c.path
+}
+
+// Profile returns the name of the profile in use.
+func (c *Config) Profile() string {
+	return c.profile
+}
+
+// Profiles returns the names of the profiles in the file, default first.
+func (c *Config) Profiles() []string {
+	var names []string
+	for name := range c.file.Profiles {
+		if name != DefaultProfile {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{DefaultProfile}, names...)
+}
+
+func (c *Config) SetUser(username string) error {
+	if err := c.Set(KeyCurrentUserName, username); err != nil {
+		return err
+	}
+	c.CurrentUserName = username
+	return nil
+}
+
+// Get returns the effective value of key.
+func (c *Config) Get(key string) (string, error) {
+	for _, setting := range c.List() {
+		if setting.Key == key {
+			return setting.Value, nil
+		}
+	}
+	return "", unknownKeyError(key)
+}
+
+// List returns every key with its effective value and source.
+func (c *Config) List() []Setting {
+	stored := c.stored()
+	var settings []Setting
+	for _, key := range profileKeys {
+		settings = append(settings, c.setting(key, *stored.field(key)))
+	}
+	return append(settings, Setting{Key: KeyProfile, Value: c.profile, Source: c.profileSource})
+}
+
+func (c *Config) setting(key, stored string) Setting {
+	if value := os.Getenv(EnvName(key)); value != "" {
+		return Setting{Key: key, Value: value, Source: "env " + EnvName(key)}
+	}
+	if stored == "" {
+		return Setting{Key: key, Source: "unset"}
+	}
+	return Setting{Key: key, Value: stored, Source: "profile " + c.profile}
+}
+
+// Set stores value for key in the current profile and writes the file.
+// Environment variables still take precedence over the stored value.
+func (c *Config) Set(key, value string) error {
+	if key == KeyProfile {
+		if value == DefaultProfile {
+			value = ""
+		}
+		c.file.CurrentProfile = value
+		return c.write()
+	}
+
+	values := c.stored()
+	field := values.field(key)
+	if field == nil {
+		return unknownKeyError(key)
+	}
+	*field = value
+
+	if c.profile == DefaultProfile {
+		c.file.profileConfig = values
+	} else {
+		if c.file.Profiles == nil {
+			c.file.Profiles = map[string]profileConfig{}
+		}
+		c.file.Profiles[c.profile] = values
+	}
+	return c.write()
+}
+
+// stored returns the values of the current profile as saved in the file.
+func (c *Config) stored() profileConfig {
+	if c.profile == DefaultProfile {
+		return c.file.profileConfig
+	}
+	return c.file.Profiles[c.profile]
+}
 
-	var config Config
-	err = json.Unmarshal(data, &config)
+// write replaces the config file atomically: the new contents go to a
+// temporary file in the same directory, which is then renamed over the old
+// one, so a crash never leaves a truncated file. The file holds the
+// database password and is only readable by its owner.
+func (c *Config) write() error {
+	data, err := json.MarshalIndent(c.file, "", "  ")
 	if err != nil {
-		return Config{}, err
+		return err
+	}
+
+	dir := filepath.Dir(c.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// Exists reports whether the config file has been created.
+func (c *Config) Exists() bool {
+	_, err := os.Stat(c.path)
+	return err == nil
+}
+
+// resolvePath picks the config file: an explicit path, then GATOR_CONFIG,
+// then an existing file under XDG_CONFIG_HOME or ~/.gatorconfig.json, and
+// finally the XDG location for a file that is yet to be created.
+func resolvePath(explicit string) (string, error) {
+	if path := firstNonEmpty(explicit, os.Getenv(envConfig)); path != "" {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	configHome := firstNonEmpty(os.Getenv("XDG_CONFIG_HOME"), filepath.Join(home, ".config"))
+	xdgPath := filepath.Join(configHome, xdgDirName, xdgFileName)
+	legacyPath := filepath.Join(home, configFileName)
+
+	for _, path := range []string{xdgPath, legacyPath} {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return xdgPath, nil
+}
+
+// EnvName returns the environment variable that overrides key.
+func EnvName(key string) string {
+	return envPrefix + strings.ToUpper(key)
+}
+
+func unknownKeyError(key string) error {
+	return fmt.Errorf("%w %q, expected one of: %v", ErrUnknownKey, key, strings.Join(Keys, ", "))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
 	}
-	return config, nil
+	return ""
 }
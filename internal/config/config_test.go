@@ -0,0 +1,221 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// isolate points HOME and XDG_CONFIG_HOME at empty directories and clears
+// every GATOR_ variable, returning the home directory.
+func isolate(t *testing.T) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv(envConfig, "")
+	t.Setenv(envProfile, "")
+	for _, key := range Keys {
+		t.Setenv(EnvName(key), "")
+	}
+	return home
+}
+
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	home := isolate(t)
+	path := filepath.Join(home, "config.json")
+	writeFile(t, path, `{
+  "db_url": "postgres://file",
+  "current_user_name": "alice",
+  "current_profile": "work",
+  "profiles": {
+    "work": {"db_url": "postgres://work", "current_user_name": "bob"},
+    "home": {"db_url": "postgres://home"}
+  }
+}`)
+
+	load := func(opts Options) Config {
+		t.Helper()
+		opts.Path = path
+		cfg, err := Load(opts)
+		if err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		return cfg
+	}
+	source := func(cfg Config, key string) string {
+		t.Helper()
+		for _, setting := range cfg.List() {
+			if setting.Key == key {
+				return setting.Source
+			}
+		}
+		t.Fatalf("no setting %v", key)
+		return ""
+	}
+
+	// The profile saved in the file beats the top level values.
+	cfg := load(Options{})
+	if cfg.Profile() != "work" || cfg.DbUrl != "postgres://work" || cfg.CurrentUserName != "bob" {
+		t.Errorf("file profile: got %v, %v, %v", cfg.Profile(), cfg.DbUrl, cfg.CurrentUserName)
+	}
+	if got := source(cfg, KeyDbUrl); got != "profile work" {
+		t.Errorf("db_url comes from %v", got)
+	}
+
+	// GATOR_PROFILE beats the file, and --profile beats GATOR_PROFILE.
+	t.Setenv(envProfile, DefaultProfile)
+	if cfg := load(Options{}); cfg.Profile() != DefaultProfile || cfg.DbUrl != "postgres://file" {
+		t.Errorf("env profile: got %v, %v", cfg.Profile(), cfg.DbUrl)
+	}
+	cfg = load(Options{Profile: "home"})
+	if cfg.Profile() != "home" || cfg.DbUrl != "postgres://home" || cfg.CurrentUserName != "" {
+		t.Errorf("flag profile: got %v, %v, %q", cfg.Profile(), cfg.DbUrl, cfg.CurrentUserName)
+	}
+	if got := source(cfg, KeyProfile); got != "flag" {
+		t.Errorf("profile comes from %v", got)
+	}
+
+	// Environment variables beat every profile, including on Get.
+	t.Setenv(EnvName(KeyDbUrl), "postgres://env")
+	cfg = load(Options{Profile: "home"})
+	if value, _ := cfg.Get(KeyDbUrl); cfg.DbUrl != "postgres://env" || value != "postgres://env" {
+		t.Errorf("env db_url: got %v, Get %v", cfg.DbUrl, value)
+	}
+	if got := source(cfg, KeyDbUrl); got != "env GATOR_DB_URL" {
+		t.Errorf("db_url comes from %v", got)
+	}
+
+	// Set stores the value in the profile, but the environment still wins.
+	if err := cfg.Set(KeyDbUrl, "postgres://home2"); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvName(KeyDbUrl), "")
+	if cfg := load(Options{Profile: "home"}); cfg.DbUrl != "postgres://home2" {
+		t.Errorf("db_url set in profile home is %v", cfg.DbUrl)
+	}
+	if cfg := load(Options{Profile: DefaultProfile}); cfg.DbUrl != "postgres://file" {
+		t.Errorf("setting profile home changed the default profile to %v", cfg.DbUrl)
+	}
+}
+
+func TestLegacyPath(t *testing.T) {
+	home := isolate(t)
+	legacy := filepath.Join(home, configFileName)
+	xdg := filepath.Join(home, ".config", xdgDirName, xdgFileName)
+
+	// A new config goes to the XDG location.
+	cfg, err := Load(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Path() != xdg || cfg.Exists() {
+		t.Errorf("without a config file the path is %v, exists %v", cfg.Path(), cfg.Exists())
+	}
+
+	// A config from before XDG support keeps being read and written in
+	// place, without a second file appearing.
+	writeFile(t, legacy, `{"db_url": "postgres://legacy", "current_user_name": "alice"}`)
+	cfg, err = Load(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Path() != legacy || cfg.DbUrl != "postgres://legacy" || cfg.CurrentUserName != "alice" {
+		t.Errorf("legacy config: path %v, db_url %v, user %v", cfg.Path(), cfg.DbUrl, cfg.CurrentUserName)
+	}
+	if err := cfg.SetUser("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(xdg); err == nil {
+		t.Errorf("SetUser created %v next to the legacy config", xdg)
+	}
+	data, err := os.ReadFile(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"current_user_name": "bob"`) || !strings.Contains(string(data), "postgres://legacy") {
+		t.Errorf("legacy config after SetUser:\n%s", data)
+	}
+
+	// Once a file exists at the XDG location it takes over.
+	writeFile(t, xdg, `{"db_url": "postgres://xdg"}`)
+	if cfg, err := Load(Options{}); err != nil || cfg.Path() != xdg || cfg.DbUrl != "postgres://xdg" {
+		t.Errorf("with both files: path %v, db_url %v, err %v", cfg.Path(), cfg.DbUrl, err)
+	}
+
+	// GATOR_CONFIG overrides both.
+	other := filepath.Join(home, "other.json")
+	t.Setenv(envConfig, other)
+	if cfg, err := Load(Options{}); err != nil || cfg.Path() != other {
+		t.Errorf("with GATOR_CONFIG: path %v, err %v", cfg.Path(), err)
+	}
+}
+
+func TestFailedWriteKeepsFile(t *testing.T) {
+	home := isolate(t)
+	// The temporary file gets a suffix that takes its name past the
+	// limit of the file system, so write fails before the rename.
+	path := filepath.Join(home, strings.Repeat("c", 250))
+	original := `{"db_url": "postgres://original", "current_user_name": "alice"}`
+	writeFile(t, path, original)
+
+	cfg, err := Load(Options{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.SetUser("bob"); err == nil {
+		t.Fatal("SetUser succeeded, want an error creating the temporary file")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != original {
+		t.Errorf("the config changed after a failed write:\n%s", data)
+	}
+	if cfg.CurrentUserName != "alice" {
+		t.Errorf("current user is %v after a failed write", cfg.CurrentUserName)
+	}
+	entries, err := os.ReadDir(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("a failed write left files behind: %v", entries)
+	}
+
+	// A successful write replaces the file with one only its owner can
+	// read.
+	path = filepath.Join(home, "config.json")
+	writeFile(t, path, original)
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = Load(Options{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.SetUser("bob"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("config mode is %v, want 0600", info.Mode().Perm())
+	}
+}
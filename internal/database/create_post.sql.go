@@ -22,7 +22,10 @@ INSERT INTO posts (
     url,
     description,
     published_at,
-    feed_id
+    feed_id,
+    summary,
+    author,
+    categories
 )
 VALUES (
     $1,
@@ -32,10 +35,15 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10,
+    $11
 )
-ON CONFLICT (url) DO NOTHING
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+ON CONFLICT (url) DO UPDATE
+SET feed_id = excluded.feed_id, updated_at = excluded.updated_at
+WHERE posts.feed_id IS NULL
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, seq, summary, content, author, categories
 `
 
 type CreatePostParams struct {
@@ -46,7 +54,10 @@ type CreatePostParams struct {
 	Url         string
 	Description sql.NullString
 	PublishedAt time.Time
-	FeedID      uuid.UUID
+	FeedID      uuid.NullUUID
+	Summary     sql.NullString
+	Author      sql.NullString
+	Categories  sql.NullString
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -59,6 +70,9 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Summary,
+		arg.Author,
+		arg.Categories,
 	)
 	var i Post
 	err := row.Scan(
@@ -70,6 +84,11 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Seq,
+		&i.Summary,
+		&i.Content,
+		&i.Author,
+		&i.Categories,
 	)
 	return i, err
 }
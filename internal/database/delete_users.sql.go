@@ -10,10 +10,13 @@ import (
 )
 
 const deleteAllUsers = `-- name: DeleteAllUsers :exec
-
+WITH kept_posts AS (
+    DELETE FROM posts WHERE feed_id IS NULL
+)
 DELETE FROM users
 `
 
+// Posts kept without a feed belong to no user and go too.
 func (q *Queries) DeleteAllUsers(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, deleteAllUsers)
 	return err
@@ -29,7 +29,7 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, last_fetch_error, dead_at, fetch_full_content
 `
 
 type CreateFeedParams struct {
@@ -59,6 +59,9 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.LastFetchError,
+		&i.DeadAt,
+		&i.FetchFullContent,
 	)
 	return i, err
 }
@@ -10,7 +10,7 @@ import (
 )
 
 const getFeedByURL = `-- name: GetFeedByURL :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, last_fetch_error, dead_at, fetch_full_content FROM feeds
 WHERE url = $1
 LIMIT 1
 `
@@ -26,6 +26,9 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.LastFetchError,
+		&i.DeadAt,
+		&i.FetchFullContent,
 	)
 	return i, err
 }
@@ -10,7 +10,8 @@ import (
 )
 
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, last_fetch_error, dead_at, fetch_full_content FROM feeds
+WHERE dead_at IS NULL
 ORDER BY last_fetched_at NULLS FIRST
 LIMIT 1
 `
@@ -26,6 +27,9 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.LastFetchError,
+		&i.DeadAt,
+		&i.FetchFullContent,
 	)
 	return i, err
 }
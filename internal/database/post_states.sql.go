@@ -0,0 +1,66 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: post_states.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const setPostHidden = `-- name: SetPostHidden :exec
+INSERT INTO post_states (user_id, post_id, hidden, updated_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET hidden = EXCLUDED.hidden, updated_at = NOW()
+`
+
+type SetPostHiddenParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	Hidden bool
+}
+
+func (q *Queries) SetPostHidden(ctx context.Context, arg SetPostHiddenParams) error {
+	_, err := q.db.ExecContext(ctx, setPostHidden, arg.UserID, arg.PostID, arg.Hidden)
+	return err
+}
+
+const setPostRead = `-- name: SetPostRead :exec
+INSERT INTO post_states (user_id, post_id, read, updated_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read = EXCLUDED.read, updated_at = NOW()
+`
+
+type SetPostReadParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	Read   bool
+}
+
+func (q *Queries) SetPostRead(ctx context.Context, arg SetPostReadParams) error {
+	_, err := q.db.ExecContext(ctx, setPostRead, arg.UserID, arg.PostID, arg.Read)
+	return err
+}
+
+const setPostStarred = `-- name: SetPostStarred :exec
+INSERT INTO post_states (user_id, post_id, starred, updated_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET starred = EXCLUDED.starred, updated_at = NOW()
+`
+
+type SetPostStarredParams struct {
+	UserID  uuid.UUID
+	PostID  uuid.UUID
+	Starred bool
+}
+
+func (q *Queries) SetPostStarred(ctx context.Context, arg SetPostStarredParams) error {
+	_, err := q.db.ExecContext(ctx, setPostStarred, arg.UserID, arg.PostID, arg.Starred)
+	return err
+}
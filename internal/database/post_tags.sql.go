@@ -0,0 +1,124 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: post_tags.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const addPostTag = `-- name: AddPostTag :exec
+INSERT INTO post_tags (user_id, post_id, tag, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (user_id, post_id, tag) DO NOTHING
+`
+
+type AddPostTagParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	Tag    string
+}
+
+func (q *Queries) AddPostTag(ctx context.Context, arg AddPostTagParams) error {
+	_, err := q.db.ExecContext(ctx, addPostTag, arg.UserID, arg.PostID, arg.Tag)
+	return err
+}
+
+const getPostTags = `-- name: GetPostTags :many
+SELECT tag FROM post_tags
+WHERE user_id = $1 AND post_id = $2
+ORDER BY tag
+`
+
+type GetPostTagsParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) GetPostTags(ctx context.Context, arg GetPostTagsParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getPostTags, arg.UserID, arg.PostID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		items = append(items, tag)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTagsForUser = `-- name: GetTagsForUser :many
+SELECT tag, COUNT(*) AS posts FROM post_tags
+WHERE user_id = $1
+GROUP BY tag
+ORDER BY tag
+`
+
+type GetTagsForUserRow struct {
+	Tag   string
+	Posts int64
+}
+
+// Returns every tag of a user with the number of posts it is on, for the
+// tag cloud.
+func (q *Queries) GetTagsForUser(ctx context.Context, userID uuid.UUID) ([]GetTagsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTagsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTagsForUserRow
+	for rows.Next() {
+		var i GetTagsForUserRow
+		if err := rows.Scan(&i.Tag, &i.Posts); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const removePostTag = `-- name: RemovePostTag :one
+DELETE FROM post_tags
+WHERE user_id = $1 AND post_id = $2 AND tag = $3
+RETURNING user_id, post_id, tag, created_at
+`
+
+type RemovePostTagParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	Tag    string
+}
+
+func (q *Queries) RemovePostTag(ctx context.Context, arg RemovePostTagParams) (PostTag, error) {
+	row := q.db.QueryRowContext(ctx, removePostTag, arg.UserID, arg.PostID, arg.Tag)
+	var i PostTag
+	err := row.Scan(
+		&i.UserID,
+		&i.PostID,
+		&i.Tag,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,88 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+type Querier interface {
+	AddPostTag(ctx context.Context, arg AddPostTagParams) error
+	CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error)
+	CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error)
+	CreateFolder(ctx context.Context, arg CreateFolderParams) (Folder, error)
+	CreatePost(ctx context.Context, arg CreatePostParams) (Post, error)
+	CreateRule(ctx context.Context, arg CreateRuleParams) (Rule, error)
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	// Posts kept without a feed go too, like the posts of the feeds.
+	DeleteAllFeeds(ctx context.Context) error
+	DeleteAllPosts(ctx context.Context) error
+	// Posts kept without a feed belong to no user and go too.
+	DeleteAllUsers(ctx context.Context) error
+	DeleteFeed(ctx context.Context, id uuid.UUID) error
+	DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowParams) error
+	// The feeds in the folder are kept, outside of any folder.
+	DeleteFolder(ctx context.Context, id uuid.UUID) error
+	DeleteRule(ctx context.Context, id uuid.UUID) error
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	DetachFeedPosts(ctx context.Context, feedID uuid.NullUUID) error
+	GetFeedByURL(ctx context.Context, url string) (Feed, error)
+	GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error)
+	GetFeedStats(ctx context.Context, feedID uuid.UUID) (GetFeedStatsRow, error)
+	GetFeeds(ctx context.Context) ([]Feed, error)
+	GetFolderByName(ctx context.Context, arg GetFolderByNameParams) (Folder, error)
+	GetFoldersForUser(ctx context.Context, userID uuid.UUID) ([]Folder, error)
+	GetNextFeedToFetch(ctx context.Context) (Feed, error)
+	GetPost(ctx context.Context, id uuid.UUID) (Post, error)
+	GetPostBySeq(ctx context.Context, seq int64) (Post, error)
+	GetPostByURL(ctx context.Context, url string) (Post, error)
+	GetPostTags(ctx context.Context, arg GetPostTagsParams) ([]string, error)
+	GetReaderItems(ctx context.Context, arg GetReaderItemsParams) ([]GetReaderItemsRow, error)
+	GetReaderItemsBySeq(ctx context.Context, arg GetReaderItemsBySeqParams) ([]GetReaderItemsBySeqRow, error)
+	// Counts the rows a reset would delete, for every user or only the one
+	// given: their feeds, the posts of those feeds and every follow of them.
+	// Posts kept without a feed only count for every user.
+	GetResetCounts(ctx context.Context, userID uuid.NullUUID) (GetResetCountsRow, error)
+	// Returns the rules that apply to new posts of a feed: those of every user
+	// following it, for all feeds or for this one.
+	GetRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]Rule, error)
+	GetRulesForUser(ctx context.Context, userID uuid.UUID) ([]GetRulesForUserRow, error)
+	// Returns every tag of a user with the number of posts it is on, for the
+	// tag cloud.
+	GetTagsForUser(ctx context.Context, userID uuid.UUID) ([]GetTagsForUserRow, error)
+	GetUnreadCountsForUser(ctx context.Context, userID uuid.UUID) ([]GetUnreadCountsForUserRow, error)
+	GetUser(ctx context.Context, name string) (User, error)
+	GetUserByAPITokenHash(ctx context.Context, apiTokenHash sql.NullString) (User, error)
+	GetUserById(ctx context.Context, id uuid.UUID) (User, error)
+	GetUsers(ctx context.Context) ([]User, error)
+	MarkFeedDead(ctx context.Context, id uuid.UUID) error
+	MarkFeedFetched(ctx context.Context, id uuid.UUID) error
+	// Moves follows to another feed, except those of users who already follow
+	// it; they are left behind and go when the old feed is deleted.
+	MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error
+	MoveFeedRules(ctx context.Context, arg MoveFeedRulesParams) error
+	MovePosts(ctx context.Context, arg MovePostsParams) error
+	RemovePostTag(ctx context.Context, arg RemovePostTagParams) (PostTag, error)
+	RenameFeed(ctx context.Context, arg RenameFeedParams) (Feed, error)
+	RenameFolder(ctx context.Context, arg RenameFolderParams) (Folder, error)
+	RenameUser(ctx context.Context, arg RenameUserParams) (User, error)
+	// Posts stored before summaries were added only have their description.
+	SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error)
+	SetFeedFetchError(ctx context.Context, arg SetFeedFetchErrorParams) error
+	SetFeedFollowFolder(ctx context.Context, arg SetFeedFollowFolderParams) (FeedFollow, error)
+	SetFeedFullContent(ctx context.Context, arg SetFeedFullContentParams) (Feed, error)
+	// A feed given a new url is fetched again even if the old one was gone.
+	SetFeedURL(ctx context.Context, arg SetFeedURLParams) (Feed, error)
+	SetPostContent(ctx context.Context, arg SetPostContentParams) error
+	SetPostHidden(ctx context.Context, arg SetPostHiddenParams) error
+	SetPostRead(ctx context.Context, arg SetPostReadParams) error
+	SetPostStarred(ctx context.Context, arg SetPostStarredParams) error
+	SetUserAPITokenHash(ctx context.Context, arg SetUserAPITokenHashParams) error
+}
+
+var _ Querier = (*Queries)(nil)
@@ -0,0 +1,266 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: reader_items.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const getReaderItems = `-- name: GetReaderItems :many
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.seq, posts.summary, posts.content, posts.author, posts.categories,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    COALESCE(post_states.read, FALSE)::boolean AS read,
+    COALESCE(post_states.starred, FALSE)::boolean AS starred
+FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id AND feed_follows.user_id = $1
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_states
+ON posts.id = post_states.post_id AND post_states.user_id = $1
+WHERE ($2::uuid IS NULL OR posts.feed_id = $2)
+AND ($3::uuid IS NULL OR feed_follows.folder_id = $3)
+AND ($4::text IS NULL OR EXISTS (
+    SELECT 1 FROM post_tags
+    WHERE post_tags.user_id = $1 AND post_tags.post_id = posts.id AND post_tags.tag = $4
+))
+AND NOT COALESCE(post_states.hidden, FALSE)
+AND (NOT $5::boolean OR NOT COALESCE(post_states.read, FALSE))
+AND (NOT $6::boolean OR COALESCE(post_states.read, FALSE))
+AND (NOT $7::boolean OR COALESCE(post_states.starred, FALSE))
+AND ($8::timestamp IS NULL OR posts.published_at >= $8)
+AND ($9::timestamp IS NULL OR posts.published_at <= $9)
+ORDER BY
+    CASE WHEN $10::boolean THEN posts.published_at END ASC,
+    posts.published_at DESC
+LIMIT $12
+OFFSET $11
+`
+
+type GetReaderItemsParams struct {
+	UserID      uuid.UUID
+	FeedID      uuid.NullUUID
+	FolderID    uuid.NullUUID
+	Tag         sql.NullString
+	ExcludeRead bool
+	OnlyRead    bool
+	OnlyStarred bool
+	NewerThan   sql.NullTime
+	OlderThan   sql.NullTime
+	OldestFirst bool
+	SkipItems   int32
+	MaxItems    int32
+}
+
+type GetReaderItemsRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt time.Time
+	FeedID      uuid.NullUUID
+	Seq         int64
+	Summary     sql.NullString
+	Content     sql.NullString
+	Author      sql.NullString
+	Categories  sql.NullString
+	FeedName    string
+	FeedUrl     string
+	Read        bool
+	Starred     bool
+}
+
+func (q *Queries) GetReaderItems(ctx context.Context, arg GetReaderItemsParams) ([]GetReaderItemsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getReaderItems,
+		arg.UserID,
+		arg.FeedID,
+		arg.FolderID,
+		arg.Tag,
+		arg.ExcludeRead,
+		arg.OnlyRead,
+		arg.OnlyStarred,
+		arg.NewerThan,
+		arg.OlderThan,
+		arg.OldestFirst,
+		arg.SkipItems,
+		arg.MaxItems,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReaderItemsRow
+	for rows.Next() {
+		var i GetReaderItemsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Seq,
+			&i.Summary,
+			&i.Content,
+			&i.Author,
+			&i.Categories,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.Read,
+			&i.Starred,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReaderItemsBySeq = `-- name: GetReaderItemsBySeq :many
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.seq, posts.summary, posts.content, posts.author, posts.categories,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    COALESCE(post_states.read, FALSE)::boolean AS read,
+    COALESCE(post_states.starred, FALSE)::boolean AS starred
+FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id AND feed_follows.user_id = $1
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_states
+ON posts.id = post_states.post_id AND post_states.user_id = $1
+WHERE posts.seq = ANY($2::bigint[])
+ORDER BY posts.published_at DESC
+`
+
+type GetReaderItemsBySeqParams struct {
+	UserID uuid.UUID
+	Seqs   []int64
+}
+
+type GetReaderItemsBySeqRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt time.Time
+	FeedID      uuid.NullUUID
+	Seq         int64
+	Summary     sql.NullString
+	Content     sql.NullString
+	Author      sql.NullString
+	Categories  sql.NullString
+	FeedName    string
+	FeedUrl     string
+	Read        bool
+	Starred     bool
+}
+
+func (q *Queries) GetReaderItemsBySeq(ctx context.Context, arg GetReaderItemsBySeqParams) ([]GetReaderItemsBySeqRow, error) {
+	rows, err := q.db.QueryContext(ctx, getReaderItemsBySeq, arg.UserID, pq.Array(arg.Seqs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReaderItemsBySeqRow
+	for rows.Next() {
+		var i GetReaderItemsBySeqRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Seq,
+			&i.Summary,
+			&i.Content,
+			&i.Author,
+			&i.Categories,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.Read,
+			&i.Starred,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUnreadCountsForUser = `-- name: GetUnreadCountsForUser :many
+SELECT
+    posts.feed_id,
+    COUNT(posts.id) AS unread,
+    MAX(posts.published_at)::timestamp AS newest
+FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+LEFT JOIN post_states
+ON posts.id = post_states.post_id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND NOT COALESCE(post_states.read, FALSE)
+AND NOT COALESCE(post_states.hidden, FALSE)
+GROUP BY posts.feed_id
+`
+
+type GetUnreadCountsForUserRow struct {
+	FeedID uuid.NullUUID
+	Unread int64
+	Newest time.Time
+}
+
+func (q *Queries) GetUnreadCountsForUser(ctx context.Context, userID uuid.UUID) ([]GetUnreadCountsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUnreadCountsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUnreadCountsForUserRow
+	for rows.Next() {
+		var i GetUnreadCountsForUserRow
+		if err := rows.Scan(&i.FeedID, &i.Unread, &i.Newest); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,103 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: search_posts.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const searchPosts = `-- name: SearchPosts :many
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.seq, posts.summary, posts.content, posts.author, posts.categories,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    COALESCE(post_states.read, FALSE)::boolean AS read,
+    COALESCE(post_states.starred, FALSE)::boolean AS starred
+FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id AND feed_follows.user_id = $1
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_states
+ON posts.id = post_states.post_id AND post_states.user_id = $1
+WHERE (strpos(lower(posts.title), lower($2::text)) > 0
+OR strpos(lower(COALESCE(posts.summary, posts.description, '')), lower($2::text)) > 0)
+AND NOT COALESCE(post_states.hidden, FALSE)
+ORDER BY posts.published_at DESC
+LIMIT $3
+`
+
+type SearchPostsParams struct {
+	UserID   uuid.UUID
+	Query    string
+	MaxItems int32
+}
+
+type SearchPostsRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt time.Time
+	FeedID      uuid.NullUUID
+	Seq         int64
+	Summary     sql.NullString
+	Content     sql.NullString
+	Author      sql.NullString
+	Categories  sql.NullString
+	FeedName    string
+	FeedUrl     string
+	Read        bool
+	Starred     bool
+}
+
+// Posts stored before summaries were added only have their description.
+func (q *Queries) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPosts, arg.UserID, arg.Query, arg.MaxItems)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsRow
+	for rows.Next() {
+		var i SearchPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Seq,
+			&i.Summary,
+			&i.Content,
+			&i.Author,
+			&i.Categories,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.Read,
+			&i.Starred,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,48 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: user_api_token.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const getUserByAPITokenHash = `-- name: GetUserByAPITokenHash :one
+SELECT id, created_at, updated_at, name, api_token_hash FROM users
+WHERE api_token_hash = $1
+LIMIT 1
+`
+
+func (q *Queries) GetUserByAPITokenHash(ctx context.Context, apiTokenHash sql.NullString) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByAPITokenHash, apiTokenHash)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.ApiTokenHash,
+	)
+	return i, err
+}
+
+const setUserAPITokenHash = `-- name: SetUserAPITokenHash :exec
+UPDATE users
+SET api_token_hash = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetUserAPITokenHashParams struct {
+	ID           uuid.UUID
+	ApiTokenHash sql.NullString
+}
+
+func (q *Queries) SetUserAPITokenHash(ctx context.Context, arg SetUserAPITokenHashParams) error {
+	_, err := q.db.ExecContext(ctx, setUserAPITokenHash, arg.ID, arg.ApiTokenHash)
+	return err
+}
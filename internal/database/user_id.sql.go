@@ -12,7 +12,7 @@ import (
 )
 
 const getUserById = `-- name: GetUserById :one
-SELECT id, created_at, updated_at, name FROM users
+SELECT id, created_at, updated_at, name, api_token_hash FROM users
 WHERE id = $1
 LIMIT 1
 `
@@ -25,6 +25,7 @@ func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.ApiTokenHash,
 	)
 	return i, err
 }
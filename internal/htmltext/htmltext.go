@@ -0,0 +1,258 @@
+// Package htmltext renders the HTML fragments found in feed descriptions as
+// readable plain text for terminal output, and sanitizes them for storing.
+package htmltext
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// blockTags start and end on their own line.
+var blockTags = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true,
+	atom.Header: true, atom.Footer: true, atom.Blockquote: true, atom.Pre: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Table: true, atom.Tr: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true,
+	atom.H6: true, atom.Figure: true, atom.Figcaption: true, atom.Hr: true,
+	atom.Dl: true, atom.Dt: true, atom.Dd: true,
+}
+
+// skipTags have content that should never be shown to the reader.
+var skipTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Head: true, atom.Title: true,
+	atom.Noscript: true, atom.Iframe: true, atom.Object: true, atom.Template: true,
+}
+
+type renderer struct {
+	out       strings.Builder
+	links     []string
+	anchors   []string
+	skipDepth int
+	preDepth  int
+	// summary leaves out bullets, link numbers and image descriptions.
+	summary bool
+	// pendingSpace and pendingLines hold whitespace until the next visible
+	// text, so runs of tags never produce runs of blank lines.
+	pendingSpace bool
+	pendingLines int
+}
+
+// Render converts an HTML fragment into plain text. Paragraph structure is
+// kept, list items are bulleted and links are numbered with their targets
+// listed at the end.
+func Render(fragment string) string {
+	r := &renderer{}
+	tokenizer := html.NewTokenizer(strings.NewReader(fragment))
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return StripControl(r.finish())
+		case html.TextToken:
+			if r.skipDepth == 0 {
+				r.text(string(tokenizer.Text()))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			r.start(tokenizer.Token())
+		case html.EndTagToken:
+			r.end(tokenizer.Token())
+		}
+	}
+}
+
+// summaryLength is the number of characters Summary keeps.
+const summaryLength = 280
+
+// Summary converts an HTML fragment into plain text on a single line,
+// shortened at a word boundary to about summaryLength characters.
+func Summary(fragment string) string {
+	r := &renderer{summary: true}
+	tokenizer := html.NewTokenizer(strings.NewReader(fragment))
+	for tokenizer.Next() != html.ErrorToken {
+		switch token := tokenizer.Token(); token.Type {
+		case html.TextToken:
+			if r.skipDepth == 0 {
+				r.text(token.Data)
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			r.start(token)
+		case html.EndTagToken:
+			r.end(token)
+		}
+	}
+
+	text := strings.Join(strings.Fields(StripControl(r.finish())), " ")
+	if len([]rune(text)) <= summaryLength {
+		return text
+	}
+	cut := string([]rune(text)[:summaryLength])
+	if i := strings.LastIndexByte(cut, ' '); i > summaryLength/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
+
+// StripControl removes the control characters from text that comes from
+// feeds, keeping tabs and newlines. Written to a terminal, ESC and the other
+// C0 and C1 controls would let a feed move the cursor, rewrite the screen
+// or set the window title.
+func StripControl(s string) string {
+	return strings.Map(func(r rune) rune {
+		if (r < 0x20 && r != '\t' && r != '\n') || (r >= 0x7f && r <= 0x9f) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func (r *renderer) start(t html.Token) {
+	if skipTags[t.DataAtom] {
+		if t.Type == html.StartTagToken {
+			r.skipDepth++
+		}
+		return
+	}
+	if r.skipDepth > 0 {
+		return
+	}
+
+	switch t.DataAtom {
+	case atom.A:
+		if t.Type == html.StartTagToken && !r.summary {
+			r.anchors = append(r.anchors, attr(t, "href"))
+		}
+	case atom.Br:
+		r.newlines(1)
+	case atom.Li:
+		r.newlines(1)
+		if !r.summary {
+			r.write("• ")
+		}
+	case atom.Pre:
+		r.newlines(2)
+		r.preDepth++
+	case atom.Img:
+		if alt := attr(t, "alt"); alt != "" && !r.summary {
+			r.write("[image: " + alt + "]")
+		}
+	case atom.Hr:
+		r.newlines(2)
+		r.write("----")
+		r.newlines(2)
+	case atom.Td, atom.Th:
+		r.pendingSpace = true
+	default:
+		if blockTags[t.DataAtom] {
+			r.newlines(2)
+		}
+	}
+}
+
+func (r *renderer) end(t html.Token) {
+	if skipTags[t.DataAtom] {
+		if r.skipDepth > 0 {
+			r.skipDepth--
+		}
+		return
+	}
+	if r.skipDepth > 0 {
+		return
+	}
+
+	switch t.DataAtom {
+	case atom.A:
+		if len(r.anchors) == 0 {
+			return
+		}
+		href := r.anchors[len(r.anchors)-1]
+		r.anchors = r.anchors[:len(r.anchors)-1]
+		if href == "" || strings.HasPrefix(href, "#") {
+			return
+		}
+		r.links = append(r.links, href)
+		r.pendingSpace = false
+		r.write(fmt.Sprintf("[%d]", len(r.links)))
+	case atom.Pre:
+		if r.preDepth > 0 {
+			r.preDepth--
+		}
+		r.newlines(2)
+	case atom.Li:
+		r.newlines(1)
+	default:
+		if blockTags[t.DataAtom] {
+			r.newlines(2)
+		}
+	}
+}
+
+func (r *renderer) text(s string) {
+	if r.preDepth > 0 {
+		r.write(s)
+		return
+	}
+
+	if len(s) > 0 && isSpace(s[0]) {
+		r.pendingSpace = true
+	}
+	words := strings.Fields(s)
+	for i, word := range words {
+		if i > 0 {
+			r.pendingSpace = true
+		}
+		r.write(word)
+	}
+	if len(s) > 0 && isSpace(s[len(s)-1]) {
+		r.pendingSpace = true
+	}
+}
+
+func (r *renderer) write(s string) {
+	if s == "" {
+		return
+	}
+	if r.out.Len() > 0 {
+		if r.pendingLines > 0 {
+			r.out.WriteString(strings.Repeat("\n", r.pendingLines))
+		} else if r.pendingSpace {
+			r.out.WriteByte(' ')
+		}
+	}
+	r.pendingLines = 0
+	r.pendingSpace = false
+	r.out.WriteString(s)
+}
+
+func (r *renderer) newlines(n int) {
+	r.pendingLines = max(r.pendingLines, n)
+}
+
+func (r *renderer) finish() string {
+	text := r.out.String()
+	if len(r.links) > 0 {
+		var b strings.Builder
+		b.WriteString(text)
+		b.WriteString("\n\nLinks:")
+		for i, link := range r.links {
+			fmt.Fprintf(&b, "\n[%d] %s", i+1, link)
+		}
+		text = b.String()
+	}
+	return strings.TrimSpace(text)
+}
+
+func attr(t html.Token, name string) string {
+	for _, a := range t.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
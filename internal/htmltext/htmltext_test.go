@@ -0,0 +1,84 @@
+package htmltext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	const base = "https://example.com/posts/first"
+	tests := []struct {
+		in, want string
+	}{
+		{`<p>Hello <b>world</b></p>`, `<p>Hello <b>world</b></p>`},
+		{`<script>alert(1)</script><style>p{}</style>Text`, `Text`},
+		{`<div style="color: red" class="x"><span>inline</span></div>`, `inline`},
+		{`<p onclick="steal()" id="p">x</p>`, `<p>x</p>`},
+		{`<a href="../about" onmouseover="x()">About</a>`, `<a href="https://example.com/about">About</a>`},
+		{`<a href="javascript:alert(1)">Click</a>`, `<a>Click</a>`},
+		{`<img src="cat.png" alt="A cat">`, `<img src="https://example.com/posts/cat.png" alt="A cat">`},
+		{`<img src="data:image/png;base64,AAAA">`, ``},
+		{`<img src="https://t.example.net/p.gif" width="1" height="1">`, ``},
+		{`<iframe src="https://example.com"></iframe><form><input></form>`, ``},
+		{`5 < 6 & "quotes"`, `5 &lt; 6 &amp; &#34;quotes&#34;`},
+		{`<!-- comment --><p>unclosed`, `<p>unclosed</p>`},
+	}
+	for _, tt := range tests {
+		if got := Sanitize(tt.in, base); got != tt.want {
+			t.Errorf("Sanitize(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeWithoutBase(t *testing.T) {
+	got := Sanitize(`<a href="/about">About</a> <a href="vbscript:x">x</a>`, "not a url")
+	if want := `<a href="/about">About</a> <a>x</a>`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	got := Summary(`<p>First   paragraph with <a href="https://example.com">a link</a>.</p>
+		<script>ignored()</script><img src="x.png" alt="picture"><ul><li>One</li><li>Two</li></ul>`)
+	if want := "First paragraph with a link. One Two"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	long := strings.Repeat("word ", 100)
+	got = Summary(long)
+	if !strings.HasSuffix(got, "word…") || len([]rune(got)) > summaryLength+1 {
+		t.Errorf("long text was shortened to %q", got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	got := Render(`<p>See <a href="https://example.com">this</a>.</p><ul><li>One</li></ul>`)
+	want := "See this[1].\n\n• One\n\nLinks:\n[1] https://example.com"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestStripControl(t *testing.T) {
+	for _, c := range []struct{ in, want string }{
+		{"plain text", "plain text"},
+		{"tab\tand\nnewline", "tab\tand\nnewline"},
+		{"\x1b[2Jclear", "[2Jclear"},
+		{"bell\a, return\r and null\x00", "bell, return and null"},
+		{"del\x7f and csi\u009b2J", "del and csi2J"},
+		{"héllo → wörld", "héllo → wörld"},
+	} {
+		if got := StripControl(c.in); got != c.want {
+			t.Errorf("StripControl(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+
+	// Escapes written as entities only appear once decoded.
+	const fragment = "<p>Now &#27;[31mred&#27;[0m</p>"
+	if got := Summary(fragment); got != "Now [31mred[0m" {
+		t.Errorf("Summary(%q) = %q", fragment, got)
+	}
+	if got := Render(fragment); got != "Now [31mred[0m" {
+		t.Errorf("Render(%q) = %q", fragment, got)
+	}
+}
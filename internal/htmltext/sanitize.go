@@ -0,0 +1,158 @@
+package htmltext
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// allowedTags are the elements Sanitize keeps, with the attributes each one
+// may keep. Other elements are replaced by their content.
+var allowedTags = map[atom.Atom][]string{
+	atom.A: {"href", "title"}, atom.Abbr: {"title"}, atom.B: nil,
+	atom.Blockquote: {"cite"}, atom.Br: nil, atom.Caption: nil, atom.Cite: nil,
+	atom.Code: nil, atom.Dd: nil, atom.Del: nil, atom.Dl: nil, atom.Dt: nil,
+	atom.Em: nil, atom.Figcaption: nil, atom.Figure: nil, atom.H1: nil,
+	atom.H2: nil, atom.H3: nil, atom.H4: nil, atom.H5: nil, atom.H6: nil,
+	atom.Hr: nil, atom.I: nil, atom.Img: {"src", "alt", "title", "width", "height"},
+	atom.Ins: nil, atom.Kbd: nil, atom.Li: nil, atom.Mark: nil, atom.Ol: nil,
+	atom.P: nil, atom.Pre: nil, atom.Q: {"cite"}, atom.S: nil, atom.Small: nil,
+	atom.Strong: nil, atom.Sub: nil, atom.Sup: nil, atom.Table: nil,
+	atom.Tbody: nil, atom.Td: {"colspan", "rowspan"}, atom.Tfoot: nil,
+	atom.Th: {"colspan", "rowspan"}, atom.Thead: nil, atom.Tr: nil, atom.U: nil,
+	atom.Ul: nil,
+}
+
+// droppedTags are removed along with their content, in addition to
+// skipTags: forms, embedded documents and elements that load resources.
+var droppedTags = map[atom.Atom]bool{
+	atom.Svg: true, atom.Math: true, atom.Form: true, atom.Input: true,
+	atom.Button: true, atom.Select: true, atom.Textarea: true, atom.Embed: true,
+	atom.Frame: true, atom.Frameset: true, atom.Link: true, atom.Meta: true,
+	atom.Base: true, atom.Audio: true, atom.Video: true, atom.Canvas: true,
+}
+
+// urlAttrs hold URLs, which are resolved against the base URL and removed
+// unless they point at a web page or an email address.
+var urlAttrs = map[string]bool{"href": true, "src": true, "cite": true}
+
+var voidTags = map[atom.Atom]bool{atom.Br: true, atom.Hr: true, atom.Img: true}
+
+// Sanitize returns a copy of an HTML fragment that is safe to show in a
+// web page: scripts, styles, event handlers, tracking pixels and unknown
+// elements are removed, and relative links and images are resolved against
+// baseURL, normally the link of the post.
+func Sanitize(fragment, baseURL string) string {
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), context)
+	if err != nil {
+		return html.EscapeString(fragment)
+	}
+
+	var base *url.URL
+	if u, err := url.Parse(baseURL); err == nil && u.IsAbs() {
+		base = u
+	}
+
+	var b strings.Builder
+	for _, n := range nodes {
+		sanitizeNode(&b, n, base)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func sanitizeNode(b *strings.Builder, n *html.Node, base *url.URL) {
+	switch n.Type {
+	case html.TextNode:
+		b.WriteString(html.EscapeString(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		// Comments and doctypes are dropped.
+		return
+	}
+
+	if skipTags[n.DataAtom] || droppedTags[n.DataAtom] {
+		return
+	}
+	allowed, ok := allowedTags[n.DataAtom]
+	if !ok {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			sanitizeNode(b, c, base)
+		}
+		return
+	}
+
+	attrs := sanitizeAttrs(n, allowed, base)
+	if n.DataAtom == atom.Img && (attrs["src"] == "" || isTrackingPixel(attrs)) {
+		return
+	}
+
+	b.WriteString("<" + n.Data)
+	for _, name := range allowed {
+		if value, ok := attrs[name]; ok {
+			b.WriteString(" " + name + `="` + html.EscapeString(value) + `"`)
+		}
+	}
+	b.WriteString(">")
+	if voidTags[n.DataAtom] {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sanitizeNode(b, c, base)
+	}
+	b.WriteString("</" + n.Data + ">")
+}
+
+func sanitizeAttrs(n *html.Node, allowed []string, base *url.URL) map[string]string {
+	attrs := map[string]string{}
+	for _, a := range n.Attr {
+		if a.Namespace != "" || !slices.Contains(allowed, a.Key) {
+			continue
+		}
+		value := a.Val
+		if urlAttrs[a.Key] {
+			var ok bool
+			if value, ok = safeURL(value, base); !ok {
+				continue
+			}
+		}
+		attrs[a.Key] = value
+	}
+	return attrs
+}
+
+// safeURL resolves raw against base and reports whether the result may be
+// linked to. Schemes such as javascript: and data: are refused.
+func safeURL(raw string, base *url.URL) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", false
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+		return u.String(), true
+	case "":
+		// Without a base, relative URLs are kept as they are.
+		return u.String(), base == nil
+	}
+	return "", false
+}
+
+// isTrackingPixel reports whether an image is too small to be seen, which
+// is how feeds embed web bugs that report when a post is read.
+func isTrackingPixel(attrs map[string]string) bool {
+	for _, name := range []string{"width", "height"} {
+		switch strings.TrimSuffix(strings.TrimSpace(attrs[name]), "px") {
+		case "0", "1":
+			return true
+		}
+	}
+	return false
+}
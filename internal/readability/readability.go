@@ -0,0 +1,279 @@
+// Package readability extracts the main article from a web page, leaving
+// out navigation, sidebars, comments and other page furniture. It scores
+// the elements holding paragraphs of text, the way browser reader modes do,
+// and keeps the best one along with related siblings.
+package readability
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ErrNoArticle is returned for pages without enough text to be an article,
+// such as index pages or pages rendered by scripts.
+var ErrNoArticle = errors.New("no article found in page")
+
+const (
+	// minArticleLength is the shortest text, in characters, accepted as
+	// an article.
+	minArticleLength = 250
+	// minParagraphLength is the shortest text that counts as a paragraph.
+	minParagraphLength = 25
+)
+
+var (
+	positiveNames = regexp.MustCompile(`(?i)article|body|content|entry|hentry|main|page|post|text|blog|story`)
+	negativeNames = regexp.MustCompile(`(?i)comment|meta|footer|footnote|sidebar|sponsor|advert|\bads?\b|share|social|related|nav|menu|promo|widget|banner|popup|cookie|subscribe|newsletter`)
+)
+
+// clutterTags never hold article text.
+var clutterTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Head: true,
+	atom.Nav: true, atom.Header: true, atom.Footer: true, atom.Aside: true,
+	atom.Form: true, atom.Iframe: true, atom.Button: true, atom.Select: true,
+	atom.Template: true, atom.Svg: true,
+}
+
+// paragraphTags are the elements whose text is scored.
+var paragraphTags = map[atom.Atom]bool{
+	atom.P: true, atom.Pre: true, atom.Td: true, atom.Blockquote: true,
+}
+
+// Extract returns the HTML of the main article of page, which must be
+// UTF-8. The HTML is not sanitized.
+func Extract(page io.Reader) (string, error) {
+	doc, err := html.Parse(page)
+	if err != nil {
+		return "", err
+	}
+	root := findElement(doc, atom.Body)
+	if root == nil {
+		root = doc
+	}
+	removeClutter(root)
+
+	scores := scoreParagraphs(root)
+	var best *html.Node
+	bestScore := 0.0
+	for _, c := range scores.candidates {
+		if score := scores.adjusted(c.node); best == nil || score > bestScore {
+			best, bestScore = c.node, score
+		}
+	}
+	if best == nil {
+		return "", ErrNoArticle
+	}
+
+	var b strings.Builder
+	length := 0
+	for _, n := range articleNodes(best, bestScore, scores) {
+		if err := html.Render(&b, n); err != nil {
+			return "", err
+		}
+		length += utf8.RuneCountInString(strings.TrimSpace(textContent(n)))
+	}
+	if length < minArticleLength {
+		return "", ErrNoArticle
+	}
+	return b.String(), nil
+}
+
+// candidate is an element containing paragraphs, with its score.
+type candidate struct {
+	node  *html.Node
+	score float64
+}
+
+// scores holds the candidates of a page in the order they were found, so
+// that ties are broken the same way every time.
+type scores struct {
+	candidates []*candidate
+	byNode     map[*html.Node]*candidate
+}
+
+// scoreParagraphs scores the parent of every paragraph below root by the
+// length of the paragraph and the number of commas in it, and the
+// grandparent by half as much.
+func scoreParagraphs(root *html.Node) *scores {
+	s := &scores{byNode: map[*html.Node]*candidate{}}
+	walk(root, func(n *html.Node) {
+		if !paragraphTags[n.DataAtom] {
+			return
+		}
+		text := strings.TrimSpace(textContent(n))
+		length := utf8.RuneCountInString(text)
+		if length < minParagraphLength {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + float64(min(length/100, 3))
+		s.add(n.Parent, score)
+		if n.Parent != nil {
+			s.add(n.Parent.Parent, score/2)
+		}
+	})
+	return s
+}
+
+func (s *scores) add(n *html.Node, score float64) {
+	if n == nil || n.Type != html.ElementNode {
+		return
+	}
+	c, ok := s.byNode[n]
+	if !ok {
+		c = &candidate{node: n, score: initialScore(n)}
+		s.byNode[n] = c
+		s.candidates = append(s.candidates, c)
+	}
+	c.score += score
+}
+
+// adjusted returns the score of n lowered by its link density, so that
+// lists of links do not win over text. Elements without paragraphs score 0.
+func (s *scores) adjusted(n *html.Node) float64 {
+	c, ok := s.byNode[n]
+	if !ok {
+		return 0
+	}
+	return c.score * (1 - linkDensity(n))
+}
+
+// articleNodes returns best and the siblings that look like part of the
+// same article, such as paragraphs split around an image, in page order.
+func articleNodes(best *html.Node, bestScore float64, s *scores) []*html.Node {
+	if best.Parent == nil {
+		return []*html.Node{best}
+	}
+
+	threshold := max(10, bestScore*0.2)
+	var nodes []*html.Node
+	for n := best.Parent.FirstChild; n != nil; n = n.NextSibling {
+		switch {
+		case n == best:
+			nodes = append(nodes, n)
+		case n.Type != html.ElementNode:
+		case s.adjusted(n) >= threshold:
+			nodes = append(nodes, n)
+		case n.DataAtom == atom.P:
+			length := utf8.RuneCountInString(strings.TrimSpace(textContent(n)))
+			if length > 80 && linkDensity(n) < 0.25 {
+				nodes = append(nodes, n)
+			}
+		}
+	}
+	return nodes
+}
+
+// initialScore favours elements usually holding articles and penalizes
+// the ones around them, judging by their tag and their class and id.
+func initialScore(n *html.Node) float64 {
+	var score float64
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		score = 10
+	case atom.Div:
+		score = 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score = 3
+	case atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li:
+		score = -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score = -5
+	}
+	return score + nameWeight(n)
+}
+
+func nameWeight(n *html.Node) float64 {
+	var weight float64
+	for _, name := range []string{attr(n, "class"), attr(n, "id")} {
+		if name == "" {
+			continue
+		}
+		if negativeNames.MatchString(name) {
+			weight -= 25
+		}
+		if positiveNames.MatchString(name) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// removeClutter deletes elements that are never part of an article, and
+// those whose class or id only suggest they are not.
+func removeClutter(root *html.Node) {
+	var clutter []*html.Node
+	walk(root, func(n *html.Node) {
+		if n == root {
+			return
+		}
+		if clutterTags[n.DataAtom] || nameWeight(n) < 0 {
+			clutter = append(clutter, n)
+		}
+	})
+	for _, n := range clutter {
+		if n.Parent != nil {
+			n.Parent.RemoveChild(n)
+		}
+	}
+}
+
+// linkDensity is the share of the text of n that is inside links.
+func linkDensity(n *html.Node) float64 {
+	length := utf8.RuneCountInString(textContent(n))
+	if length == 0 {
+		return 0
+	}
+	linkLength := 0
+	walk(n, func(c *html.Node) {
+		if c.DataAtom == atom.A {
+			linkLength += utf8.RuneCountInString(textContent(c))
+		}
+	})
+	return min(float64(linkLength)/float64(length), 1)
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+// walk calls visit for n and every element below it, parents first.
+func walk(n *html.Node, visit func(*html.Node)) {
+	if n.Type == html.ElementNode {
+		visit(n)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, visit)
+	}
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	var found *html.Node
+	walk(n, func(c *html.Node) {
+		if found == nil && c.DataAtom == a {
+			found = c
+		}
+	})
+	return found
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
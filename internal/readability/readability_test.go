@@ -0,0 +1,62 @@
+package readability
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	page, err := os.Open("../../testdata/feeds/article.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer page.Close()
+
+	article, err := Extract(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"goes on to explain", "the rest of the text", `<img src="images/chart.png"`, "without a connection"} {
+		if !strings.Contains(article, want) {
+			t.Errorf("article does not contain %q:\n%s", want, article)
+		}
+	}
+	for _, unwanted := range []string{"Archive", "newsletter", "Great post", "Copyright", "showAds", "trackVisit"} {
+		if strings.Contains(article, unwanted) {
+			t.Errorf("article contains %q:\n%s", unwanted, article)
+		}
+	}
+}
+
+func TestExtractSiblingParagraphs(t *testing.T) {
+	paragraph := "<p>" + strings.Repeat("Words, and more words, ", 8) + "</p>"
+	page := `<body><div class="entry">` + paragraph + paragraph + `</div>` +
+		`<p>A closing paragraph outside the entry, long enough to belong to the article as well.</p>` +
+		`<div><p><a href="/1">A list of links to other posts on the site</a></p></div></body>`
+
+	article, err := Extract(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(article, "A closing paragraph") {
+		t.Errorf("sibling paragraph is missing:\n%s", article)
+	}
+	if strings.Contains(article, "other posts") {
+		t.Errorf("list of links is included:\n%s", article)
+	}
+}
+
+func TestExtractNoArticle(t *testing.T) {
+	for _, page := range []string{
+		``,
+		`<html><body><div id="app"></div><script>render()</script></body></html>`,
+		`<body><ul><li><a href="/1">First post</a></li><li><a href="/2">Second post</a></li></ul></body>`,
+		`<body><p>A single short paragraph that is not an article.</p></body>`,
+	} {
+		if article, err := Extract(strings.NewReader(page)); !errors.Is(err, ErrNoArticle) {
+			t.Errorf("Extract(%q) = %q, %v", page, article, err)
+		}
+	}
+}
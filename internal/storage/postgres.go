@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"database/sql"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+)
+
+type postgresStore struct {
+	*database.Queries
+	db *sql.DB
+}
+
+func openPostgres(dsn string) (Store, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresStore{Queries: database.New(db), db: db}, nil
+}
+
+func (s *postgresStore) Backend() Backend {
+	return Postgres
+}
+
+func (s *postgresStore) DB() *sql.DB {
+	return s.db
+}
+
+func (s *postgresStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,514 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/alifoo/blog-aggregator/internal/storage/sqlitedb"
+	"github.com/google/uuid"
+)
+
+// sqliteTimeLayouts are the formats timestamps come back in when SQLite
+// cannot tell the column type, as with MAX(published_at).
+var sqliteTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	time.RFC3339Nano,
+}
+
+// sqliteStore adapts the queries generated for SQLite to the Store
+// interface. Rows have the same fields as their PostgreSQL counterparts and
+// are converted directly; timestamps are stored in UTC so they sort and
+// compare correctly as text.
+type sqliteStore struct {
+	q  *sqlitedb.Queries
+	db *sql.DB
+}
+
+func openSQLite(dsn string) (Store, error) {
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer, and a single connection also keeps an
+	// in-memory database alive for the whole run.
+	db.SetMaxOpenConns(1)
+	return &sqliteStore{q: sqlitedb.New(db), db: db}, nil
+}
+
+func (s *sqliteStore) Backend() Backend {
+	return SQLite
+}
+
+func (s *sqliteStore) DB() *sql.DB {
+	return s.db
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *sqliteStore) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	arg.CreatedAt, arg.UpdatedAt = arg.CreatedAt.UTC(), arg.UpdatedAt.UTC()
+	user, err := s.q.CreateUser(ctx, sqlitedb.CreateUserParams(arg))
+	return database.User(user), err
+}
+
+func (s *sqliteStore) GetUser(ctx context.Context, name string) (database.User, error) {
+	user, err := s.q.GetUser(ctx, name)
+	return database.User(user), err
+}
+
+func (s *sqliteStore) GetUserById(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, err := s.q.GetUserById(ctx, id)
+	return database.User(user), err
+}
+
+func (s *sqliteStore) GetUserByAPITokenHash(ctx context.Context, apiTokenHash sql.NullString) (database.User, error) {
+	user, err := s.q.GetUserByAPITokenHash(ctx, apiTokenHash)
+	return database.User(user), err
+}
+
+func (s *sqliteStore) GetUsers(ctx context.Context) ([]database.User, error) {
+	users, err := s.q.GetUsers(ctx)
+	return convertRows(users, func(u sqlitedb.User) database.User { return database.User(u) }), err
+}
+
+func (s *sqliteStore) SetUserAPITokenHash(ctx context.Context, arg database.SetUserAPITokenHashParams) error {
+	return s.q.SetUserAPITokenHash(ctx, sqlitedb.SetUserAPITokenHashParams{
+		ApiTokenHash: arg.ApiTokenHash,
+		UpdatedAt:    now(),
+		ID:           arg.ID,
+	})
+}
+
+func (s *sqliteStore) RenameUser(ctx context.Context, arg database.RenameUserParams) (database.User, error) {
+	user, err := s.q.RenameUser(ctx, sqlitedb.RenameUserParams{
+		Name:      arg.Name,
+		UpdatedAt: now(),
+		ID:        arg.ID,
+	})
+	return database.User(user), err
+}
+
+func (s *sqliteStore) DeleteAllUsers(ctx context.Context) error {
+	return s.withKeptPostsDeleted(ctx, (*sqlitedb.Queries).DeleteAllUsers)
+}
+
+func (s *sqliteStore) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	return s.q.DeleteUser(ctx, id)
+}
+
+func (s *sqliteStore) DeleteAllFeeds(ctx context.Context) error {
+	return s.withKeptPostsDeleted(ctx, (*sqlitedb.Queries).DeleteAllFeeds)
+}
+
+// withKeptPostsDeleted runs del along with DeleteKeptPosts in a transaction,
+// as the PostgreSQL queries do in a single statement.
+func (s *sqliteStore) withKeptPostsDeleted(ctx context.Context, del func(*sqlitedb.Queries, context.Context) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	q := s.q.WithTx(tx)
+	if err := q.DeleteKeptPosts(ctx); err != nil {
+		return err
+	}
+	if err := del(q, ctx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStore) DeleteAllPosts(ctx context.Context) error {
+	return s.q.DeleteAllPosts(ctx)
+}
+
+func (s *sqliteStore) GetResetCounts(ctx context.Context, userID uuid.NullUUID) (database.GetResetCountsRow, error) {
+	var id interface{}
+	if userID.Valid {
+		id = userID.UUID
+	}
+	counts, err := s.q.GetResetCounts(ctx, id)
+	return database.GetResetCountsRow(counts), err
+}
+
+func (s *sqliteStore) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	arg.CreatedAt, arg.UpdatedAt = arg.CreatedAt.UTC(), arg.UpdatedAt.UTC()
+	feed, err := s.q.CreateFeed(ctx, sqlitedb.CreateFeedParams(arg))
+	return database.Feed(feed), err
+}
+
+func (s *sqliteStore) GetFeedByURL(ctx context.Context, url string) (database.Feed, error) {
+	feed, err := s.q.GetFeedByURL(ctx, url)
+	return database.Feed(feed), err
+}
+
+func (s *sqliteStore) GetFeeds(ctx context.Context) ([]database.Feed, error) {
+	feeds, err := s.q.GetFeeds(ctx)
+	return convertRows(feeds, func(f sqlitedb.Feed) database.Feed { return database.Feed(f) }), err
+}
+
+func (s *sqliteStore) GetNextFeedToFetch(ctx context.Context) (database.Feed, error) {
+	feed, err := s.q.GetNextFeedToFetch(ctx)
+	return database.Feed(feed), err
+}
+
+func (s *sqliteStore) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
+	return s.q.MarkFeedFetched(ctx, sqlitedb.MarkFeedFetchedParams{
+		Now: sql.NullTime{Time: now(), Valid: true},
+		ID:  id,
+	})
+}
+
+func (s *sqliteStore) RenameFeed(ctx context.Context, arg database.RenameFeedParams) (database.Feed, error) {
+	feed, err := s.q.RenameFeed(ctx, sqlitedb.RenameFeedParams{
+		Name:      arg.Name,
+		UpdatedAt: now(),
+		ID:        arg.ID,
+	})
+	return database.Feed(feed), err
+}
+
+func (s *sqliteStore) SetFeedURL(ctx context.Context, arg database.SetFeedURLParams) (database.Feed, error) {
+	feed, err := s.q.SetFeedURL(ctx, sqlitedb.SetFeedURLParams{
+		Url:       arg.Url,
+		UpdatedAt: now(),
+		ID:        arg.ID,
+	})
+	return database.Feed(feed), err
+}
+
+func (s *sqliteStore) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	return s.q.DeleteFeed(ctx, id)
+}
+
+func (s *sqliteStore) DetachFeedPosts(ctx context.Context, feedID uuid.NullUUID) error {
+	return s.q.DetachFeedPosts(ctx, feedID)
+}
+
+func (s *sqliteStore) SetFeedFetchError(ctx context.Context, arg database.SetFeedFetchErrorParams) error {
+	return s.q.SetFeedFetchError(ctx, sqlitedb.SetFeedFetchErrorParams{
+		LastFetchError: arg.LastFetchError,
+		ID:             arg.ID,
+	})
+}
+
+func (s *sqliteStore) SetFeedFullContent(ctx context.Context, arg database.SetFeedFullContentParams) (database.Feed, error) {
+	feed, err := s.q.SetFeedFullContent(ctx, sqlitedb.SetFeedFullContentParams{
+		FetchFullContent: arg.FetchFullContent,
+		UpdatedAt:        now(),
+		ID:               arg.ID,
+	})
+	return database.Feed(feed), err
+}
+
+func (s *sqliteStore) GetFeedStats(ctx context.Context, feedID uuid.UUID) (database.GetFeedStatsRow, error) {
+	stats, err := s.q.GetFeedStats(ctx, feedID)
+	return database.GetFeedStatsRow(stats), err
+}
+
+func (s *sqliteStore) MarkFeedDead(ctx context.Context, id uuid.UUID) error {
+	return s.q.MarkFeedDead(ctx, sqlitedb.MarkFeedDeadParams{
+		Now: sql.NullTime{Time: now(), Valid: true},
+		ID:  id,
+	})
+}
+
+func (s *sqliteStore) MoveFeedFollows(ctx context.Context, arg database.MoveFeedFollowsParams) error {
+	return s.q.MoveFeedFollows(ctx, sqlitedb.MoveFeedFollowsParams{
+		ToFeedID:   arg.ToFeedID,
+		UpdatedAt:  now(),
+		FromFeedID: arg.FromFeedID,
+	})
+}
+
+func (s *sqliteStore) MovePosts(ctx context.Context, arg database.MovePostsParams) error {
+	return s.q.MovePosts(ctx, sqlitedb.MovePostsParams{
+		ToFeedID:   arg.ToFeedID,
+		UpdatedAt:  now(),
+		FromFeedID: arg.FromFeedID,
+	})
+}
+
+// CreateFeedFollow inserts the follow and then reads it back with the feed
+// and user names, as SQLite does not allow INSERT inside a WITH clause.
+func (s *sqliteStore) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	arg.CreatedAt, arg.UpdatedAt = arg.CreatedAt.UTC(), arg.UpdatedAt.UTC()
+	follow, err := s.q.CreateFeedFollow(ctx, sqlitedb.CreateFeedFollowParams(arg))
+	if err != nil {
+		return database.CreateFeedFollowRow{}, err
+	}
+	row, err := s.q.GetFeedFollow(ctx, follow.ID)
+	return database.CreateFeedFollowRow(row), err
+}
+
+func (s *sqliteStore) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	follows, err := s.q.GetFeedFollowsForUser(ctx, userID)
+	return convertRows(follows, func(f sqlitedb.GetFeedFollowsForUserRow) database.GetFeedFollowsForUserRow {
+		return database.GetFeedFollowsForUserRow(f)
+	}), err
+}
+
+func (s *sqliteStore) DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) error {
+	return s.q.DeleteFeedFollow(ctx, sqlitedb.DeleteFeedFollowParams(arg))
+}
+
+func (s *sqliteStore) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	arg.CreatedAt, arg.UpdatedAt, arg.PublishedAt = arg.CreatedAt.UTC(), arg.UpdatedAt.UTC(), arg.PublishedAt.UTC()
+	post, err := s.q.CreatePost(ctx, sqlitedb.CreatePostParams(arg))
+	return database.Post(post), err
+}
+
+func (s *sqliteStore) SetPostContent(ctx context.Context, arg database.SetPostContentParams) error {
+	return s.q.SetPostContent(ctx, sqlitedb.SetPostContentParams{
+		Content:   arg.Content,
+		UpdatedAt: now(),
+		ID:        arg.ID,
+	})
+}
+
+func (s *sqliteStore) SetPostRead(ctx context.Context, arg database.SetPostReadParams) error {
+	return s.q.SetPostRead(ctx, sqlitedb.SetPostReadParams{
+		UserID:    arg.UserID,
+		PostID:    arg.PostID,
+		Read:      arg.Read,
+		UpdatedAt: now(),
+	})
+}
+
+func (s *sqliteStore) SetPostStarred(ctx context.Context, arg database.SetPostStarredParams) error {
+	return s.q.SetPostStarred(ctx, sqlitedb.SetPostStarredParams{
+		UserID:    arg.UserID,
+		PostID:    arg.PostID,
+		Starred:   arg.Starred,
+		UpdatedAt: now(),
+	})
+}
+
+func (s *sqliteStore) GetReaderItems(ctx context.Context, arg database.GetReaderItemsParams) ([]database.GetReaderItemsRow, error) {
+	params := sqlitedb.GetReaderItemsParams{
+		UserID:      arg.UserID,
+		ExcludeRead: arg.ExcludeRead,
+		OnlyRead:    arg.OnlyRead,
+		OnlyStarred: arg.OnlyStarred,
+		NewerThan:   nullTime(arg.NewerThan),
+		OlderThan:   nullTime(arg.OlderThan),
+		SkipItems:   int64(arg.SkipItems),
+		MaxItems:    int64(arg.MaxItems),
+		OldestFirst: arg.OldestFirst,
+	}
+	if arg.FeedID.Valid {
+		params.FeedID = arg.FeedID.UUID
+	}
+	if arg.FolderID.Valid {
+		params.FolderID = arg.FolderID.UUID
+	}
+	if arg.Tag.Valid {
+		params.Tag = arg.Tag.String
+	}
+
+	items, err := s.q.GetReaderItems(ctx, params)
+	return convertRows(items, func(i sqlitedb.GetReaderItemsRow) database.GetReaderItemsRow {
+		return database.GetReaderItemsRow(i)
+	}), err
+}
+
+func (s *sqliteStore) GetReaderItemsBySeq(ctx context.Context, arg database.GetReaderItemsBySeqParams) ([]database.GetReaderItemsBySeqRow, error) {
+	if len(arg.Seqs) == 0 {
+		return nil, nil
+	}
+	items, err := s.q.GetReaderItemsBySeq(ctx, sqlitedb.GetReaderItemsBySeqParams(arg))
+	return convertRows(items, func(i sqlitedb.GetReaderItemsBySeqRow) database.GetReaderItemsBySeqRow {
+		return database.GetReaderItemsBySeqRow(i)
+	}), err
+}
+
+func (s *sqliteStore) SearchPosts(ctx context.Context, arg database.SearchPostsParams) ([]database.SearchPostsRow, error) {
+	items, err := s.q.SearchPosts(ctx, sqlitedb.SearchPostsParams{
+		UserID:   arg.UserID,
+		Query:    arg.Query,
+		MaxItems: int64(arg.MaxItems),
+	})
+	return convertRows(items, func(i sqlitedb.SearchPostsRow) database.SearchPostsRow {
+		return database.SearchPostsRow(i)
+	}), err
+}
+
+func (s *sqliteStore) SetPostHidden(ctx context.Context, arg database.SetPostHiddenParams) error {
+	return s.q.SetPostHidden(ctx, sqlitedb.SetPostHiddenParams{
+		UserID:    arg.UserID,
+		PostID:    arg.PostID,
+		Hidden:    arg.Hidden,
+		UpdatedAt: now(),
+	})
+}
+
+func (s *sqliteStore) AddPostTag(ctx context.Context, arg database.AddPostTagParams) error {
+	return s.q.AddPostTag(ctx, sqlitedb.AddPostTagParams{
+		UserID:    arg.UserID,
+		PostID:    arg.PostID,
+		Tag:       arg.Tag,
+		CreatedAt: now(),
+	})
+}
+
+func (s *sqliteStore) RemovePostTag(ctx context.Context, arg database.RemovePostTagParams) (database.PostTag, error) {
+	tag, err := s.q.RemovePostTag(ctx, sqlitedb.RemovePostTagParams(arg))
+	return database.PostTag(tag), err
+}
+
+func (s *sqliteStore) GetPostTags(ctx context.Context, arg database.GetPostTagsParams) ([]string, error) {
+	return s.q.GetPostTags(ctx, sqlitedb.GetPostTagsParams(arg))
+}
+
+func (s *sqliteStore) GetTagsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetTagsForUserRow, error) {
+	tags, err := s.q.GetTagsForUser(ctx, userID)
+	return convertRows(tags, func(t sqlitedb.GetTagsForUserRow) database.GetTagsForUserRow {
+		return database.GetTagsForUserRow(t)
+	}), err
+}
+
+func (s *sqliteStore) GetPost(ctx context.Context, id uuid.UUID) (database.Post, error) {
+	post, err := s.q.GetPost(ctx, id)
+	return database.Post(post), err
+}
+
+func (s *sqliteStore) GetPostByURL(ctx context.Context, url string) (database.Post, error) {
+	post, err := s.q.GetPostByURL(ctx, url)
+	return database.Post(post), err
+}
+
+func (s *sqliteStore) GetPostBySeq(ctx context.Context, seq int64) (database.Post, error) {
+	post, err := s.q.GetPostBySeq(ctx, seq)
+	return database.Post(post), err
+}
+
+func (s *sqliteStore) CreateRule(ctx context.Context, arg database.CreateRuleParams) (database.Rule, error) {
+	arg.CreatedAt, arg.UpdatedAt = arg.CreatedAt.UTC(), arg.UpdatedAt.UTC()
+	rule, err := s.q.CreateRule(ctx, sqlitedb.CreateRuleParams(arg))
+	return database.Rule(rule), err
+}
+
+func (s *sqliteStore) GetRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.GetRulesForUserRow, error) {
+	rules, err := s.q.GetRulesForUser(ctx, userID)
+	return convertRows(rules, func(r sqlitedb.GetRulesForUserRow) database.GetRulesForUserRow {
+		return database.GetRulesForUserRow(r)
+	}), err
+}
+
+func (s *sqliteStore) GetRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]database.Rule, error) {
+	rules, err := s.q.GetRulesForFeed(ctx, feedID)
+	return convertRows(rules, func(r sqlitedb.Rule) database.Rule {
+		return database.Rule(r)
+	}), err
+}
+
+func (s *sqliteStore) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	return s.q.DeleteRule(ctx, id)
+}
+
+func (s *sqliteStore) MoveFeedRules(ctx context.Context, arg database.MoveFeedRulesParams) error {
+	return s.q.MoveFeedRules(ctx, sqlitedb.MoveFeedRulesParams{
+		ToFeedID:   uuid.NullUUID{UUID: arg.ToFeedID, Valid: true},
+		UpdatedAt:  now(),
+		FromFeedID: uuid.NullUUID{UUID: arg.FromFeedID, Valid: true},
+	})
+}
+
+func (s *sqliteStore) CreateFolder(ctx context.Context, arg database.CreateFolderParams) (database.Folder, error) {
+	arg.CreatedAt, arg.UpdatedAt = arg.CreatedAt.UTC(), arg.UpdatedAt.UTC()
+	folder, err := s.q.CreateFolder(ctx, sqlitedb.CreateFolderParams(arg))
+	return database.Folder(folder), err
+}
+
+func (s *sqliteStore) GetFoldersForUser(ctx context.Context, userID uuid.UUID) ([]database.Folder, error) {
+	folders, err := s.q.GetFoldersForUser(ctx, userID)
+	return convertRows(folders, func(f sqlitedb.Folder) database.Folder { return database.Folder(f) }), err
+}
+
+func (s *sqliteStore) GetFolderByName(ctx context.Context, arg database.GetFolderByNameParams) (database.Folder, error) {
+	folder, err := s.q.GetFolderByName(ctx, sqlitedb.GetFolderByNameParams(arg))
+	return database.Folder(folder), err
+}
+
+func (s *sqliteStore) RenameFolder(ctx context.Context, arg database.RenameFolderParams) (database.Folder, error) {
+	folder, err := s.q.RenameFolder(ctx, sqlitedb.RenameFolderParams{
+		Name:      arg.Name,
+		UpdatedAt: now(),
+		ID:        arg.ID,
+	})
+	return database.Folder(folder), err
+}
+
+func (s *sqliteStore) DeleteFolder(ctx context.Context, id uuid.UUID) error {
+	return s.q.DeleteFolder(ctx, id)
+}
+
+func (s *sqliteStore) SetFeedFollowFolder(ctx context.Context, arg database.SetFeedFollowFolderParams) (database.FeedFollow, error) {
+	follow, err := s.q.SetFeedFollowFolder(ctx, sqlitedb.SetFeedFollowFolderParams{
+		FolderID:  arg.FolderID,
+		UpdatedAt: now(),
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+	})
+	return database.FeedFollow(follow), err
+}
+
+func (s *sqliteStore) GetUnreadCountsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetUnreadCountsForUserRow, error) {
+	counts, err := s.q.GetUnreadCountsForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []database.GetUnreadCountsForUserRow
+	for _, c := range counts {
+		newest, err := parseSQLiteTime(c.Newest)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, database.GetUnreadCountsForUserRow{FeedID: c.FeedID, Unread: c.Unread, Newest: newest})
+	}
+	return rows, nil
+}
+
+func convertRows[T, U any](rows []T, convert func(T) U) []U {
+	if rows == nil {
+		return nil
+	}
+	converted := make([]U, len(rows))
+	for i, row := range rows {
+		converted[i] = convert(row)
+	}
+	return converted
+}
+
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+func nullTime(t sql.NullTime) any {
+	if !t.Valid {
+		return nil
+	}
+	return t.Time.UTC()
+}
+
+func parseSQLiteTime(v any) (time.Time, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		for _, layout := range sqliteTimeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("unexpected timestamp %v in SQLite result", v)
+}
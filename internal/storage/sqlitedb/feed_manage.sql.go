@@ -0,0 +1,219 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: feed_manage.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = ?
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const detachFeedPosts = `-- name: DetachFeedPosts :exec
+UPDATE posts
+SET feed_id = NULL
+WHERE feed_id = ?
+`
+
+func (q *Queries) DetachFeedPosts(ctx context.Context, feedID uuid.NullUUID) error {
+	_, err := q.db.ExecContext(ctx, detachFeedPosts, feedID)
+	return err
+}
+
+const getFeedStats = `-- name: GetFeedStats :one
+SELECT
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = ?1) AS followers,
+    (SELECT COUNT(*) FROM posts WHERE posts.feed_id = ?1) AS posts
+`
+
+type GetFeedStatsRow struct {
+	Followers int64
+	Posts     int64
+}
+
+func (q *Queries) GetFeedStats(ctx context.Context, feedID uuid.UUID) (GetFeedStatsRow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedStats, feedID)
+	var i GetFeedStatsRow
+	err := row.Scan(&i.Followers, &i.Posts)
+	return i, err
+}
+
+const markFeedDead = `-- name: MarkFeedDead :exec
+UPDATE feeds
+SET dead_at = ?1, updated_at = ?1
+WHERE id = ?2
+`
+
+type MarkFeedDeadParams struct {
+	Now sql.NullTime
+	ID  uuid.UUID
+}
+
+func (q *Queries) MarkFeedDead(ctx context.Context, arg MarkFeedDeadParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedDead, arg.Now, arg.ID)
+	return err
+}
+
+const moveFeedFollows = `-- name: MoveFeedFollows :exec
+UPDATE feed_follows
+SET feed_id = ?1, updated_at = ?2
+WHERE feed_follows.feed_id = ?3
+AND feed_follows.user_id NOT IN (
+    SELECT existing.user_id FROM feed_follows AS existing
+    WHERE existing.feed_id = ?1
+)
+`
+
+type MoveFeedFollowsParams struct {
+	ToFeedID   uuid.UUID
+	UpdatedAt  time.Time
+	FromFeedID uuid.UUID
+}
+
+// Moves follows to another feed, except those of users who already follow
+// it; they are left behind and go when the old feed is deleted.
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedFollows, arg.ToFeedID, arg.UpdatedAt, arg.FromFeedID)
+	return err
+}
+
+const movePosts = `-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = ?1, updated_at = ?2
+WHERE posts.feed_id = ?3
+`
+
+type MovePostsParams struct {
+	ToFeedID   uuid.NullUUID
+	UpdatedAt  time.Time
+	FromFeedID uuid.NullUUID
+}
+
+func (q *Queries) MovePosts(ctx context.Context, arg MovePostsParams) error {
+	_, err := q.db.ExecContext(ctx, movePosts, arg.ToFeedID, arg.UpdatedAt, arg.FromFeedID)
+	return err
+}
+
+const renameFeed = `-- name: RenameFeed :one
+UPDATE feeds
+SET name = ?1, updated_at = ?2
+WHERE id = ?3
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, last_fetch_error, dead_at, fetch_full_content
+`
+
+type RenameFeedParams struct {
+	Name      string
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) RenameFeed(ctx context.Context, arg RenameFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, renameFeed, arg.Name, arg.UpdatedAt, arg.ID)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.LastFetchError,
+		&i.DeadAt,
+		&i.FetchFullContent,
+	)
+	return i, err
+}
+
+const setFeedFetchError = `-- name: SetFeedFetchError :exec
+UPDATE feeds
+SET last_fetch_error = ?1
+WHERE id = ?2
+`
+
+type SetFeedFetchErrorParams struct {
+	LastFetchError sql.NullString
+	ID             uuid.UUID
+}
+
+func (q *Queries) SetFeedFetchError(ctx context.Context, arg SetFeedFetchErrorParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFetchError, arg.LastFetchError, arg.ID)
+	return err
+}
+
+const setFeedFullContent = `-- name: SetFeedFullContent :one
+UPDATE feeds
+SET fetch_full_content = ?1, updated_at = ?2
+WHERE id = ?3
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, last_fetch_error, dead_at, fetch_full_content
+`
+
+type SetFeedFullContentParams struct {
+	FetchFullContent bool
+	UpdatedAt        time.Time
+	ID               uuid.UUID
+}
+
+func (q *Queries) SetFeedFullContent(ctx context.Context, arg SetFeedFullContentParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedFullContent, arg.FetchFullContent, arg.UpdatedAt, arg.ID)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.LastFetchError,
+		&i.DeadAt,
+		&i.FetchFullContent,
+	)
+	return i, err
+}
+
+const setFeedURL = `-- name: SetFeedURL :one
+UPDATE feeds
+SET url = ?1, updated_at = ?2, dead_at = NULL
+WHERE id = ?3
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, last_fetch_error, dead_at, fetch_full_content
+`
+
+type SetFeedURLParams struct {
+	Url       string
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+// A feed given a new url is fetched again even if the old one was gone.
+func (q *Queries) SetFeedURL(ctx context.Context, arg SetFeedURLParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedURL, arg.Url, arg.UpdatedAt, arg.ID)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.LastFetchError,
+		&i.DeadAt,
+		&i.FetchFullContent,
+	)
+	return i, err
+}
@@ -0,0 +1,31 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: post_content.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const setPostContent = `-- name: SetPostContent :exec
+UPDATE posts
+SET content = ?1, updated_at = ?2
+WHERE id = ?3
+`
+
+type SetPostContentParams struct {
+	Content   sql.NullString
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) SetPostContent(ctx context.Context, arg SetPostContentParams) error {
+	_, err := q.db.ExecContext(ctx, setPostContent, arg.Content, arg.UpdatedAt, arg.ID)
+	return err
+}
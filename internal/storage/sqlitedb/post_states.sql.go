@@ -0,0 +1,85 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: post_states.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const setPostHidden = `-- name: SetPostHidden :exec
+INSERT INTO post_states (user_id, post_id, hidden, updated_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET hidden = excluded.hidden, updated_at = excluded.updated_at
+`
+
+type SetPostHiddenParams struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	Hidden    bool
+	UpdatedAt time.Time
+}
+
+func (q *Queries) SetPostHidden(ctx context.Context, arg SetPostHiddenParams) error {
+	_, err := q.db.ExecContext(ctx, setPostHidden,
+		arg.UserID,
+		arg.PostID,
+		arg.Hidden,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
+const setPostRead = `-- name: SetPostRead :exec
+INSERT INTO post_states (user_id, post_id, read, updated_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read = excluded.read, updated_at = excluded.updated_at
+`
+
+type SetPostReadParams struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	Read      bool
+	UpdatedAt time.Time
+}
+
+func (q *Queries) SetPostRead(ctx context.Context, arg SetPostReadParams) error {
+	_, err := q.db.ExecContext(ctx, setPostRead,
+		arg.UserID,
+		arg.PostID,
+		arg.Read,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
+const setPostStarred = `-- name: SetPostStarred :exec
+INSERT INTO post_states (user_id, post_id, starred, updated_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET starred = excluded.starred, updated_at = excluded.updated_at
+`
+
+type SetPostStarredParams struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	Starred   bool
+	UpdatedAt time.Time
+}
+
+func (q *Queries) SetPostStarred(ctx context.Context, arg SetPostStarredParams) error {
+	_, err := q.db.ExecContext(ctx, setPostStarred,
+		arg.UserID,
+		arg.PostID,
+		arg.Starred,
+		arg.UpdatedAt,
+	)
+	return err
+}
@@ -0,0 +1,90 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: posts.sql
+
+package sqlitedb
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const getPost = `-- name: GetPost :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, seq, summary, content, author, categories FROM posts
+WHERE id = ?
+`
+
+func (q *Queries) GetPost(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPost, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Seq,
+		&i.Summary,
+		&i.Content,
+		&i.Author,
+		&i.Categories,
+	)
+	return i, err
+}
+
+const getPostBySeq = `-- name: GetPostBySeq :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, seq, summary, content, author, categories FROM posts
+WHERE seq = ?
+`
+
+func (q *Queries) GetPostBySeq(ctx context.Context, seq int64) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostBySeq, seq)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Seq,
+		&i.Summary,
+		&i.Content,
+		&i.Author,
+		&i.Categories,
+	)
+	return i, err
+}
+
+const getPostByURL = `-- name: GetPostByURL :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, seq, summary, content, author, categories FROM posts
+WHERE url = ?
+`
+
+func (q *Queries) GetPostByURL(ctx context.Context, url string) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByURL, url)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Seq,
+		&i.Summary,
+		&i.Content,
+		&i.Author,
+		&i.Categories,
+	)
+	return i, err
+}
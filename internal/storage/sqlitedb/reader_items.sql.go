@@ -0,0 +1,283 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: reader_items.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const getReaderItems = `-- name: GetReaderItems :many
+WITH options AS (
+    SELECT CAST(?12 AS BOOLEAN) AS oldest_first
+)
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.seq, posts.summary, posts.content, posts.author, posts.categories,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    CAST(COALESCE(post_states.read, FALSE) AS BOOLEAN) AS read,
+    CAST(COALESCE(post_states.starred, FALSE) AS BOOLEAN) AS starred
+FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id AND feed_follows.user_id = ?1
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_states
+ON posts.id = post_states.post_id AND post_states.user_id = ?1
+CROSS JOIN options
+WHERE (?2 IS NULL OR posts.feed_id = ?2)
+AND (?3 IS NULL OR feed_follows.folder_id = ?3)
+AND (?4 IS NULL OR EXISTS (
+    SELECT 1 FROM post_tags
+    WHERE post_tags.user_id = ?1 AND post_tags.post_id = posts.id AND post_tags.tag = ?4
+))
+AND COALESCE(post_states.hidden, FALSE) = FALSE
+AND (CAST(?5 AS BOOLEAN) = FALSE OR COALESCE(post_states.read, FALSE) = FALSE)
+AND (CAST(?6 AS BOOLEAN) = FALSE OR COALESCE(post_states.read, FALSE) = TRUE)
+AND (CAST(?7 AS BOOLEAN) = FALSE OR COALESCE(post_states.starred, FALSE) = TRUE)
+AND (?8 IS NULL OR posts.published_at >= ?8)
+AND (?9 IS NULL OR posts.published_at <= ?9)
+ORDER BY
+    CASE WHEN options.oldest_first THEN posts.published_at END ASC,
+    posts.published_at DESC
+LIMIT ?11
+OFFSET ?10
+`
+
+type GetReaderItemsParams struct {
+	UserID      uuid.UUID
+	FeedID      interface{}
+	FolderID    interface{}
+	Tag         interface{}
+	ExcludeRead bool
+	OnlyRead    bool
+	OnlyStarred bool
+	NewerThan   interface{}
+	OlderThan   interface{}
+	SkipItems   int64
+	MaxItems    int64
+	OldestFirst bool
+}
+
+type GetReaderItemsRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt time.Time
+	FeedID      uuid.NullUUID
+	Seq         int64
+	Summary     sql.NullString
+	Content     sql.NullString
+	Author      sql.NullString
+	Categories  sql.NullString
+	FeedName    string
+	FeedUrl     string
+	Read        bool
+	Starred     bool
+}
+
+// sqlc does not bind parameters in the ORDER BY clause of SQLite queries,
+// so the sort order is passed in through a one-row CTE.
+func (q *Queries) GetReaderItems(ctx context.Context, arg GetReaderItemsParams) ([]GetReaderItemsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getReaderItems,
+		arg.UserID,
+		arg.FeedID,
+		arg.FolderID,
+		arg.Tag,
+		arg.ExcludeRead,
+		arg.OnlyRead,
+		arg.OnlyStarred,
+		arg.NewerThan,
+		arg.OlderThan,
+		arg.SkipItems,
+		arg.MaxItems,
+		arg.OldestFirst,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReaderItemsRow
+	for rows.Next() {
+		var i GetReaderItemsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Seq,
+			&i.Summary,
+			&i.Content,
+			&i.Author,
+			&i.Categories,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.Read,
+			&i.Starred,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReaderItemsBySeq = `-- name: GetReaderItemsBySeq :many
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.seq, posts.summary, posts.content, posts.author, posts.categories,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    CAST(COALESCE(post_states.read, FALSE) AS BOOLEAN) AS read,
+    CAST(COALESCE(post_states.starred, FALSE) AS BOOLEAN) AS starred
+FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id AND feed_follows.user_id = ?1
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_states
+ON posts.id = post_states.post_id AND post_states.user_id = ?1
+WHERE posts.seq IN (/*SLICE:seqs*/?)
+ORDER BY posts.published_at DESC
+`
+
+type GetReaderItemsBySeqParams struct {
+	UserID uuid.UUID
+	Seqs   []int64
+}
+
+type GetReaderItemsBySeqRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt time.Time
+	FeedID      uuid.NullUUID
+	Seq         int64
+	Summary     sql.NullString
+	Content     sql.NullString
+	Author      sql.NullString
+	Categories  sql.NullString
+	FeedName    string
+	FeedUrl     string
+	Read        bool
+	Starred     bool
+}
+
+func (q *Queries) GetReaderItemsBySeq(ctx context.Context, arg GetReaderItemsBySeqParams) ([]GetReaderItemsBySeqRow, error) {
+	query := getReaderItemsBySeq
+	var queryParams []interface{}
+	queryParams = append(queryParams, arg.UserID)
+	if len(arg.Seqs) > 0 {
+		for _, v := range arg.Seqs {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:seqs*/?", strings.Repeat(",?", len(arg.Seqs))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:seqs*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReaderItemsBySeqRow
+	for rows.Next() {
+		var i GetReaderItemsBySeqRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Seq,
+			&i.Summary,
+			&i.Content,
+			&i.Author,
+			&i.Categories,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.Read,
+			&i.Starred,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUnreadCountsForUser = `-- name: GetUnreadCountsForUser :many
+SELECT
+    posts.feed_id,
+    COUNT(posts.id) AS unread,
+    MAX(posts.published_at) AS newest
+FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+LEFT JOIN post_states
+ON posts.id = post_states.post_id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = ?
+AND NOT COALESCE(post_states.read, FALSE)
+AND NOT COALESCE(post_states.hidden, FALSE)
+GROUP BY posts.feed_id
+`
+
+type GetUnreadCountsForUserRow struct {
+	FeedID uuid.NullUUID
+	Unread int64
+	Newest interface{}
+}
+
+func (q *Queries) GetUnreadCountsForUser(ctx context.Context, userID uuid.UUID) ([]GetUnreadCountsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUnreadCountsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUnreadCountsForUserRow
+	for rows.Next() {
+		var i GetUnreadCountsForUserRow
+		if err := rows.Scan(&i.FeedID, &i.Unread, &i.Newest); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,91 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: reset.sql
+
+package sqlitedb
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const deleteAllFeeds = `-- name: DeleteAllFeeds :exec
+DELETE FROM feeds
+`
+
+func (q *Queries) DeleteAllFeeds(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteAllFeeds)
+	return err
+}
+
+const deleteAllPosts = `-- name: DeleteAllPosts :exec
+DELETE FROM posts
+`
+
+func (q *Queries) DeleteAllPosts(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteAllPosts)
+	return err
+}
+
+const deleteKeptPosts = `-- name: DeleteKeptPosts :exec
+DELETE FROM posts
+WHERE feed_id IS NULL
+`
+
+// SQLite has no DELETE in WITH, so unlike on PostgreSQL the posts kept
+// without a feed are deleted on their own, in the same transaction as
+// DeleteAllUsers or DeleteAllFeeds.
+func (q *Queries) DeleteKeptPosts(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteKeptPosts)
+	return err
+}
+
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = ?
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, id)
+	return err
+}
+
+const getResetCounts = `-- name: GetResetCounts :one
+SELECT
+    (SELECT COUNT(*) FROM users
+     WHERE ?1 IS NULL OR users.id = ?1) AS users,
+    (SELECT COUNT(*) FROM feeds
+     WHERE ?1 IS NULL OR feeds.user_id = ?1) AS feeds,
+    (SELECT COUNT(*) FROM feed_follows
+     INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+     WHERE ?1 IS NULL
+     OR feed_follows.user_id = ?1
+     OR feeds.user_id = ?1) AS feed_follows,
+    (SELECT COUNT(*) FROM posts
+     LEFT JOIN feeds ON posts.feed_id = feeds.id
+     WHERE ?1 IS NULL OR feeds.user_id = ?1) AS posts
+`
+
+type GetResetCountsRow struct {
+	Users       int64
+	Feeds       int64
+	FeedFollows int64
+	Posts       int64
+}
+
+// Counts the rows a reset would delete, for every user or only the one
+// given: their feeds, the posts of those feeds and every follow of them.
+// Posts kept without a feed only count for every user.
+func (q *Queries) GetResetCounts(ctx context.Context, userID interface{}) (GetResetCountsRow, error) {
+	row := q.db.QueryRowContext(ctx, getResetCounts, userID)
+	var i GetResetCountsRow
+	err := row.Scan(
+		&i.Users,
+		&i.Feeds,
+		&i.FeedFollows,
+		&i.Posts,
+	)
+	return i, err
+}
@@ -0,0 +1,212 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: rules.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createRule = `-- name: CreateRule :one
+INSERT INTO rules (
+    id,
+    created_at,
+    updated_at,
+    user_id,
+    feed_id,
+    field,
+    pattern,
+    is_regex,
+    action,
+    tag
+)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?
+)
+RETURNING id, created_at, updated_at, user_id, feed_id, field, pattern, is_regex, "action", tag
+`
+
+type CreateRuleParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Field     string
+	Pattern   string
+	IsRegex   bool
+	Action    string
+	Tag       sql.NullString
+}
+
+func (q *Queries) CreateRule(ctx context.Context, arg CreateRuleParams) (Rule, error) {
+	row := q.db.QueryRowContext(ctx, createRule,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+		arg.Field,
+		arg.Pattern,
+		arg.IsRegex,
+		arg.Action,
+		arg.Tag,
+	)
+	var i Rule
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.Field,
+		&i.Pattern,
+		&i.IsRegex,
+		&i.Action,
+		&i.Tag,
+	)
+	return i, err
+}
+
+const deleteRule = `-- name: DeleteRule :exec
+DELETE FROM rules
+WHERE id = ?
+`
+
+func (q *Queries) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteRule, id)
+	return err
+}
+
+const getRulesForFeed = `-- name: GetRulesForFeed :many
+SELECT rules.id, rules.created_at, rules.updated_at, rules.user_id, rules.feed_id, rules.field, rules.pattern, rules.is_regex, rules."action", rules.tag FROM rules
+INNER JOIN feed_follows
+ON rules.user_id = feed_follows.user_id AND feed_follows.feed_id = ?1
+WHERE rules.feed_id IS NULL OR rules.feed_id = ?1
+ORDER BY rules.created_at, rules.id
+`
+
+// Returns the rules that apply to new posts of a feed: those of every user
+// following it, for all feeds or for this one.
+func (q *Queries) GetRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]Rule, error) {
+	rows, err := q.db.QueryContext(ctx, getRulesForFeed, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Rule
+	for rows.Next() {
+		var i Rule
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.Field,
+			&i.Pattern,
+			&i.IsRegex,
+			&i.Action,
+			&i.Tag,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRulesForUser = `-- name: GetRulesForUser :many
+SELECT rules.id, rules.created_at, rules.updated_at, rules.user_id, rules.feed_id, rules.field, rules.pattern, rules.is_regex, rules."action", rules.tag, feeds.url AS feed_url FROM rules
+LEFT JOIN feeds
+ON rules.feed_id = feeds.id
+WHERE rules.user_id = ?
+ORDER BY rules.created_at, rules.id
+`
+
+type GetRulesForUserRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Field     string
+	Pattern   string
+	IsRegex   bool
+	Action    string
+	Tag       sql.NullString
+	FeedUrl   sql.NullString
+}
+
+func (q *Queries) GetRulesForUser(ctx context.Context, userID uuid.UUID) ([]GetRulesForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRulesForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRulesForUserRow
+	for rows.Next() {
+		var i GetRulesForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.Field,
+			&i.Pattern,
+			&i.IsRegex,
+			&i.Action,
+			&i.Tag,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const moveFeedRules = `-- name: MoveFeedRules :exec
+UPDATE rules
+SET feed_id = ?1, updated_at = ?2
+WHERE rules.feed_id = ?3
+`
+
+type MoveFeedRulesParams struct {
+	ToFeedID   uuid.NullUUID
+	UpdatedAt  time.Time
+	FromFeedID uuid.NullUUID
+}
+
+func (q *Queries) MoveFeedRules(ctx context.Context, arg MoveFeedRulesParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedRules, arg.ToFeedID, arg.UpdatedAt, arg.FromFeedID)
+	return err
+}
@@ -0,0 +1,50 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: user_api_token.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const getUserByAPITokenHash = `-- name: GetUserByAPITokenHash :one
+SELECT id, created_at, updated_at, name, api_token_hash FROM users
+WHERE api_token_hash = ?
+LIMIT 1
+`
+
+func (q *Queries) GetUserByAPITokenHash(ctx context.Context, apiTokenHash sql.NullString) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByAPITokenHash, apiTokenHash)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.ApiTokenHash,
+	)
+	return i, err
+}
+
+const setUserAPITokenHash = `-- name: SetUserAPITokenHash :exec
+UPDATE users
+SET api_token_hash = ?1, updated_at = ?2
+WHERE id = ?3
+`
+
+type SetUserAPITokenHashParams struct {
+	ApiTokenHash sql.NullString
+	UpdatedAt    time.Time
+	ID           uuid.UUID
+}
+
+func (q *Queries) SetUserAPITokenHash(ctx context.Context, arg SetUserAPITokenHashParams) error {
+	_, err := q.db.ExecContext(ctx, setUserAPITokenHash, arg.ApiTokenHash, arg.UpdatedAt, arg.ID)
+	return err
+}
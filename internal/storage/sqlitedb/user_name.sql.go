@@ -0,0 +1,29 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: user_name.sql
+
+package sqlitedb
+
+import (
+	"context"
+)
+
+const getUser = `-- name: GetUser :one
+SELECT id, created_at, updated_at, name, api_token_hash FROM users
+WHERE name = ?
+LIMIT 1
+`
+
+func (q *Queries) GetUser(ctx context.Context, name string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUser, name)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.ApiTokenHash,
+	)
+	return i, err
+}
@@ -0,0 +1,106 @@
+// Package storage opens the database gator keeps its users, feeds and posts
+// in. PostgreSQL and SQLite are supported, chosen by the scheme of db_url.
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type Backend string
+
+const (
+	Postgres Backend = "postgres"
+	SQLite   Backend = "sqlite"
+)
+
+const sqliteScheme = "sqlite:"
+
+// Store runs the queries used by the commands. Every backend returns the
+// types generated for PostgreSQL in the database package.
+type Store interface {
+	database.Querier
+	Backend() Backend
+	// DB returns the connection pool, for running migrations.
+	DB() *sql.DB
+	Close() error
+}
+
+// Open connects to the database named by dbURL. URLs starting with
+// "sqlite:" open a SQLite file, e.g. sqlite:///home/alice/gator.db or
+// sqlite:gator.db for a path relative to the working directory. Anything
+// else is passed to the PostgreSQL driver.
+func Open(dbURL string) (Store, error) {
+	backend, dsn, err := Parse(dbURL)
+	if err != nil {
+		return nil, err
+	}
+	if backend == SQLite {
+		return openSQLite(dsn)
+	}
+	return openPostgres(dsn)
+}
+
+// Parse returns the backend selected by dbURL and the data source name its
+// driver is opened with.
+func Parse(dbURL string) (Backend, string, error) {
+	path, found := strings.CutPrefix(dbURL, sqliteScheme)
+	if !found {
+		return Postgres, dbURL, nil
+	}
+
+	path = strings.TrimPrefix(path, "//")
+	if path == "" {
+		return "", "", errors.New("sqlite URL has no file path, e.g. sqlite:///home/alice/gator.db")
+	}
+	if rest, found := strings.CutPrefix(path, "~/"); found {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", err
+		}
+		path = filepath.Join(home, rest)
+	}
+
+	// Foreign keys are off by default in SQLite, and the busy timeout lets
+	// a second gator process wait for the lock instead of failing.
+	return SQLite, fmt.Sprintf("%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite", path), nil
+}
+
+// ErrUniqueViolation is returned by stores that check UNIQUE constraints
+// themselves instead of leaving them to a database, such as the in-memory
+// store of the tests.
+var ErrUniqueViolation = errors.New("duplicate key value violates unique constraint")
+
+// IsUniqueViolation reports whether err is the database rejecting a
+// duplicate value for a UNIQUE column.
+func IsUniqueViolation(err error) bool {
+	if errors.Is(err, ErrUniqueViolation) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	return false
+}
+
+// IsDriverError reports whether err was returned by one of the database
+// drivers.
+func IsDriverError(err error) bool {
+	var pqErr *pq.Error
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &pqErr) || errors.As(err, &sqliteErr)
+}
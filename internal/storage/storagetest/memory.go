@@ -0,0 +1,1084 @@
+// Package storagetest provides an in-memory storage.Store for tests, so
+// that they need no database server. It is only imported by tests and is
+// never part of the gator binary.
+package storagetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/alifoo/blog-aggregator/internal/storage"
+	"github.com/google/uuid"
+)
+
+// Memory is the backend reported by the store returned by NewMemory.
+const Memory storage.Backend = "memory"
+
+var errForeignKeyViolation = errors.New("foreign key constraint failed")
+
+type postState struct {
+	read    bool
+	starred bool
+	hidden  bool
+}
+
+type postStateKey struct {
+	userID uuid.UUID
+	postID uuid.UUID
+}
+
+// memoryStore keeps everything in maps for tests. It enforces the same
+// UNIQUE and foreign key constraints as the SQL schema, and returns
+// sql.ErrNoRows where a query would find nothing. Slices keep rows in
+// insertion order so results are deterministic.
+type memoryStore struct {
+	mu      sync.Mutex
+	users   []database.User
+	feeds   []database.Feed
+	follows []database.FeedFollow
+	posts   []database.Post
+	states  map[postStateKey]postState
+	nextSeq int64
+	rules   []database.Rule
+	tags    []database.PostTag
+	folders []database.Folder
+}
+
+// NewMemory returns an empty store that lives only as long as the process.
+func NewMemory() storage.Store {
+	return &memoryStore{states: map[postStateKey]postState{}}
+}
+
+func (m *memoryStore) Backend() storage.Backend {
+	return Memory
+}
+
+// DB returns nil: there is no connection pool, and no schema to migrate.
+func (m *memoryStore) DB() *sql.DB {
+	return nil
+}
+
+func (m *memoryStore) Close() error {
+	return nil
+}
+
+func (m *memoryStore) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, u := range m.users {
+		if u.ID == arg.ID || u.Name == arg.Name {
+			return database.User{}, fmt.Errorf("%w: users.name", storage.ErrUniqueViolation)
+		}
+	}
+	user := database.User{ID: arg.ID, CreatedAt: arg.CreatedAt, UpdatedAt: arg.UpdatedAt, Name: arg.Name}
+	m.users = append(m.users, user)
+	return user, nil
+}
+
+func (m *memoryStore) GetUser(ctx context.Context, name string) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return findOne(m.users, func(u database.User) bool { return u.Name == name })
+}
+
+func (m *memoryStore) GetUserById(ctx context.Context, id uuid.UUID) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return findOne(m.users, func(u database.User) bool { return u.ID == id })
+}
+
+func (m *memoryStore) GetUserByAPITokenHash(ctx context.Context, apiTokenHash sql.NullString) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return findOne(m.users, func(u database.User) bool {
+		return apiTokenHash.Valid && u.ApiTokenHash.Valid && u.ApiTokenHash.String == apiTokenHash.String
+	})
+}
+
+func (m *memoryStore) GetUsers(ctx context.Context) ([]database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]database.User(nil), m.users...), nil
+}
+
+func (m *memoryStore) SetUserAPITokenHash(ctx context.Context, arg database.SetUserAPITokenHashParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, u := range m.users {
+		if u.ID != arg.ID && arg.ApiTokenHash.Valid && u.ApiTokenHash == arg.ApiTokenHash {
+			return fmt.Errorf("%w: users.api_token_hash", storage.ErrUniqueViolation)
+		}
+		if u.ID == arg.ID {
+			m.users[i].ApiTokenHash = arg.ApiTokenHash
+			m.users[i].UpdatedAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) RenameUser(ctx context.Context, arg database.RenameUserParams) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, u := range m.users {
+		if u.Name == arg.Name && u.ID != arg.ID {
+			return database.User{}, fmt.Errorf("%w: users.name", storage.ErrUniqueViolation)
+		}
+	}
+	for i, u := range m.users {
+		if u.ID == arg.ID {
+			m.users[i].Name = arg.Name
+			m.users[i].UpdatedAt = time.Now()
+			return m.users[i], nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+// DeleteAllUsers cascades to feeds, follows, posts and post states like
+// the SQL schema, and deletes the posts kept without a feed like the query.
+func (m *memoryStore) DeleteAllUsers(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteKeptPosts()
+	m.deleteFeeds(func(database.Feed) bool { return true })
+	m.users, m.rules, m.folders = nil, nil, nil
+	return nil
+}
+
+func (m *memoryStore) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.users = deleteWhere(m.users, func(u database.User) bool { return u.ID == id })
+	m.follows = deleteWhere(m.follows, func(f database.FeedFollow) bool { return f.UserID == id })
+	for key := range m.states {
+		if key.userID == id {
+			delete(m.states, key)
+		}
+	}
+	m.rules = deleteWhere(m.rules, func(r database.Rule) bool { return r.UserID == id })
+	m.tags = deleteWhere(m.tags, func(t database.PostTag) bool { return t.UserID == id })
+	m.folders = deleteWhere(m.folders, func(f database.Folder) bool { return f.UserID == id })
+	m.deleteFeeds(func(f database.Feed) bool { return f.UserID == id })
+	return nil
+}
+
+func (m *memoryStore) DeleteAllFeeds(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteKeptPosts()
+	m.deleteFeeds(func(database.Feed) bool { return true })
+	return nil
+}
+
+func (m *memoryStore) DeleteAllPosts(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.posts = nil
+	m.states = map[postStateKey]postState{}
+	m.tags = nil
+	return nil
+}
+
+// deleteKeptPosts removes the posts kept without a feed along with their
+// states and tags.
+func (m *memoryStore) deleteKeptPosts() {
+	m.deletePosts(func(p database.Post) bool { return !p.FeedID.Valid })
+}
+
+// deleteFeeds removes the matching feeds along with their follows, rules,
+// posts and the states and tags of those posts.
+func (m *memoryStore) deleteFeeds(match func(database.Feed) bool) {
+	deleted := map[uuid.UUID]bool{}
+	for _, f := range m.feeds {
+		if match(f) {
+			deleted[f.ID] = true
+		}
+	}
+	m.feeds = deleteWhere(m.feeds, match)
+	m.follows = deleteWhere(m.follows, func(f database.FeedFollow) bool { return deleted[f.FeedID] })
+	m.rules = deleteWhere(m.rules, func(r database.Rule) bool { return r.FeedID.Valid && deleted[r.FeedID.UUID] })
+
+	m.deletePosts(func(p database.Post) bool { return p.FeedID.Valid && deleted[p.FeedID.UUID] })
+}
+
+// deletePosts removes the matching posts along with their states and tags.
+func (m *memoryStore) deletePosts(match func(database.Post) bool) {
+	posts := map[uuid.UUID]bool{}
+	for _, p := range m.posts {
+		if match(p) {
+			posts[p.ID] = true
+		}
+	}
+	m.posts = deleteWhere(m.posts, match)
+	for key := range m.states {
+		if posts[key.postID] {
+			delete(m.states, key)
+		}
+	}
+	m.tags = deleteWhere(m.tags, func(t database.PostTag) bool { return posts[t.PostID] })
+}
+
+func (m *memoryStore) GetResetCounts(ctx context.Context, userID uuid.NullUUID) (database.GetResetCountsRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	all := !userID.Valid
+	feeds := map[uuid.UUID]bool{}
+	var counts database.GetResetCountsRow
+	for _, u := range m.users {
+		if all || u.ID == userID.UUID {
+			counts.Users++
+		}
+	}
+	for _, f := range m.feeds {
+		if all || f.UserID == userID.UUID {
+			feeds[f.ID] = true
+			counts.Feeds++
+		}
+	}
+	for _, f := range m.follows {
+		if all || f.UserID == userID.UUID || feeds[f.FeedID] {
+			counts.FeedFollows++
+		}
+	}
+	for _, p := range m.posts {
+		if all || feeds[p.FeedID.UUID] {
+			counts.Posts++
+		}
+	}
+	return counts, nil
+}
+
+func (m *memoryStore) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.userExists(arg.UserID) {
+		return database.Feed{}, fmt.Errorf("%w: feeds.user_id", errForeignKeyViolation)
+	}
+	for _, f := range m.feeds {
+		if f.ID == arg.ID || f.Url == arg.Url {
+			return database.Feed{}, fmt.Errorf("%w: feeds.url", storage.ErrUniqueViolation)
+		}
+	}
+	feed := database.Feed{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+		Url:       arg.Url,
+		UserID:    arg.UserID,
+	}
+	m.feeds = append(m.feeds, feed)
+	return feed, nil
+}
+
+func (m *memoryStore) GetFeedByURL(ctx context.Context, url string) (database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return findOne(m.feeds, func(f database.Feed) bool { return f.Url == url })
+}
+
+func (m *memoryStore) GetFeeds(ctx context.Context) ([]database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]database.Feed(nil), m.feeds...), nil
+}
+
+func (m *memoryStore) GetNextFeedToFetch(ctx context.Context) (database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var feeds []database.Feed
+	for _, f := range m.feeds {
+		if !f.DeadAt.Valid {
+			feeds = append(feeds, f)
+		}
+	}
+	if len(feeds) == 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	sort.SliceStable(feeds, func(i, j int) bool {
+		a, b := feeds[i].LastFetchedAt, feeds[j].LastFetchedAt
+		if !a.Valid || !b.Valid {
+			return !a.Valid && b.Valid
+		}
+		return a.Time.Before(b.Time)
+	})
+	return feeds[0], nil
+}
+
+func (m *memoryStore) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for i, f := range m.feeds {
+		if f.ID == id {
+			m.feeds[i].LastFetchedAt = sql.NullTime{Time: now, Valid: true}
+			m.feeds[i].UpdatedAt = now
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) RenameFeed(ctx context.Context, arg database.RenameFeedParams) (database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.updateFeed(arg.ID, func(f *database.Feed) { f.Name = arg.Name })
+}
+
+func (m *memoryStore) SetFeedURL(ctx context.Context, arg database.SetFeedURLParams) (database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, f := range m.feeds {
+		if f.Url == arg.Url && f.ID != arg.ID {
+			return database.Feed{}, fmt.Errorf("%w: feeds.url", storage.ErrUniqueViolation)
+		}
+	}
+	return m.updateFeed(arg.ID, func(f *database.Feed) {
+		f.Url = arg.Url
+		f.DeadAt = sql.NullTime{}
+	})
+}
+
+// updateFeed applies update to the feed with the given id. The caller must
+// hold the lock.
+func (m *memoryStore) updateFeed(id uuid.UUID, update func(*database.Feed)) (database.Feed, error) {
+	for i := range m.feeds {
+		if m.feeds[i].ID == id {
+			update(&m.feeds[i])
+			m.feeds[i].UpdatedAt = time.Now()
+			return m.feeds[i], nil
+		}
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+func (m *memoryStore) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteFeeds(func(f database.Feed) bool { return f.ID == id })
+	return nil
+}
+
+func (m *memoryStore) DetachFeedPosts(ctx context.Context, feedID uuid.NullUUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, p := range m.posts {
+		if p.FeedID == feedID {
+			m.posts[i].FeedID = uuid.NullUUID{}
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) SetFeedFetchError(ctx context.Context, arg database.SetFeedFetchErrorParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.feeds {
+		if m.feeds[i].ID == arg.ID {
+			m.feeds[i].LastFetchError = arg.LastFetchError
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) SetFeedFullContent(ctx context.Context, arg database.SetFeedFullContentParams) (database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.updateFeed(arg.ID, func(f *database.Feed) { f.FetchFullContent = arg.FetchFullContent })
+}
+
+func (m *memoryStore) GetFeedStats(ctx context.Context, feedID uuid.UUID) (database.GetFeedStatsRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var stats database.GetFeedStatsRow
+	for _, f := range m.follows {
+		if f.FeedID == feedID {
+			stats.Followers++
+		}
+	}
+	for _, p := range m.posts {
+		if p.FeedID.UUID == feedID {
+			stats.Posts++
+		}
+	}
+	return stats, nil
+}
+
+func (m *memoryStore) MarkFeedDead(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.updateFeed(id, func(f *database.Feed) { f.DeadAt = sql.NullTime{Time: time.Now(), Valid: true} })
+	return nil
+}
+
+func (m *memoryStore) MoveFeedFollows(ctx context.Context, arg database.MoveFeedFollowsParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	following := map[uuid.UUID]bool{}
+	for _, f := range m.follows {
+		if f.FeedID == arg.ToFeedID {
+			following[f.UserID] = true
+		}
+	}
+	for i, f := range m.follows {
+		if f.FeedID == arg.FromFeedID && !following[f.UserID] {
+			m.follows[i].FeedID = arg.ToFeedID
+			m.follows[i].UpdatedAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) MovePosts(ctx context.Context, arg database.MovePostsParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, p := range m.posts {
+		if p.FeedID == arg.FromFeedID {
+			m.posts[i].FeedID = arg.ToFeedID
+			m.posts[i].UpdatedAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, err := findOne(m.users, func(u database.User) bool { return u.ID == arg.UserID })
+	if err != nil {
+		return database.CreateFeedFollowRow{}, fmt.Errorf("%w: feed_follows.user_id", errForeignKeyViolation)
+	}
+	feed, err := findOne(m.feeds, func(f database.Feed) bool { return f.ID == arg.FeedID })
+	if err != nil {
+		return database.CreateFeedFollowRow{}, fmt.Errorf("%w: feed_follows.feed_id", errForeignKeyViolation)
+	}
+	for _, f := range m.follows {
+		if f.ID == arg.ID || (f.UserID == arg.UserID && f.FeedID == arg.FeedID) {
+			return database.CreateFeedFollowRow{}, fmt.Errorf("%w: feed_follows.user_id, feed_follows.feed_id", storage.ErrUniqueViolation)
+		}
+	}
+
+	follow := database.FeedFollow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+	}
+	m.follows = append(m.follows, follow)
+	return database.CreateFeedFollowRow{
+		ID:        follow.ID,
+		CreatedAt: follow.CreatedAt,
+		UpdatedAt: follow.UpdatedAt,
+		UserID:    follow.UserID,
+		FeedID:    follow.FeedID,
+		FolderID:  follow.FolderID,
+		FeedName:  feed.Name,
+		UserName:  user.Name,
+	}, nil
+}
+
+func (m *memoryStore) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rows []database.GetFeedFollowsForUserRow
+	for _, f := range m.follows {
+		if f.UserID != userID {
+			continue
+		}
+		feed, _ := findOne(m.feeds, func(fd database.Feed) bool { return fd.ID == f.FeedID })
+		user, _ := findOne(m.users, func(u database.User) bool { return u.ID == f.UserID })
+		row := database.GetFeedFollowsForUserRow{
+			ID:        f.ID,
+			CreatedAt: f.CreatedAt,
+			UpdatedAt: f.UpdatedAt,
+			UserID:    f.UserID,
+			FeedID:    f.FeedID,
+			FolderID:  f.FolderID,
+			FeedName:  feed.Name,
+			FeedUrl:   feed.Url,
+			UserName:  user.Name,
+		}
+		if folder, err := findOne(m.folders, func(fd database.Folder) bool { return f.FolderID.Valid && fd.ID == f.FolderID.UUID }); err == nil {
+			row.FolderName = sql.NullString{String: folder.Name, Valid: true}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (m *memoryStore) DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	feed, err := findOne(m.feeds, func(f database.Feed) bool { return f.Url == arg.Url })
+	if err != nil {
+		return nil
+	}
+	m.follows = deleteWhere(m.follows, func(f database.FeedFollow) bool {
+		return f.UserID == arg.UserID && f.FeedID == feed.ID
+	})
+	return nil
+}
+
+// CreatePost ignores posts whose URL is already stored and then returns
+// sql.ErrNoRows, like INSERT ... ON CONFLICT DO UPDATE ... WHERE RETURNING,
+// except for posts without a feed, which are given the feed of arg.
+func (m *memoryStore) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := findOne(m.feeds, func(f database.Feed) bool { return f.ID == arg.FeedID.UUID }); err != nil {
+		return database.Post{}, fmt.Errorf("%w: posts.feed_id", errForeignKeyViolation)
+	}
+	for i, p := range m.posts {
+		if p.Url == arg.Url && !p.FeedID.Valid {
+			m.posts[i].FeedID = arg.FeedID
+			m.posts[i].UpdatedAt = arg.UpdatedAt
+			return m.posts[i], nil
+		}
+		if p.Url == arg.Url {
+			return database.Post{}, sql.ErrNoRows
+		}
+		if p.ID == arg.ID {
+			return database.Post{}, fmt.Errorf("%w: posts.id", storage.ErrUniqueViolation)
+		}
+	}
+
+	m.nextSeq++
+	post := database.Post{
+		ID:          arg.ID,
+		CreatedAt:   arg.CreatedAt,
+		UpdatedAt:   arg.UpdatedAt,
+		Title:       arg.Title,
+		Url:         arg.Url,
+		Description: arg.Description,
+		PublishedAt: arg.PublishedAt,
+		FeedID:      arg.FeedID,
+		Seq:         m.nextSeq,
+		Summary:     arg.Summary,
+		Author:      arg.Author,
+		Categories:  arg.Categories,
+	}
+	m.posts = append(m.posts, post)
+	return post, nil
+}
+
+func (m *memoryStore) GetPost(ctx context.Context, id uuid.UUID) (database.Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return findOne(m.posts, func(p database.Post) bool { return p.ID == id })
+}
+
+func (m *memoryStore) GetPostByURL(ctx context.Context, url string) (database.Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return findOne(m.posts, func(p database.Post) bool { return p.Url == url })
+}
+
+func (m *memoryStore) GetPostBySeq(ctx context.Context, seq int64) (database.Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return findOne(m.posts, func(p database.Post) bool { return p.Seq == seq })
+}
+
+func (m *memoryStore) SetPostContent(ctx context.Context, arg database.SetPostContentParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.posts {
+		if m.posts[i].ID == arg.ID {
+			m.posts[i].Content = arg.Content
+			m.posts[i].UpdatedAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) SetPostRead(ctx context.Context, arg database.SetPostReadParams) error {
+	return m.updatePostState(arg.UserID, arg.PostID, func(s *postState) { s.read = arg.Read })
+}
+
+func (m *memoryStore) SetPostStarred(ctx context.Context, arg database.SetPostStarredParams) error {
+	return m.updatePostState(arg.UserID, arg.PostID, func(s *postState) { s.starred = arg.Starred })
+}
+
+func (m *memoryStore) SetPostHidden(ctx context.Context, arg database.SetPostHiddenParams) error {
+	return m.updatePostState(arg.UserID, arg.PostID, func(s *postState) { s.hidden = arg.Hidden })
+}
+
+func (m *memoryStore) updatePostState(userID, postID uuid.UUID, update func(*postState)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.userExists(userID) {
+		return fmt.Errorf("%w: post_states.user_id", errForeignKeyViolation)
+	}
+	if _, err := findOne(m.posts, func(p database.Post) bool { return p.ID == postID }); err != nil {
+		return fmt.Errorf("%w: post_states.post_id", errForeignKeyViolation)
+	}
+
+	key := postStateKey{userID: userID, postID: postID}
+	state := m.states[key]
+	update(&state)
+	m.states[key] = state
+	return nil
+}
+
+func (m *memoryStore) GetReaderItems(ctx context.Context, arg database.GetReaderItemsParams) ([]database.GetReaderItemsRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	inFolder := map[uuid.UUID]bool{}
+	for _, f := range m.follows {
+		if f.UserID == arg.UserID && f.FolderID == arg.FolderID {
+			inFolder[f.FeedID] = true
+		}
+	}
+
+	var rows []database.GetReaderItemsRow
+	for _, item := range m.readerItems(arg.UserID, false) {
+		switch {
+		case arg.FeedID.Valid && item.FeedID != arg.FeedID,
+			arg.FolderID.Valid && !inFolder[item.FeedID.UUID],
+			arg.Tag.Valid && !m.hasTag(arg.UserID, item.ID, arg.Tag.String),
+			arg.ExcludeRead && item.Read,
+			arg.OnlyRead && !item.Read,
+			arg.OnlyStarred && !item.Starred,
+			arg.NewerThan.Valid && item.PublishedAt.Before(arg.NewerThan.Time),
+			arg.OlderThan.Valid && item.PublishedAt.After(arg.OlderThan.Time):
+			continue
+		}
+		rows = append(rows, item)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if arg.OldestFirst {
+			return rows[i].PublishedAt.Before(rows[j].PublishedAt)
+		}
+		return rows[i].PublishedAt.After(rows[j].PublishedAt)
+	})
+
+	skip := min(int(arg.SkipItems), len(rows))
+	rows = rows[skip:]
+	return rows[:min(int(arg.MaxItems), len(rows))], nil
+}
+
+func (m *memoryStore) GetReaderItemsBySeq(ctx context.Context, arg database.GetReaderItemsBySeqParams) ([]database.GetReaderItemsBySeqRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seqs := map[int64]bool{}
+	for _, seq := range arg.Seqs {
+		seqs[seq] = true
+	}
+
+	var rows []database.GetReaderItemsBySeqRow
+	for _, item := range m.readerItems(arg.UserID, true) {
+		if seqs[item.Seq] {
+			rows = append(rows, database.GetReaderItemsBySeqRow(item))
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].PublishedAt.After(rows[j].PublishedAt) })
+	return rows, nil
+}
+
+// SearchPosts matches the query case-insensitively against the title and
+// the summary, or the description of posts stored without one.
+func (m *memoryStore) SearchPosts(ctx context.Context, arg database.SearchPostsParams) ([]database.SearchPostsRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	query := strings.ToLower(arg.Query)
+	var rows []database.SearchPostsRow
+	for _, item := range m.readerItems(arg.UserID, false) {
+		text := item.Summary
+		if !text.Valid {
+			text = item.Description
+		}
+		if strings.Contains(strings.ToLower(item.Title), query) || strings.Contains(strings.ToLower(text.String), query) {
+			rows = append(rows, database.SearchPostsRow(item))
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].PublishedAt.After(rows[j].PublishedAt) })
+	return rows[:min(int(arg.MaxItems), len(rows))], nil
+}
+
+func (m *memoryStore) GetUnreadCountsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetUnreadCountsForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rows []database.GetUnreadCountsForUserRow
+	index := map[uuid.UUID]int{}
+	for _, item := range m.readerItems(userID, false) {
+		if item.Read {
+			continue
+		}
+		i, exists := index[item.FeedID.UUID]
+		if !exists {
+			i = len(rows)
+			index[item.FeedID.UUID] = i
+			rows = append(rows, database.GetUnreadCountsForUserRow{FeedID: item.FeedID})
+		}
+		rows[i].Unread++
+		if item.PublishedAt.After(rows[i].Newest) {
+			rows[i].Newest = item.PublishedAt
+		}
+	}
+	return rows, nil
+}
+
+// readerItems returns the posts of the feeds userID follows, joined with
+// the feed and the user's read and starred states. Posts the user hid are
+// left out unless withHidden is set.
+func (m *memoryStore) readerItems(userID uuid.UUID, withHidden bool) []database.GetReaderItemsRow {
+	followed := map[uuid.UUID]bool{}
+	for _, f := range m.follows {
+		if f.UserID == userID {
+			followed[f.FeedID] = true
+		}
+	}
+
+	var rows []database.GetReaderItemsRow
+	for _, p := range m.posts {
+		if !followed[p.FeedID.UUID] {
+			continue
+		}
+		feed, _ := findOne(m.feeds, func(f database.Feed) bool { return f.ID == p.FeedID.UUID })
+		state := m.states[postStateKey{userID: userID, postID: p.ID}]
+		if state.hidden && !withHidden {
+			continue
+		}
+		rows = append(rows, database.GetReaderItemsRow{
+			ID:          p.ID,
+			CreatedAt:   p.CreatedAt,
+			UpdatedAt:   p.UpdatedAt,
+			Title:       p.Title,
+			Url:         p.Url,
+			Description: p.Description,
+			PublishedAt: p.PublishedAt,
+			FeedID:      p.FeedID,
+			Seq:         p.Seq,
+			Summary:     p.Summary,
+			Content:     p.Content,
+			Author:      p.Author,
+			Categories:  p.Categories,
+			FeedName:    feed.Name,
+			FeedUrl:     feed.Url,
+			Read:        state.read,
+			Starred:     state.starred,
+		})
+	}
+	return rows
+}
+
+func (m *memoryStore) CreateRule(ctx context.Context, arg database.CreateRuleParams) (database.Rule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.userExists(arg.UserID) {
+		return database.Rule{}, fmt.Errorf("%w: rules.user_id", errForeignKeyViolation)
+	}
+	if arg.FeedID.Valid {
+		if _, err := findOne(m.feeds, func(f database.Feed) bool { return f.ID == arg.FeedID.UUID }); err != nil {
+			return database.Rule{}, fmt.Errorf("%w: rules.feed_id", errForeignKeyViolation)
+		}
+	}
+	rule := database.Rule(arg)
+	m.rules = append(m.rules, rule)
+	return rule, nil
+}
+
+func (m *memoryStore) GetRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.GetRulesForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rows []database.GetRulesForUserRow
+	for _, r := range m.rules {
+		if r.UserID != userID {
+			continue
+		}
+		row := database.GetRulesForUserRow{
+			ID:        r.ID,
+			CreatedAt: r.CreatedAt,
+			UpdatedAt: r.UpdatedAt,
+			UserID:    r.UserID,
+			FeedID:    r.FeedID,
+			Field:     r.Field,
+			Pattern:   r.Pattern,
+			IsRegex:   r.IsRegex,
+			Action:    r.Action,
+			Tag:       r.Tag,
+		}
+		if feed, err := findOne(m.feeds, func(f database.Feed) bool { return r.FeedID.Valid && f.ID == r.FeedID.UUID }); err == nil {
+			row.FeedUrl = sql.NullString{String: feed.Url, Valid: true}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (m *memoryStore) GetRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]database.Rule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	following := map[uuid.UUID]bool{}
+	for _, f := range m.follows {
+		if f.FeedID == feedID {
+			following[f.UserID] = true
+		}
+	}
+	var rules []database.Rule
+	for _, r := range m.rules {
+		if following[r.UserID] && (!r.FeedID.Valid || r.FeedID.UUID == feedID) {
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+func (m *memoryStore) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rules = deleteWhere(m.rules, func(r database.Rule) bool { return r.ID == id })
+	return nil
+}
+
+func (m *memoryStore) MoveFeedRules(ctx context.Context, arg database.MoveFeedRulesParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, r := range m.rules {
+		if r.FeedID.Valid && r.FeedID.UUID == arg.FromFeedID {
+			m.rules[i].FeedID.UUID = arg.ToFeedID
+			m.rules[i].UpdatedAt = time.Now()
+		}
+	}
+	return nil
+}
+
+// AddPostTag ignores tags the post already has, like ON CONFLICT DO NOTHING.
+func (m *memoryStore) AddPostTag(ctx context.Context, arg database.AddPostTagParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.userExists(arg.UserID) {
+		return fmt.Errorf("%w: post_tags.user_id", errForeignKeyViolation)
+	}
+	if _, err := findOne(m.posts, func(p database.Post) bool { return p.ID == arg.PostID }); err != nil {
+		return fmt.Errorf("%w: post_tags.post_id", errForeignKeyViolation)
+	}
+	for _, t := range m.tags {
+		if t.UserID == arg.UserID && t.PostID == arg.PostID && t.Tag == arg.Tag {
+			return nil
+		}
+	}
+	m.tags = append(m.tags, database.PostTag{UserID: arg.UserID, PostID: arg.PostID, Tag: arg.Tag, CreatedAt: time.Now()})
+	return nil
+}
+
+func (m *memoryStore) RemovePostTag(ctx context.Context, arg database.RemovePostTagParams) (database.PostTag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	match := func(t database.PostTag) bool {
+		return t.UserID == arg.UserID && t.PostID == arg.PostID && t.Tag == arg.Tag
+	}
+	tag, err := findOne(m.tags, match)
+	if err != nil {
+		return database.PostTag{}, err
+	}
+	m.tags = deleteWhere(m.tags, match)
+	return tag, nil
+}
+
+func (m *memoryStore) GetPostTags(ctx context.Context, arg database.GetPostTagsParams) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var tags []string
+	for _, t := range m.tags {
+		if t.UserID == arg.UserID && t.PostID == arg.PostID {
+			tags = append(tags, t.Tag)
+		}
+	}
+	slices.Sort(tags)
+	return tags, nil
+}
+
+func (m *memoryStore) GetTagsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetTagsForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rows []database.GetTagsForUserRow
+	index := map[string]int{}
+	for _, t := range m.tags {
+		if t.UserID != userID {
+			continue
+		}
+		i, exists := index[t.Tag]
+		if !exists {
+			i = len(rows)
+			index[t.Tag] = i
+			rows = append(rows, database.GetTagsForUserRow{Tag: t.Tag})
+		}
+		rows[i].Posts++
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Tag < rows[j].Tag })
+	return rows, nil
+}
+
+func (m *memoryStore) hasTag(userID, postID uuid.UUID, tag string) bool {
+	return slices.ContainsFunc(m.tags, func(t database.PostTag) bool {
+		return t.UserID == userID && t.PostID == postID && t.Tag == tag
+	})
+}
+
+func (m *memoryStore) CreateFolder(ctx context.Context, arg database.CreateFolderParams) (database.Folder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.userExists(arg.UserID) {
+		return database.Folder{}, fmt.Errorf("%w: folders.user_id", errForeignKeyViolation)
+	}
+	for _, f := range m.folders {
+		if f.ID == arg.ID || (f.UserID == arg.UserID && f.Name == arg.Name) {
+			return database.Folder{}, fmt.Errorf("%w: folders.user_id, folders.name", storage.ErrUniqueViolation)
+		}
+	}
+	folder := database.Folder(arg)
+	m.folders = append(m.folders, folder)
+	return folder, nil
+}
+
+func (m *memoryStore) GetFoldersForUser(ctx context.Context, userID uuid.UUID) ([]database.Folder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var folders []database.Folder
+	for _, f := range m.folders {
+		if f.UserID == userID {
+			folders = append(folders, f)
+		}
+	}
+	sort.SliceStable(folders, func(i, j int) bool { return folders[i].Name < folders[j].Name })
+	return folders, nil
+}
+
+func (m *memoryStore) GetFolderByName(ctx context.Context, arg database.GetFolderByNameParams) (database.Folder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return findOne(m.folders, func(f database.Folder) bool { return f.UserID == arg.UserID && f.Name == arg.Name })
+}
+
+func (m *memoryStore) RenameFolder(ctx context.Context, arg database.RenameFolderParams) (database.Folder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := slices.IndexFunc(m.folders, func(f database.Folder) bool { return f.ID == arg.ID })
+	if i < 0 {
+		return database.Folder{}, sql.ErrNoRows
+	}
+	for _, f := range m.folders {
+		if f.UserID == m.folders[i].UserID && f.Name == arg.Name && f.ID != arg.ID {
+			return database.Folder{}, fmt.Errorf("%w: folders.user_id, folders.name", storage.ErrUniqueViolation)
+		}
+	}
+	m.folders[i].Name = arg.Name
+	m.folders[i].UpdatedAt = time.Now()
+	return m.folders[i], nil
+}
+
+// DeleteFolder moves the follows in the folder out of it, like ON DELETE
+// SET NULL.
+func (m *memoryStore) DeleteFolder(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.folders = deleteWhere(m.folders, func(f database.Folder) bool { return f.ID == id })
+	for i, f := range m.follows {
+		if f.FolderID.Valid && f.FolderID.UUID == id {
+			m.follows[i].FolderID = uuid.NullUUID{}
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) SetFeedFollowFolder(ctx context.Context, arg database.SetFeedFollowFolderParams) (database.FeedFollow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if arg.FolderID.Valid {
+		if _, err := findOne(m.folders, func(f database.Folder) bool { return f.ID == arg.FolderID.UUID }); err != nil {
+			return database.FeedFollow{}, fmt.Errorf("%w: feed_follows.folder_id", errForeignKeyViolation)
+		}
+	}
+	for i, f := range m.follows {
+		if f.UserID == arg.UserID && f.FeedID == arg.FeedID {
+			m.follows[i].FolderID = arg.FolderID
+			m.follows[i].UpdatedAt = time.Now()
+			return m.follows[i], nil
+		}
+	}
+	return database.FeedFollow{}, sql.ErrNoRows
+}
+
+func (m *memoryStore) userExists(id uuid.UUID) bool {
+	_, err := findOne(m.users, func(u database.User) bool { return u.ID == id })
+	return err == nil
+}
+
+func findOne[T any](rows []T, match func(T) bool) (T, error) {
+	for _, row := range rows {
+		if match(row) {
+			return row, nil
+		}
+	}
+	var zero T
+	return zero, sql.ErrNoRows
+}
+
+func deleteWhere[T any](rows []T, match func(T) bool) []T {
+	kept := rows[:0]
+	for _, row := range rows {
+		if !match(row) {
+			kept = append(kept, row)
+		}
+	}
+	return kept
+}
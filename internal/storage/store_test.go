@@ -0,0 +1,990 @@
+package storage_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/alifoo/blog-aggregator/internal/storage"
+	"github.com/alifoo/blog-aggregator/internal/storage/storagetest"
+	"github.com/google/uuid"
+	"github.com/pressly/goose/v3"
+)
+
+// testStores returns every backend that can run without a server: the
+// in-memory store and a migrated SQLite file. The memory store is checked
+// against the same expectations as SQLite so it stays a faithful stand-in.
+func testStores(t *testing.T) map[storage.Backend]storage.Store {
+	t.Helper()
+
+	sqliteStore, err := storage.Open("sqlite://" + filepath.Join(t.TempDir(), "gator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqliteStore.Close() })
+
+	migrations, err := goose.NewProvider(goose.DialectSQLite3, sqliteStore.DB(), os.DirFS("../../sql/sqlite/schema"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrations.Up(context.Background()); err != nil {
+		t.Fatalf("migrating SQLite: %v", err)
+	}
+
+	return map[storage.Backend]storage.Store{
+		storagetest.Memory: storagetest.NewMemory(),
+		storage.SQLite:     sqliteStore,
+	}
+}
+
+func forEachStore(t *testing.T, test func(t *testing.T, store storage.Store)) {
+	for backend, store := range testStores(t) {
+		t.Run(string(backend), func(t *testing.T) {
+			test(t, store)
+		})
+	}
+}
+
+func createUser(t *testing.T, store storage.Store, name string) database.User {
+	t.Helper()
+
+	now := time.Now()
+	user, err := store.CreateUser(context.Background(), database.CreateUserParams{
+		ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: name,
+	})
+	if err != nil {
+		t.Fatalf("creating user %v: %v", name, err)
+	}
+	return user
+}
+
+func createFeed(t *testing.T, store storage.Store, user database.User, url string) database.Feed {
+	t.Helper()
+
+	now := time.Now()
+	feed, err := store.CreateFeed(context.Background(), database.CreateFeedParams{
+		ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: url, Url: url, UserID: user.ID,
+	})
+	if err != nil {
+		t.Fatalf("creating feed %v: %v", url, err)
+	}
+	return feed
+}
+
+func follow(t *testing.T, store storage.Store, user database.User, feed database.Feed) {
+	t.Helper()
+
+	now := time.Now()
+	_, err := store.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+		ID: uuid.New(), CreatedAt: now, UpdatedAt: now, UserID: user.ID, FeedID: feed.ID,
+	})
+	if err != nil {
+		t.Fatalf("following %v: %v", feed.Url, err)
+	}
+}
+
+func createPost(store storage.Store, feed database.Feed, url string, published time.Time) (database.Post, error) {
+	now := time.Now()
+	return store.CreatePost(context.Background(), database.CreatePostParams{
+		ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Title: url, Url: url,
+		PublishedAt: published, FeedID: uuid.NullUUID{UUID: feed.ID, Valid: true},
+	})
+}
+
+func TestUsers(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store storage.Store) {
+		ctx := context.Background()
+		alice := createUser(t, store, "alice")
+
+		_, err := store.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), Name: "alice"})
+		if !storage.IsUniqueViolation(err) {
+			t.Fatalf("duplicate user name returned %v, want a unique violation", err)
+		}
+
+		got, err := store.GetUser(ctx, "alice")
+		if err != nil || got.ID != alice.ID {
+			t.Fatalf("GetUser returned %v, %v", got, err)
+		}
+		if _, err := store.GetUser(ctx, "bob"); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("GetUser for a missing user returned %v, want sql.ErrNoRows", err)
+		}
+
+		renamed, err := store.RenameUser(ctx, database.RenameUserParams{ID: alice.ID, Name: "alicia"})
+		if err != nil || renamed.Name != "alicia" || renamed.ID != alice.ID {
+			t.Fatalf("RenameUser returned %v, %v", renamed, err)
+		}
+		createUser(t, store, "bob")
+		if _, err := store.RenameUser(ctx, database.RenameUserParams{ID: alice.ID, Name: "bob"}); !storage.IsUniqueViolation(err) {
+			t.Fatalf("renaming to a taken name returned %v, want a unique violation", err)
+		}
+
+		hash := sql.NullString{String: "hash", Valid: true}
+		err = store.SetUserAPITokenHash(ctx, database.SetUserAPITokenHashParams{ID: alice.ID, ApiTokenHash: hash})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err = store.GetUserByAPITokenHash(ctx, hash)
+		if err != nil || got.ID != alice.ID {
+			t.Fatalf("GetUserByAPITokenHash returned %v, %v", got, err)
+		}
+	})
+}
+
+func TestFeedFollows(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store storage.Store) {
+		ctx := context.Background()
+		alice := createUser(t, store, "alice")
+		feed := createFeed(t, store, alice, "https://example.com/rss")
+
+		_, err := store.CreateFeed(ctx, database.CreateFeedParams{ID: uuid.New(), Url: feed.Url, UserID: alice.ID})
+		if !storage.IsUniqueViolation(err) {
+			t.Fatalf("duplicate feed url returned %v, want a unique violation", err)
+		}
+		_, err = store.CreateFeed(ctx, database.CreateFeedParams{ID: uuid.New(), Url: "https://other.example.com", UserID: uuid.New()})
+		if err == nil {
+			t.Fatal("created a feed for a user that does not exist")
+		}
+
+		follow(t, store, alice, feed)
+		_, err = store.CreateFeedFollow(ctx, database.CreateFeedFollowParams{ID: uuid.New(), UserID: alice.ID, FeedID: feed.ID})
+		if !storage.IsUniqueViolation(err) {
+			t.Fatalf("duplicate follow returned %v, want a unique violation", err)
+		}
+
+		follows, err := store.GetFeedFollowsForUser(ctx, alice.ID)
+		if err != nil || len(follows) != 1 || follows[0].FeedName != feed.Name || follows[0].UserName != "alice" {
+			t.Fatalf("GetFeedFollowsForUser returned %+v, %v", follows, err)
+		}
+
+		err = store.DeleteFeedFollow(ctx, database.DeleteFeedFollowParams{UserID: alice.ID, Url: feed.Url})
+		if err != nil {
+			t.Fatal(err)
+		}
+		follows, err = store.GetFeedFollowsForUser(ctx, alice.ID)
+		if err != nil || len(follows) != 0 {
+			t.Fatalf("follows after unfollowing: %+v, %v", follows, err)
+		}
+	})
+}
+
+func TestNextFeedToFetch(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store storage.Store) {
+		ctx := context.Background()
+		if _, err := store.GetNextFeedToFetch(ctx); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("GetNextFeedToFetch without feeds returned %v, want sql.ErrNoRows", err)
+		}
+
+		alice := createUser(t, store, "alice")
+		first := createFeed(t, store, alice, "https://one.example.com")
+		second := createFeed(t, store, alice, "https://two.example.com")
+
+		// Feeds that were never fetched come first, then the least recent.
+		next, err := store.GetNextFeedToFetch(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.MarkFeedFetched(ctx, next.ID); err != nil {
+			t.Fatal(err)
+		}
+		other, err := store.GetNextFeedToFetch(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if other.ID == next.ID || (other.ID != first.ID && other.ID != second.ID) {
+			t.Fatalf("fetched %v twice before %v", next.Url, other.Url)
+		}
+		if err := store.MarkFeedFetched(ctx, other.ID); err != nil {
+			t.Fatal(err)
+		}
+		again, err := store.GetNextFeedToFetch(ctx)
+		if err != nil || again.ID != next.ID {
+			t.Fatalf("GetNextFeedToFetch returned %v, %v, want the least recently fetched feed", again.Url, err)
+		}
+	})
+}
+
+func TestPostsAndReaderItems(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store storage.Store) {
+		ctx := context.Background()
+		alice := createUser(t, store, "alice")
+		bob := createUser(t, store, "bob")
+		feed := createFeed(t, store, alice, "https://example.com/rss")
+		follow(t, store, alice, feed)
+
+		day := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		older, err := createPost(store, feed, "https://example.com/1", day)
+		if err != nil {
+			t.Fatal(err)
+		}
+		newer, err := createPost(store, feed, "https://example.com/2", day.Add(24*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if newer.Seq <= older.Seq {
+			t.Errorf("seq %d of the second post is not after %d", newer.Seq, older.Seq)
+		}
+		if _, err := createPost(store, feed, older.Url, day); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("duplicate post returned %v, want sql.ErrNoRows", err)
+		}
+
+		items, err := store.GetReaderItems(ctx, database.GetReaderItemsParams{UserID: alice.ID, MaxItems: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != 2 || items[0].ID != newer.ID || items[1].ID != older.ID {
+			t.Fatalf("reader items are not newest first: %+v", items)
+		}
+		if !items[0].PublishedAt.Equal(newer.PublishedAt) {
+			t.Errorf("published_at changed from %v to %v", newer.PublishedAt, items[0].PublishedAt)
+		}
+
+		items, err = store.GetReaderItems(ctx, database.GetReaderItemsParams{UserID: alice.ID, MaxItems: 10, OldestFirst: true})
+		if err != nil || len(items) != 2 || items[0].ID != older.ID {
+			t.Fatalf("reader items are not oldest first: %+v, %v", items, err)
+		}
+
+		items, err = store.GetReaderItems(ctx, database.GetReaderItemsParams{UserID: bob.ID, MaxItems: 10})
+		if err != nil || len(items) != 0 {
+			t.Fatalf("bob sees posts of feeds he does not follow: %+v, %v", items, err)
+		}
+
+		err = store.SetPostRead(ctx, database.SetPostReadParams{UserID: alice.ID, PostID: older.ID, Read: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		items, err = store.GetReaderItems(ctx, database.GetReaderItemsParams{UserID: alice.ID, MaxItems: 10, ExcludeRead: true})
+		if err != nil || len(items) != 1 || items[0].ID != newer.ID {
+			t.Fatalf("unread items: %+v, %v", items, err)
+		}
+
+		counts, err := store.GetUnreadCountsForUser(ctx, alice.ID)
+		if err != nil || len(counts) != 1 || counts[0].Unread != 1 {
+			t.Fatalf("unread counts: %+v, %v", counts, err)
+		}
+
+		bySeq, err := store.GetReaderItemsBySeq(ctx, database.GetReaderItemsBySeqParams{UserID: alice.ID, Seqs: []int64{older.Seq}})
+		if err != nil || len(bySeq) != 1 || bySeq[0].ID != older.ID || !bySeq[0].Read {
+			t.Fatalf("GetReaderItemsBySeq returned %+v, %v", bySeq, err)
+		}
+	})
+}
+
+// TestSeqNotReused checks short ids of deleted posts are never handed to
+// new ones, since users and Google Reader clients may still hold them.
+func TestSeqNotReused(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store storage.Store) {
+		ctx := context.Background()
+		alice := createUser(t, store, "alice")
+		feed := createFeed(t, store, alice, "https://example.com/rss")
+
+		if _, err := createPost(store, feed, "https://example.com/1", time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		newest, err := createPost(store, feed, "https://example.com/2", time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.DeleteAllPosts(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		post, err := createPost(store, feed, "https://example.com/3", time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if post.Seq <= newest.Seq {
+			t.Fatalf("new post got seq %d, reusing the seq of a deleted post up to %d", post.Seq, newest.Seq)
+		}
+		if _, err := store.GetPostBySeq(ctx, newest.Seq); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("GetPostBySeq for a deleted post returned %v, want sql.ErrNoRows", err)
+		}
+
+		// Posts deleted with their feed are not reused either.
+		if err := store.DeleteFeed(ctx, feed.ID); err != nil {
+			t.Fatal(err)
+		}
+		feed = createFeed(t, store, alice, "https://example.com/rss")
+		again, err := createPost(store, feed, "https://example.com/3", time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again.Seq <= post.Seq {
+			t.Fatalf("post after deleting the feed got seq %d, want more than %d", again.Seq, post.Seq)
+		}
+	})
+}
+
+func TestDeletesCascade(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store storage.Store) {
+		ctx := context.Background()
+		alice := createUser(t, store, "alice")
+		bob := createUser(t, store, "bob")
+		aliceFeed := createFeed(t, store, alice, "https://alice.example.com")
+		bobFeed := createFeed(t, store, bob, "https://bob.example.com")
+		follow(t, store, alice, aliceFeed)
+		follow(t, store, bob, aliceFeed)
+		follow(t, store, bob, bobFeed)
+		post, err := createPost(store, aliceFeed, "https://alice.example.com/1", time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := createPost(store, bobFeed, "https://bob.example.com/1", time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		err = store.SetPostStarred(ctx, database.SetPostStarredParams{UserID: bob.ID, PostID: post.ID, Starred: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		counts, err := store.GetResetCounts(ctx, uuid.NullUUID{UUID: alice.ID, Valid: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := database.GetResetCountsRow{Users: 1, Feeds: 1, FeedFollows: 2, Posts: 1}
+		if counts != want {
+			t.Fatalf("counts for alice are %+v, want %+v", counts, want)
+		}
+
+		// Deleting alice takes her feed with it, including its posts and
+		// bob's follow and star.
+		if err := store.DeleteUser(ctx, alice.ID); err != nil {
+			t.Fatal(err)
+		}
+		counts, err = store.GetResetCounts(ctx, uuid.NullUUID{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = database.GetResetCountsRow{Users: 1, Feeds: 1, FeedFollows: 1, Posts: 1}
+		if counts != want {
+			t.Fatalf("counts after deleting alice are %+v, want %+v", counts, want)
+		}
+
+		if err := store.DeleteAllPosts(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.DeleteAllFeeds(ctx); err != nil {
+			t.Fatal(err)
+		}
+		counts, err = store.GetResetCounts(ctx, uuid.NullUUID{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want = (database.GetResetCountsRow{Users: 1}); counts != want {
+			t.Fatalf("counts after deleting feeds are %+v, want %+v", counts, want)
+		}
+
+		if err := store.DeleteAllUsers(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if users, err := store.GetUsers(ctx); err != nil || len(users) != 0 {
+			t.Fatalf("users left after DeleteAllUsers: %+v, %v", users, err)
+		}
+	})
+}
+
+func TestKeptPosts(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store storage.Store) {
+		ctx := context.Background()
+		alice := createUser(t, store, "alice")
+		feed := createFeed(t, store, alice, "https://alice.example.com")
+		follow(t, store, alice, feed)
+		post, err := createPost(store, feed, "https://alice.example.com/1", time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.SetPostStarred(ctx, database.SetPostStarredParams{UserID: alice.ID, PostID: post.ID, Starred: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Detached posts survive their feed.
+		if err := store.DetachFeedPosts(ctx, uuid.NullUUID{UUID: feed.ID, Valid: true}); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.DeleteFeed(ctx, feed.ID); err != nil {
+			t.Fatal(err)
+		}
+		kept, err := store.GetPostByURL(ctx, post.Url)
+		if err != nil || kept.FeedID.Valid {
+			t.Fatalf("kept post is %+v, %v, want it without a feed", kept, err)
+		}
+		counts, err := store.GetResetCounts(ctx, uuid.NullUUID{})
+		if want := (database.GetResetCountsRow{Users: 1, Posts: 1}); err != nil || counts != want {
+			t.Fatalf("counts with a kept post are %+v, %v, want %+v", counts, err, want)
+		}
+
+		// Adding the feed again takes the post back, star included, while
+		// posts of other feeds stay where they are.
+		feed = createFeed(t, store, alice, "https://alice.example.com")
+		follow(t, store, alice, feed)
+		back, err := createPost(store, feed, post.Url, time.Now())
+		if err != nil || back.ID != post.ID || back.FeedID.UUID != feed.ID {
+			t.Fatalf("post after adding the feed again is %+v, %v", back, err)
+		}
+		items, err := store.GetReaderItems(ctx, database.GetReaderItemsParams{UserID: alice.ID, OnlyStarred: true, MaxItems: 10})
+		if err != nil || len(items) != 1 || items[0].ID != post.ID {
+			t.Fatalf("starred items are %+v, %v", items, err)
+		}
+		other := createFeed(t, store, alice, "https://other.example.com")
+		if _, err := createPost(store, other, post.Url, time.Now()); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("creating a post of another feed with the same url returned %v, want sql.ErrNoRows", err)
+		}
+
+		// Without detaching, deleting the feed deletes its posts.
+		if err := store.DeleteFeed(ctx, feed.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.GetPostByURL(ctx, post.Url); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("post of a deleted feed returned %v, want sql.ErrNoRows", err)
+		}
+	})
+}
+
+// TestResetDeletesKeptPosts checks deleting every feed or user also deletes
+// the posts kept without a feed, which GetResetCounts counts for them.
+func TestResetDeletesKeptPosts(t *testing.T) {
+	for name, reset := range map[string]func(storage.Store, context.Context) error{
+		"DeleteAllFeeds": storage.Store.DeleteAllFeeds,
+		"DeleteAllUsers": storage.Store.DeleteAllUsers,
+	} {
+		t.Run(name, func(t *testing.T) {
+			forEachStore(t, func(t *testing.T, store storage.Store) {
+				ctx := context.Background()
+				alice := createUser(t, store, "alice")
+				feed := createFeed(t, store, alice, "https://alice.example.com")
+				other := createFeed(t, store, alice, "https://other.example.com")
+				kept, err := createPost(store, feed, "https://alice.example.com/1", time.Now())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := createPost(store, other, "https://other.example.com/1", time.Now()); err != nil {
+					t.Fatal(err)
+				}
+				if err := store.DetachFeedPosts(ctx, uuid.NullUUID{UUID: feed.ID, Valid: true}); err != nil {
+					t.Fatal(err)
+				}
+				if err := store.DeleteFeed(ctx, feed.ID); err != nil {
+					t.Fatal(err)
+				}
+				counts, err := store.GetResetCounts(ctx, uuid.NullUUID{})
+				if err != nil || counts.Posts != 2 {
+					t.Fatalf("counts before the reset are %+v, %v, want 2 posts", counts, err)
+				}
+
+				if err := reset(store, ctx); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := store.GetPostByURL(ctx, kept.Url); !errors.Is(err, sql.ErrNoRows) {
+					t.Fatalf("kept post after %v returned %v, want sql.ErrNoRows", name, err)
+				}
+				counts, err = store.GetResetCounts(ctx, uuid.NullUUID{})
+				if err != nil || counts.Posts != 0 {
+					t.Fatalf("counts after %v are %+v, %v, want no posts", name, counts, err)
+				}
+			})
+		})
+	}
+}
+
+func TestFeedUpdates(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store storage.Store) {
+		ctx := context.Background()
+		alice := createUser(t, store, "alice")
+		feed := createFeed(t, store, alice, "https://one.example.com")
+		other := createFeed(t, store, alice, "https://two.example.com")
+		follow(t, store, alice, feed)
+		if _, err := createPost(store, feed, "https://one.example.com/1", time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		renamed, err := store.RenameFeed(ctx, database.RenameFeedParams{ID: feed.ID, Name: "One"})
+		if err != nil || renamed.Name != "One" || renamed.Url != feed.Url {
+			t.Fatalf("RenameFeed returned %+v, %v", renamed, err)
+		}
+		_, err = store.SetFeedURL(ctx, database.SetFeedURLParams{ID: feed.ID, Url: other.Url})
+		if !storage.IsUniqueViolation(err) {
+			t.Fatalf("moving a feed to a taken url returned %v, want a unique violation", err)
+		}
+		moved, err := store.SetFeedURL(ctx, database.SetFeedURLParams{ID: feed.ID, Url: "https://new.example.com"})
+		if err != nil || moved.Url != "https://new.example.com" || moved.Name != "One" {
+			t.Fatalf("SetFeedURL returned %+v, %v", moved, err)
+		}
+
+		fetchErr := sql.NullString{String: "404 Not Found", Valid: true}
+		err = store.SetFeedFetchError(ctx, database.SetFeedFetchErrorParams{ID: feed.ID, LastFetchError: fetchErr})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := store.GetFeedByURL(ctx, moved.Url)
+		if err != nil || got.LastFetchError != fetchErr {
+			t.Fatalf("feed after a failed fetch: %+v, %v", got, err)
+		}
+
+		stats, err := store.GetFeedStats(ctx, feed.ID)
+		if want := (database.GetFeedStatsRow{Followers: 1, Posts: 1}); err != nil || stats != want {
+			t.Fatalf("GetFeedStats returned %+v, %v, want %+v", stats, err, want)
+		}
+
+		if err := store.DeleteFeed(ctx, feed.ID); err != nil {
+			t.Fatal(err)
+		}
+		counts, err := store.GetResetCounts(ctx, uuid.NullUUID{})
+		if want := (database.GetResetCountsRow{Users: 1, Feeds: 1}); err != nil || counts != want {
+			t.Fatalf("counts after DeleteFeed are %+v, %v, want %+v", counts, err, want)
+		}
+	})
+}
+
+func TestMergeFeeds(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store storage.Store) {
+		ctx := context.Background()
+		alice := createUser(t, store, "alice")
+		bob := createUser(t, store, "bob")
+		from := createFeed(t, store, alice, "https://old.example.com")
+		to := createFeed(t, store, alice, "https://new.example.com")
+		follow(t, store, alice, from)
+		follow(t, store, bob, from)
+		follow(t, store, bob, to)
+		if _, err := createPost(store, from, "https://old.example.com/1", time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		err := store.MoveFeedFollows(ctx, database.MoveFeedFollowsParams{FromFeedID: from.ID, ToFeedID: to.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.MovePosts(ctx, database.MovePostsParams{FromFeedID: uuid.NullUUID{UUID: from.ID, Valid: true}, ToFeedID: uuid.NullUUID{UUID: to.ID, Valid: true}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.DeleteFeed(ctx, from.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		stats, err := store.GetFeedStats(ctx, to.ID)
+		if want := (database.GetFeedStatsRow{Followers: 2, Posts: 1}); err != nil || stats != want {
+			t.Fatalf("stats after merging are %+v, %v, want %+v", stats, err, want)
+		}
+	})
+}
+
+func TestDeadFeedsAreNotFetched(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store storage.Store) {
+		ctx := context.Background()
+		alice := createUser(t, store, "alice")
+		feed := createFeed(t, store, alice, "https://gone.example.com")
+
+		if err := store.MarkFeedDead(ctx, feed.ID); err != nil {
+			t.Fatal(err)
+		}
+		got, err := store.GetFeedByURL(ctx, feed.Url)
+		if err != nil || !got.DeadAt.Valid {
+			t.Fatalf("feed after MarkFeedDead: %+v, %v", got, err)
+		}
+		if _, err := store.GetNextFeedToFetch(ctx); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("GetNextFeedToFetch returned a dead feed: %v", err)
+		}
+
+		moved, err := store.SetFeedURL(ctx, database.SetFeedURLParams{ID: feed.ID, Url: "https://new.example.com"})
+		if err != nil || moved.DeadAt.Valid {
+			t.Fatalf("feed is still dead after SetFeedURL: %+v, %v", moved, err)
+		}
+	})
+}
+
+func TestSearchPosts(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store storage.Store) {
+		ctx := context.Background()
+		alice := createUser(t, store, "alice")
+		bob := createUser(t, store, "bob")
+		feed := createFeed(t, store, alice, "https://example.com/rss")
+		follow(t, store, alice, feed)
+
+		now := time.Now()
+		posts := []database.CreatePostParams{
+			{Title: "Gophers at work", Url: "https://example.com/1", Summary: sql.NullString{String: "Nothing else.", Valid: true}},
+			{Title: "Weekly notes", Url: "https://example.com/2", Summary: sql.NullString{String: "More about GOPHERS.", Valid: true}},
+			// Posts stored before summaries existed are searched by description.
+			{Title: "Old post", Url: "https://example.com/3", Description: sql.NullString{String: "<p>gophers</p>", Valid: true}},
+			{Title: "Unrelated", Url: "https://example.com/4", Summary: sql.NullString{String: "Cats.", Valid: true}},
+		}
+		for i, p := range posts {
+			p.ID, p.CreatedAt, p.UpdatedAt, p.FeedID = uuid.New(), now, now, uuid.NullUUID{UUID: feed.ID, Valid: true}
+			p.PublishedAt = now.Add(time.Duration(-i) * time.Hour)
+			if _, err := store.CreatePost(ctx, p); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		found, err := store.SearchPosts(ctx, database.SearchPostsParams{UserID: alice.ID, Query: "Gophers", MaxItems: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var titles []string
+		for _, p := range found {
+			titles = append(titles, p.Title)
+		}
+		if len(titles) != 3 || titles[0] != "Gophers at work" || titles[1] != "Weekly notes" || titles[2] != "Old post" {
+			t.Errorf("search found %q", titles)
+		}
+		if found[1].Summary.String != "More about GOPHERS." {
+			t.Errorf("summary is %q", found[1].Summary.String)
+		}
+
+		found, err = store.SearchPosts(ctx, database.SearchPostsParams{UserID: alice.ID, Query: "gophers", MaxItems: 1})
+		if err != nil || len(found) != 1 {
+			t.Errorf("limited search returned %d posts, %v", len(found), err)
+		}
+		found, err = store.SearchPosts(ctx, database.SearchPostsParams{UserID: bob.ID, Query: "gophers", MaxItems: 10})
+		if err != nil || len(found) != 0 {
+			t.Errorf("bob finds posts of feeds he does not follow: %+v, %v", found, err)
+		}
+	})
+}
+
+func TestFullContent(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store storage.Store) {
+		ctx := context.Background()
+		alice := createUser(t, store, "alice")
+		feed := createFeed(t, store, alice, "https://example.com/rss")
+		follow(t, store, alice, feed)
+		if feed.FetchFullContent {
+			t.Error("new feeds fetch full content")
+		}
+
+		feed, err := store.SetFeedFullContent(ctx, database.SetFeedFullContentParams{ID: feed.ID, FetchFullContent: true})
+		if err != nil || !feed.FetchFullContent {
+			t.Fatalf("SetFeedFullContent returned %+v, %v", feed, err)
+		}
+
+		post, err := createPost(store, feed, "https://example.com/1", time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := sql.NullString{String: "<p>The whole article.</p>", Valid: true}
+		if err := store.SetPostContent(ctx, database.SetPostContentParams{ID: post.ID, Content: content}); err != nil {
+			t.Fatal(err)
+		}
+		items, err := store.GetReaderItems(ctx, database.GetReaderItemsParams{UserID: alice.ID, MaxItems: 10})
+		if err != nil || len(items) != 1 || items[0].Content != content {
+			t.Fatalf("reader items: %+v, %v", items, err)
+		}
+	})
+}
+
+func TestRules(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store storage.Store) {
+		ctx := context.Background()
+		alice := createUser(t, store, "alice")
+		bob := createUser(t, store, "bob")
+		feed := createFeed(t, store, alice, "https://example.com/rss")
+		other := createFeed(t, store, alice, "https://other.example.com/rss")
+		follow(t, store, alice, feed)
+		follow(t, store, alice, other)
+
+		now := time.Now().UTC().Truncate(time.Second)
+		createRule := func(user database.User, feedID uuid.NullUUID, pattern string, age time.Duration) database.Rule {
+			t.Helper()
+			r, err := store.CreateRule(ctx, database.CreateRuleParams{
+				ID:        uuid.New(),
+				CreatedAt: now.Add(-age),
+				UpdatedAt: now.Add(-age),
+				UserID:    user.ID,
+				FeedID:    feedID,
+				Field:     "title",
+				Pattern:   pattern,
+				Action:    "tag",
+				Tag:       sql.NullString{String: "news", Valid: true},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return r
+		}
+		global := createRule(alice, uuid.NullUUID{}, "go", 2*time.Minute)
+		forFeed := createRule(alice, uuid.NullUUID{UUID: feed.ID, Valid: true}, "rust", time.Minute)
+		forOther := createRule(alice, uuid.NullUUID{UUID: other.ID, Valid: true}, "zig", 0)
+		// bob does not follow the feed, so his rules do not apply to it.
+		createRule(bob, uuid.NullUUID{}, "java", 0)
+
+		rules, err := store.GetRulesForUser(ctx, alice.ID)
+		if err != nil || len(rules) != 3 {
+			t.Fatalf("rules of alice: %+v, %v", rules, err)
+		}
+		if rules[0].ID != global.ID || rules[0].FeedUrl.Valid || rules[0].Tag.String != "news" {
+			t.Errorf("first rule is %+v", rules[0])
+		}
+		if rules[1].ID != forFeed.ID || rules[1].FeedUrl.String != feed.Url {
+			t.Errorf("second rule is %+v", rules[1])
+		}
+
+		feedRules, err := store.GetRulesForFeed(ctx, feed.ID)
+		if err != nil || len(feedRules) != 2 || feedRules[0].ID != global.ID || feedRules[1].ID != forFeed.ID {
+			t.Fatalf("rules for feed: %+v, %v", feedRules, err)
+		}
+
+		err = store.MoveFeedRules(ctx, database.MoveFeedRulesParams{FromFeedID: other.ID, ToFeedID: feed.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.DeleteRule(ctx, forFeed.ID); err != nil {
+			t.Fatal(err)
+		}
+		feedRules, err = store.GetRulesForFeed(ctx, feed.ID)
+		if err != nil || len(feedRules) != 2 || feedRules[1].ID != forOther.ID {
+			t.Fatalf("rules for feed after moving and deleting: %+v, %v", feedRules, err)
+		}
+
+		// Deleting a feed deletes its rules.
+		if err := store.DeleteFeed(ctx, feed.ID); err != nil {
+			t.Fatal(err)
+		}
+		rules, err = store.GetRulesForUser(ctx, alice.ID)
+		if err != nil || len(rules) != 1 || rules[0].ID != global.ID {
+			t.Fatalf("rules after deleting the feed: %+v, %v", rules, err)
+		}
+	})
+}
+
+func TestHiddenPosts(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store storage.Store) {
+		ctx := context.Background()
+		alice := createUser(t, store, "alice")
+		bob := createUser(t, store, "bob")
+		feed := createFeed(t, store, alice, "https://example.com/rss")
+		follow(t, store, alice, feed)
+		follow(t, store, bob, feed)
+		post, err := createPost(store, feed, "https://example.com/1", time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = store.SetPostHidden(ctx, database.SetPostHiddenParams{UserID: alice.ID, PostID: post.ID, Hidden: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.AddPostTag(ctx, database.AddPostTagParams{UserID: alice.ID, PostID: post.ID, Tag: "news"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Adding a tag twice is not an error.
+		err = store.AddPostTag(ctx, database.AddPostTagParams{UserID: alice.ID, PostID: post.ID, Tag: "news"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		items, err := store.GetReaderItems(ctx, database.GetReaderItemsParams{UserID: alice.ID, MaxItems: 10})
+		if err != nil || len(items) != 0 {
+			t.Errorf("reader items of alice: %+v, %v", items, err)
+		}
+		counts, err := store.GetUnreadCountsForUser(ctx, alice.ID)
+		if err != nil || len(counts) != 0 {
+			t.Errorf("unread counts of alice: %+v, %v", counts, err)
+		}
+		found, err := store.SearchPosts(ctx, database.SearchPostsParams{UserID: alice.ID, Query: "example", MaxItems: 10})
+		if err != nil || len(found) != 0 {
+			t.Errorf("search of alice: %+v, %v", found, err)
+		}
+
+		items, err = store.GetReaderItems(ctx, database.GetReaderItemsParams{UserID: bob.ID, MaxItems: 10})
+		if err != nil || len(items) != 1 {
+			t.Errorf("reader items of bob: %+v, %v", items, err)
+		}
+	})
+}
+
+func TestFolders(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store storage.Store) {
+		ctx := context.Background()
+		alice := createUser(t, store, "alice")
+		bob := createUser(t, store, "bob")
+		feed := createFeed(t, store, alice, "https://example.com/rss")
+		other := createFeed(t, store, alice, "https://other.example.com/rss")
+		follow(t, store, alice, feed)
+		follow(t, store, alice, other)
+		follow(t, store, bob, feed)
+		if _, err := createPost(store, feed, "https://example.com/1", time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := createPost(store, other, "https://other.example.com/1", time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		createFolder := func(user database.User, name string) (database.Folder, error) {
+			now := time.Now()
+			return store.CreateFolder(ctx, database.CreateFolderParams{
+				ID: uuid.New(), CreatedAt: now, UpdatedAt: now, UserID: user.ID, Name: name,
+			})
+		}
+		tech, err := createFolder(alice, "tech")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := createFolder(alice, "news"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := createFolder(alice, "tech"); !storage.IsUniqueViolation(err) {
+			t.Errorf("duplicate folder returned %v, want a unique violation", err)
+		}
+		// Folder names are per user.
+		if _, err := createFolder(bob, "tech"); err != nil {
+			t.Fatal(err)
+		}
+
+		folders, err := store.GetFoldersForUser(ctx, alice.ID)
+		if err != nil || len(folders) != 2 || folders[0].Name != "news" || folders[1].ID != tech.ID {
+			t.Fatalf("folders of alice: %+v, %v", folders, err)
+		}
+		found, err := store.GetFolderByName(ctx, database.GetFolderByNameParams{UserID: bob.ID, Name: "tech"})
+		if err != nil || found.UserID != bob.ID {
+			t.Fatalf("folder of bob: %+v, %v", found, err)
+		}
+		if _, err := store.RenameFolder(ctx, database.RenameFolderParams{ID: tech.ID, Name: "news"}); !storage.IsUniqueViolation(err) {
+			t.Errorf("renaming to an existing folder returned %v, want a unique violation", err)
+		}
+		tech, err = store.RenameFolder(ctx, database.RenameFolderParams{ID: tech.ID, Name: "blogs"})
+		if err != nil || tech.Name != "blogs" {
+			t.Fatalf("RenameFolder returned %+v, %v", tech, err)
+		}
+
+		inTech := uuid.NullUUID{UUID: tech.ID, Valid: true}
+		moved, err := store.SetFeedFollowFolder(ctx, database.SetFeedFollowFolderParams{FolderID: inTech, UserID: alice.ID, FeedID: feed.ID})
+		if err != nil || moved.FolderID != inTech {
+			t.Fatalf("SetFeedFollowFolder returned %+v, %v", moved, err)
+		}
+		_, err = store.SetFeedFollowFolder(ctx, database.SetFeedFollowFolderParams{FolderID: inTech, UserID: bob.ID, FeedID: other.ID})
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("moving a feed that is not followed returned %v", err)
+		}
+
+		follows, err := store.GetFeedFollowsForUser(ctx, alice.ID)
+		if err != nil || len(follows) != 2 {
+			t.Fatalf("follows of alice: %+v, %v", follows, err)
+		}
+		for _, f := range follows {
+			inFolder := f.FeedID == feed.ID
+			if f.FolderID.Valid != inFolder || f.FolderName.Valid != inFolder || (inFolder && f.FolderName.String != "blogs") {
+				t.Errorf("follow of %v has folder %v %v", f.FeedUrl, f.FolderID, f.FolderName)
+			}
+		}
+
+		items, err := store.GetReaderItems(ctx, database.GetReaderItemsParams{UserID: alice.ID, FolderID: inTech, MaxItems: 10})
+		if err != nil || len(items) != 1 || items[0].FeedID.UUID != feed.ID {
+			t.Fatalf("reader items in folder: %+v, %v", items, err)
+		}
+		// The folder of alice does not filter the posts of bob.
+		items, err = store.GetReaderItems(ctx, database.GetReaderItemsParams{UserID: bob.ID, FolderID: inTech, MaxItems: 10})
+		if err != nil || len(items) != 0 {
+			t.Fatalf("reader items of bob in the folder of alice: %+v, %v", items, err)
+		}
+
+		if err := store.DeleteFolder(ctx, tech.ID); err != nil {
+			t.Fatal(err)
+		}
+		follows, err = store.GetFeedFollowsForUser(ctx, alice.ID)
+		if err != nil || len(follows) != 2 || follows[0].FolderID.Valid || follows[1].FolderID.Valid {
+			t.Fatalf("follows after deleting the folder: %+v, %v", follows, err)
+		}
+
+		if err := store.DeleteUser(ctx, bob.ID); err != nil {
+			t.Fatal(err)
+		}
+		if folders, err := store.GetFoldersForUser(ctx, bob.ID); err != nil || len(folders) != 0 {
+			t.Errorf("folders of a deleted user: %+v, %v", folders, err)
+		}
+	})
+}
+
+func TestPostTags(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store storage.Store) {
+		ctx := context.Background()
+		alice := createUser(t, store, "alice")
+		bob := createUser(t, store, "bob")
+		feed := createFeed(t, store, alice, "https://example.com/rss")
+		follow(t, store, alice, feed)
+		follow(t, store, bob, feed)
+		first, err := createPost(store, feed, "https://example.com/1", time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := createPost(store, feed, "https://example.com/2", time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := store.GetPost(ctx, first.ID)
+		if err != nil || found.Url != first.Url {
+			t.Fatalf("GetPost returned %+v, %v", found, err)
+		}
+		found, err = store.GetPostByURL(ctx, second.Url)
+		if err != nil || found.ID != second.ID {
+			t.Fatalf("GetPostByURL returned %+v, %v", found, err)
+		}
+		if _, err := store.GetPostByURL(ctx, "https://example.com/3"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("GetPostByURL of an unknown post returned %v", err)
+		}
+		found, err = store.GetPostBySeq(ctx, second.Seq)
+		if err != nil || found.ID != second.ID || second.Seq == first.Seq {
+			t.Fatalf("GetPostBySeq returned %+v, %v", found, err)
+		}
+
+		for _, tag := range []database.AddPostTagParams{
+			{UserID: alice.ID, PostID: first.ID, Tag: "to-discuss"},
+			{UserID: alice.ID, PostID: first.ID, Tag: "go"},
+			{UserID: alice.ID, PostID: second.ID, Tag: "to-discuss"},
+			{UserID: bob.ID, PostID: second.ID, Tag: "later"},
+		} {
+			if err := store.AddPostTag(ctx, tag); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		tags, err := store.GetPostTags(ctx, database.GetPostTagsParams{UserID: alice.ID, PostID: first.ID})
+		if err != nil || len(tags) != 2 || tags[0] != "go" || tags[1] != "to-discuss" {
+			t.Errorf("tags of the first post: %v, %v", tags, err)
+		}
+		cloud, err := store.GetTagsForUser(ctx, alice.ID)
+		want := []database.GetTagsForUserRow{{Tag: "go", Posts: 1}, {Tag: "to-discuss", Posts: 2}}
+		if err != nil || len(cloud) != 2 || cloud[0] != want[0] || cloud[1] != want[1] {
+			t.Errorf("tags of alice: %+v, %v", cloud, err)
+		}
+
+		tagged := func(user database.User, tag string) []database.GetReaderItemsRow {
+			t.Helper()
+			items, err := store.GetReaderItems(ctx, database.GetReaderItemsParams{
+				UserID: user.ID, Tag: sql.NullString{String: tag, Valid: true}, MaxItems: 10,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return items
+		}
+		if items := tagged(alice, "go"); len(items) != 1 || items[0].ID != first.ID {
+			t.Errorf("posts of alice tagged go: %+v", items)
+		}
+		if items := tagged(alice, "to-discuss"); len(items) != 2 {
+			t.Errorf("posts of alice tagged to-discuss: %+v", items)
+		}
+		// Tags are per user.
+		if items := tagged(bob, "to-discuss"); len(items) != 0 {
+			t.Errorf("posts of bob tagged to-discuss by alice: %+v", items)
+		}
+
+		removed, err := store.RemovePostTag(ctx, database.RemovePostTagParams{UserID: alice.ID, PostID: first.ID, Tag: "go"})
+		if err != nil || removed.Tag != "go" {
+			t.Fatalf("RemovePostTag returned %+v, %v", removed, err)
+		}
+		_, err = store.RemovePostTag(ctx, database.RemovePostTagParams{UserID: alice.ID, PostID: first.ID, Tag: "go"})
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("removing a missing tag returned %v", err)
+		}
+		if items := tagged(alice, "go"); len(items) != 0 {
+			t.Errorf("posts of alice still tagged go: %+v", items)
+		}
+	})
+}
@@ -5,22 +5,31 @@ import (
 	"database/sql"
 	"encoding/xml"
 	"errors"
+	"flag"
 	"fmt"
 	"html"
-	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/alifoo/blog-aggregator/internal/config"
 	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/alifoo/blog-aggregator/internal/htmltext"
+	"github.com/alifoo/blog-aggregator/internal/storage"
 	"github.com/google/uuid"
-	"github.com/lib/pq"
-	_ "github.com/lib/pq"
+	"github.com/pressly/goose/v3"
 )
 
 type RSSFeed struct {
+	// XMLName and Version are only set when marshalling, so feeds with other
+	// root elements keep parsing as before.
+	XMLName xml.Name
+	Version string `xml:"version,attr,omitempty"`
 	Channel struct {
 		Title       string    `xml:"title"`
 		Link        string    `xml:"link"`
@@ -34,49 +43,41 @@ type RSSItem struct {
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
 	PubDate     string `xml:"pubDate"`
+	// Author is an email address in RSS 2.0, so the Dublin Core creator,
+	// a name, is preferred when a feed has both.
+	Author   string   `xml:"author,omitempty"`
+	Creator  string   `xml:"http://purl.org/dc/elements/1.1/ creator,omitempty"`
+	Category []string `xml:"category,omitempty"`
 }
 
-type state struct {
-	db *database.Queries
-	configPointer *config.Config
-}
-
-type command struct {
-	name string
-	arguments []string
-}
-
-type commands struct {
-	handlers map[string]func(*state, command) error
-}
-
-func (c *commands) register(name string, f func(*state, command) error) {
-	c.handlers[name] = f
-}
-
-func (c *commands) run(s *state, cmd command) error {
-	handler, exists := c.handlers[cmd.name]
-	if !exists {
-		return fmt.Errorf("command %s not found", cmd.name)
+// author returns the name of the author of the item, if the feed gives it.
+func (item RSSItem) author() string {
+	if creator := strings.TrimSpace(item.Creator); creator != "" {
+		return creator
 	}
-	return handler(s, cmd)
+	return strings.TrimSpace(item.Author)
 }
 
+type state struct {
+	db storage.Store
+	configPointer *config.Config
+	output outputFormat
+	migrations *goose.Provider
+	// schemaChecked is set once checkSchema found every migration applied.
+	schemaChecked bool
+	// fetcher is created on first use, see feedFetcher.
+	fetcher *fetcher
+}
 
 func handlerLogin(s *state, cmd command) error {
-	if cmd.arguments == nil {
-		return errors.New("no arguments passed to the handler")
-	}
-
 	username := cmd.arguments[0]
 	_, err := s.db.GetUser(context.Background(), username)
-    if err != nil {
-        if err == sql.ErrNoRows {
-            fmt.Printf("User '%s' does not exist\n", username)
-            os.Exit(1)
-        }
-        return fmt.Errorf("database error: %w", err)
-    }
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return notFoundError("user '%s' does not exist", username)
+		}
+		return databaseError("error getting user: %w", err)
+	}
 
 	err = s.configPointer.SetUser(username)
 	if err != nil {
@@ -89,10 +90,6 @@ func handlerLogin(s *state, cmd command) error {
 }
 
 func handlerRegister(s *state, cmd command) error {
-	if cmd.arguments == nil {
-		return errors.New("no arguments passed to the handler")
-	}
-
 	name := cmd.arguments[0]
 
 	params := database.CreateUserParams{
@@ -104,18 +101,15 @@ func handlerRegister(s *state, cmd command) error {
 
 	user, err := s.db.CreateUser(context.Background(), params)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
-			fmt.Println("user already exists: %w", err)
-			os.Exit(1)
+		if storage.IsUniqueViolation(err) {
+			return conflictError("user '%s' already exists", name)
 		}
-		fmt.Println(err)
-		os.Exit(1)
+		return databaseError("error creating user: %w", err)
 	}
 
 	err = s.configPointer.SetUser(name)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return fmt.Errorf("error saving current user to config: %w", err)
 	}
 
 	fmt.Println("The user was successfully created. User data:")
@@ -124,36 +118,28 @@ func handlerRegister(s *state, cmd command) error {
 	return nil
 }
 
-func handlerReset(s *state, cmd command) error {
-	if cmd.arguments == nil {
-		return errors.New("no arguments passed to the handler")
-	}
-	err := s.db.DeleteAllUsers(context.Background())
-	if err != nil {
-		fmt.Printf("Error deleting all users:\n %v\n", err)
-		os.Exit(1)
-	}
-	fmt.Println("Deleted all users successfully.")
-	return nil
-}
-
 func handlerUsers(s *state, cmd command) error {
-	if cmd.arguments == nil {
-		return errors.New("no arguments passed to the handler")
-	}
 	users, err := s.db.GetUsers(context.Background())
 	if err != nil {
-		fmt.Printf("Error getting all users:\n %v\n", err)
-		os.Exit(1)
+		return databaseError("error getting all users: %w", err)
 	}
 
-	cfg, err := config.Read()
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	cfg := s.configPointer
+
+	if s.output != outputPlain {
+		var records []userRecord
+		for _, u := range users {
+			records = append(records, userRecord{
+				ID: u.ID,
+				Name: u.Name,
+				Current: u.Name == cfg.CurrentUserName,
+				CreatedAt: u.CreatedAt,
+				UpdatedAt: u.UpdatedAt,
+			})
+		}
+		return writeRecords(os.Stdout, s.output, records)
 	}
 
-
 	fmt.Println("All users:")
 	for _, u := range users {
 		if u.Name == cfg.CurrentUserName {
@@ -165,64 +151,78 @@ func handlerUsers(s *state, cmd command) error {
 	return nil
 }
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+// fetchFeed downloads and parses the feed at feedURL. If the feed moved
+// permanently it also returns its new URL, otherwise "".
+func fetchFeed(ctx context.Context, f *fetcher, feedURL string) (*RSSFeed, string, error) {
+	resp, err := f.get(ctx, feedURL)
 	if err != nil {
-		return nil, fmt.Errorf("error creating request for url: %v\n %v", feedURL, err)
+		return nil, "", err
 	}
 
-	req.Header.Set("User-Agent", "gator")
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error fetching url: %v\n %v", feedURL, err)
+	if resp.status == http.StatusGone {
+		return nil, "", networkError("error fetching url: %v\n %v: %w", feedURL, resp.statusMsg, errFeedGone)
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading body: %v\n %v", feedURL, err)
+	if resp.status < 200 || resp.status > 299 {
+		return nil, "", networkError("error fetching url: %v\n unexpected status %v", feedURL, resp.statusMsg)
 	}
 
-	var rssFeed RSSFeed
-	if err := xml.Unmarshal(body, &rssFeed); err != nil {
-		return nil, fmt.Errorf("error unmarshalling body: %v\n %v", feedURL, err)
+	rssFeed, err := parseFeed(resp.body, resp.contentType)
+	if err != nil {
+		return nil, "", fmt.Errorf("error unmarshalling body: %v\n %w", feedURL, err)
 	}
 
-	rssFeed.Channel.Title = html.UnescapeString(rssFeed.Channel.Title)
-	rssFeed.Channel.Description = html.UnescapeString(rssFeed.Channel.Description)
+	// Text shown as is, rather than rendered from HTML, loses its control
+	// characters here, before it is printed or stored.
+	rssFeed.Channel.Title = htmltext.StripControl(html.UnescapeString(rssFeed.Channel.Title))
+	rssFeed.Channel.Description = htmltext.StripControl(html.UnescapeString(rssFeed.Channel.Description))
 
 	for i := range rssFeed.Channel.Item {
-		rssFeed.Channel.Item[i].Title = html.UnescapeString(rssFeed.Channel.Item[i].Title)
-		rssFeed.Channel.Item[i].Description = html.UnescapeString(rssFeed.Channel.Item[i].Description)
+		item := &rssFeed.Channel.Item[i]
+		item.Title = htmltext.StripControl(html.UnescapeString(item.Title))
+		item.Link = htmltext.StripControl(item.Link)
+		item.Author = htmltext.StripControl(item.Author)
+		item.Creator = htmltext.StripControl(item.Creator)
+		for j := range item.Category {
+			item.Category[j] = htmltext.StripControl(item.Category[j])
+		}
+		// Links may be relative to the feed, and relative links in the
+		// description point next to the post.
+		if u, err := url.Parse(feedURL); err == nil {
+			if link, err := u.Parse(item.Link); err == nil {
+				item.Link = link.String()
+			}
+		}
+		item.Description = htmltext.StripControl(htmltext.Sanitize(html.UnescapeString(item.Description), item.Link))
 	}
 
-	return &rssFeed, nil
+	return rssFeed, resp.redirects.permanentURL(), nil
 }
 
+// defaultAggParallel is how many feeds agg fetches at once.
+const defaultAggParallel = 4
+
 func handlerAgg(s *state, cmd command) error {
-	if len(cmd.arguments) < 1 {
-		return errors.New("not enough arguments passed to the handler")
-	}
 	time_between_reqs_duration, err := time.ParseDuration(cmd.arguments[0])
 	if err != nil {
-		return err
+		return validationError("invalid interval: %w", err)
+	}
+
+	parallel := cmd.intFlag("parallel")
+	if parallel < 1 {
+		return validationError("invalid --parallel %d, expected at least 1", parallel)
 	}
 
 	fmt.Printf("Collecting feeds every %v\n", time_between_reqs_duration)
 
 	ticker := time.NewTicker(time_between_reqs_duration)
 	for ; ; <-ticker.C {
-		err := scrapeFeeds(s); if err != nil {
+		err := scrapeFeedBatch(s, parallel); if err != nil {
 			fmt.Println(err)
 		}
 	}
 }
 
 func handlerAddFeed(s *state, cmd command, user database.User) error {
-	if len(cmd.arguments) < 2 {
-		return errors.New("not enough arguments passed to the handler")
-	}
-
 	name := cmd.arguments[0]
 	url := cmd.arguments[1]
 
@@ -236,7 +236,10 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 	}
 
 	feed, err := s.db.CreateFeed(context.Background(), params); if err != nil {
-		return fmt.Errorf("error creating feed: %v", err)
+		if storage.IsUniqueViolation(err) {
+			return conflictError("a feed with url %v already exists, use follow to follow it", url)
+		}
+		return databaseError("error creating feed: %w", err)
 	}
 
 	fmt.Println(feed)
@@ -250,7 +253,7 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 	}
 
 	_, err = s.db.CreateFeedFollow(context.Background(), feedFollowParams); if err != nil {
-		return fmt.Errorf("error creating feed follow: %v", err)
+		return databaseError("error creating feed follow: %w", err)
 	}
 
 	fmt.Printf("Added a feed follow for the created feed %v.\n", feed.Name)
@@ -260,27 +263,47 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 
 func handlerFeeds(s *state, cmd command) error {
 	feeds, err := s.db.GetFeeds(context.Background()); if err != nil {
-		return fmt.Errorf("error getting all feeds: %v", err)
+		return databaseError("error getting all feeds: %w", err)
 	}
 
+	var records []feedRecord
 	for _, f := range feeds {
 		feedUser, err := s.db.GetUserById(context.Background(), f.UserID); if err != nil {
-			return fmt.Errorf("error getting user info by id: %v", err)
+			return databaseError("error getting user info by id: %w", err)
 		}
-		fmt.Printf("Name: %v, URL: %v, User: %v\n", f.Name, f.Url, feedUser.Name)
+		if s.output == outputPlain {
+			fmt.Printf("Name: %v, URL: %v, User: %v\n", f.Name, f.Url, feedUser.Name)
+			continue
+		}
+
+		record := feedRecord{
+			ID: f.ID,
+			Name: f.Name,
+			URL: f.Url,
+			User: feedUser.Name,
+			CreatedAt: f.CreatedAt,
+			UpdatedAt: f.UpdatedAt,
+		}
+		if f.LastFetchedAt.Valid {
+			record.LastFetchedAt = &f.LastFetchedAt.Time
+		}
+		records = append(records, record)
 	}
 
+	if s.output != outputPlain {
+		return writeRecords(os.Stdout, s.output, records)
+	}
 	return nil
 }
 
 func handlerFollow(s *state, cmd command, user database.User) error {
-	if len(cmd.arguments) < 1 {
-		return errors.New("not enough arguments passed to the handler")
-	}
 	url := cmd.arguments[0]
 
 	feed, err := s.db.GetFeedByURL(context.Background(), url); if err != nil {
-		return fmt.Errorf("error getting feed by url: %v", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return notFoundError("no feed with url %v, use addfeed to add it", url)
+		}
+		return databaseError("error getting feed by url: %w", err)
 	}
 
 	params := database.CreateFeedFollowParams{
@@ -292,7 +315,10 @@ func handlerFollow(s *state, cmd command, user database.User) error {
 	}
 
 	created_feed_follow, err := s.db.CreateFeedFollow(context.Background(), params); if err != nil {
-		return fmt.Errorf("error creating feed follow: %v", err)
+		if storage.IsUniqueViolation(err) {
+			return conflictError("already following %v", url)
+		}
+		return databaseError("error creating feed follow: %w", err)
 	}
 
 	fmt.Printf("Successfully created the feed follow record with name %v for the current user, %v.\n", created_feed_follow.FeedName, created_feed_follow.UserName)
@@ -303,22 +329,78 @@ func handlerFollow(s *state, cmd command, user database.User) error {
 func handlerFollowing(s *state, cmd command, user database.User) error {
 	currentUserFeeds, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
 	if err != nil {
-		return fmt.Errorf("error getting feed follows for current user: %v", err)
+		return databaseError("error getting feed follows for current user: %w", err)
+	}
+	unread, err := unreadCounts(s, user); if err != nil {
+		return err
+	}
+
+	folderName := cmd.stringFlag("folder")
+	if folderName != "" {
+		folder, err := getFolder(s, user, folderName); if err != nil {
+			return err
+		}
+		var inFolder []database.GetFeedFollowsForUserRow
+		for _, c := range currentUserFeeds {
+			if c.FolderID.Valid && c.FolderID.UUID == folder.ID {
+				inFolder = append(inFolder, c)
+			}
+		}
+		currentUserFeeds = inFolder
+	}
+
+	if s.output != outputPlain {
+		var records []followRecord
+		for _, c := range currentUserFeeds {
+			records = append(records, followRecord{
+				ID: c.ID,
+				FeedID: c.FeedID,
+				FeedName: c.FeedName,
+				FeedURL: c.FeedUrl,
+				User: c.UserName,
+				Folder: c.FolderName.String,
+				Unread: unread[c.FeedID],
+				CreatedAt: c.CreatedAt,
+			})
+		}
+		return writeRecords(os.Stdout, s.output, records)
 	}
 
+	if folderName != "" {
+		fmt.Printf("Feeds in %v:\n", folderName)
+		for _, c := range currentUserFeeds {
+			fmt.Printf("%v (%v unread)\n", c.FeedName, unread[c.FeedID])
+		}
+		return nil
+	}
+
+	// Feeds outside of any folder come first, then each folder by name.
 	fmt.Println("Current user feeds:")
+	var folders []string
+	byFolder := map[string][]database.GetFeedFollowsForUserRow{}
 	for _, c := range currentUserFeeds {
-		fmt.Println(c.FeedName)
+		if !c.FolderName.Valid {
+			fmt.Printf("%v (%v unread)\n", c.FeedName, unread[c.FeedID])
+			continue
+		}
+		name := c.FolderName.String
+		if _, seen := byFolder[name]; !seen {
+			folders = append(folders, name)
+		}
+		byFolder[name] = append(byFolder[name], c)
+	}
+	slices.Sort(folders)
+	for _, name := range folders {
+		fmt.Printf("%v:\n", name)
+		for _, c := range byFolder[name] {
+			fmt.Printf("  %v (%v unread)\n", c.FeedName, unread[c.FeedID])
+		}
 	}
 
 	return nil
 }
 
 func handlerUnfollow(s *state, cmd command, user database.User) error {
-	if len(cmd.arguments) < 1 {
-		return errors.New("not enough arguments passed to the handler")
-	}
-
 	url := cmd.arguments[0]
 
 	params := database.DeleteFeedFollowParams{
@@ -326,7 +408,7 @@ func handlerUnfollow(s *state, cmd command, user database.User) error {
 		Url: url,
 	}
 	err := s.db.DeleteFeedFollow(context.Background(), params); if err != nil {
-		return fmt.Errorf("error deleting feed follow: %v", err)
+		return databaseError("error deleting feed follow: %w", err)
 	}
 
 	fmt.Println("Successfully unfollowed url.")
@@ -337,66 +419,147 @@ func handlerBrowse(s *state, cmd command, user database.User) error {
 	limit := int32(2)
 	if len(cmd.arguments) > 0 {
 		parsedLimit, err := strconv.Atoi(cmd.arguments[0])
-		if err != nil {
-			return fmt.Errorf("invalid limit value: %v", err)
+		if err != nil || parsedLimit < 1 {
+			return validationError("invalid limit value: %v", cmd.arguments[0])
 		}
 		limit = int32(parsedLimit)
 	}
 
-	params := database.GetPostsForUserParams{
+	params := database.GetReaderItemsParams{
 		UserID: user.ID,
-		Limit: limit,
+		ExcludeRead: cmd.boolFlag("unread"),
+		MaxItems: limit,
 	}
-
-	posts, err := s.db.GetPostsForUser(context.Background(), params); if err != nil {
-		return err
+	if name := cmd.stringFlag("folder"); name != "" {
+		folder, err := getFolder(s, user, name); if err != nil {
+			return err
+		}
+		params.FolderID = uuid.NullUUID{UUID: folder.ID, Valid: true}
+	}
+	if tag := strings.TrimSpace(cmd.stringFlag("tag")); tag != "" {
+		params.Tag = sql.NullString{String: tag, Valid: true}
 	}
 
-	fmt.Println("Current user posts:")
-	for _, p := range posts {
-		fmt.Println(p.Title)
+	posts, err := s.db.GetReaderItems(context.Background(), params); if err != nil {
+		return databaseError("error getting posts for current user: %w", err)
 	}
 
-	return nil
+	return writePosts(s, "Current user posts:", posts)
 }
+// scrapeFeeds fetches the feed due next.
 func scrapeFeeds(s *state) error {
-	nextFeed, err := s.db.GetNextFeedToFetch(context.Background()); if err != nil {
+	return scrapeFeedBatch(s, 1)
+}
+
+// scrapeFeedBatch fetches up to n of the feeds due next at the same time.
+// The per-host limit of the fetcher keeps feeds on the same server from
+// being fetched all at once.
+func scrapeFeedBatch(s *state, n int) error {
+	client, err := s.feedFetcher(); if err != nil {
 		return err
 	}
-	fmt.Printf("Currently getting feed: %v\n", nextFeed.Name)
 
-	err = s.db.MarkFeedFetched(context.Background(), nextFeed.ID); if err != nil {
-		return err
+	var feeds []database.Feed
+	claimed := map[uuid.UUID]bool{}
+	for len(feeds) < n {
+		nextFeed, err := s.db.GetNextFeedToFetch(context.Background()); if err != nil {
+			if errors.Is(err, sql.ErrNoRows) && len(feeds) == 0 {
+				return notFoundError("there are no feeds to fetch, use addfeed to add one")
+			}
+			if errors.Is(err, sql.ErrNoRows) {
+				break
+			}
+			return databaseError("error getting next feed to fetch: %w", err)
+		}
+		// Marking a feed fetched sends it to the back of the queue, so
+		// meeting one again means every feed is in the batch.
+		if claimed[nextFeed.ID] {
+			break
+		}
+		fmt.Printf("Currently getting feed: %v\n", nextFeed.Name)
+
+		err = s.db.MarkFeedFetched(context.Background(), nextFeed.ID); if err != nil {
+			return databaseError("error marking feed fetched: %w", err)
+		}
+		fmt.Printf("Marked feed %v as fetched with current time.\n", nextFeed.Name)
+		claimed[nextFeed.ID] = true
+		feeds = append(feeds, nextFeed)
+	}
+
+	errs := make([]error, len(feeds))
+	var wg sync.WaitGroup
+	for i, feed := range feeds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = scrapeFeed(s, client, feed)
+		}()
 	}
-	fmt.Printf("Marked feed %v as fetched with current time.\n", nextFeed.Name)
+	wg.Wait()
+	return errors.Join(errs...)
+}
 
-	feed, err := fetchFeed(context.Background(), nextFeed.Url); if err != nil {
+// scrapeFeed fetches nextFeed and stores its new posts.
+func scrapeFeed(s *state, client *fetcher, nextFeed database.Feed) error {
+	feed, movedTo, fetchErr := fetchFeed(context.Background(), client, nextFeed.Url)
+	err := recordFetchError(s, nextFeed, fetchErr); if err != nil {
 		return err
 	}
+	if errors.Is(fetchErr, errFeedGone) {
+		err = s.db.MarkFeedDead(context.Background(), nextFeed.ID); if err != nil {
+			return databaseError("error marking feed dead: %w", err)
+		}
+		fmt.Printf("Feed %v is gone and will no longer be fetched, use setfeedurl if it moved.\n", nextFeed.Name)
+		return nil
+	}
+	if fetchErr != nil {
+		return fetchErr
+	}
+	if movedTo != "" {
+		nextFeed, err = moveFeed(s, nextFeed, movedTo); if err != nil {
+			return err
+		}
+	}
 	fmt.Printf("Currently fetching feed RSS Struct of title: %v\n", feed.Channel.Title)
 
 	feedItems := feed.Channel.Item
 
+	rules, err := feedRules(s, nextFeed.ID); if err != nil {
+		return err
+	}
+
 	fmt.Println("Channel items titles:")
 	for _, post := range feedItems {
-		var description sql.NullString
+		var description, summary sql.NullString
 		if post.Description != "" {
 			description = sql.NullString{
 				String: post.Description,
 				Valid: true,
 			}
+			summary = sql.NullString{
+				String: htmltext.Summary(post.Description),
+				Valid: true,
+			}
 		} else {
 			description = sql.NullString{
 				Valid: false,
 			}
 		}
 
-		pubTime, err := time.Parse(time.RFC1123Z, post.PubDate)
+		pubTime, err := parsePubDate(post.PubDate)
 		if err != nil {
 			fmt.Printf("Could not parse time %s: %v\n", post.PubDate, err)
 			pubTime = time.Now()
 		}
 
+		var author, categories sql.NullString
+		if name := post.author(); name != "" {
+			author = sql.NullString{String: name, Valid: true}
+		}
+		if len(post.Category) > 0 {
+			categories = sql.NullString{String: joinCategories(post.Category), Valid: true}
+		}
+
 		postParams := database.CreatePostParams{
 			ID: uuid.New(),
 			CreatedAt: time.Now(),
@@ -405,77 +568,499 @@ func scrapeFeeds(s *state) error {
 			Url: post.Link,
 			Description: description,
 			PublishedAt: pubTime,
-			FeedID: nextFeed.ID,
+			FeedID: uuid.NullUUID{UUID: nextFeed.ID, Valid: true},
+			Summary: summary,
+			Author: author,
+			Categories: categories,
 		}
 
 		post, err := s.db.CreatePost(context.Background(), postParams); if err != nil {
-			fmt.Printf("error creating post: %v\n", err)
-			return err
+			// Posts already stored by an earlier fetch are skipped by the
+			// insert, which then returns no row.
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			return databaseError("error creating post: %w", err)
 		}
 
 		fmt.Printf("Post %v added.\n", post.Title)
+
+		err = applyRules(s, rules, post); if err != nil {
+			return err
+		}
+
+		if nextFeed.FetchFullContent {
+			err = storeArticle(s, client, post); if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// recordFetchError keeps the outcome of the latest fetch of feed, shown by
+// feedinfo. A nil fetchErr clears the previous error.
+func recordFetchError(s *state, feed database.Feed, fetchErr error) error {
+	params := database.SetFeedFetchErrorParams{
+		ID: feed.ID,
+	}
+	if fetchErr != nil {
+		params.LastFetchError = sql.NullString{String: fetchErr.Error(), Valid: true}
 	}
 
+	err := s.db.SetFeedFetchError(context.Background(), params); if err != nil {
+		return databaseError("error saving fetch result: %w", err)
+	}
 	return nil
 }
 
 func middlewareLoggedIn(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
 	return func(s *state, cmd command) error {
+		if s.configPointer.CurrentUserName == "" {
+			return notFoundError("no user is logged in, use login or register first")
+		}
 		user, err := s.db.GetUser(context.Background(), s.configPointer.CurrentUserName)
 		if err != nil {
-			return err
+			if errors.Is(err, sql.ErrNoRows) {
+				return notFoundError("current user '%s' does not exist, use login or register first", s.configPointer.CurrentUserName)
+			}
+			return databaseError("error getting current user: %w", err)
 		}
 		return handler(s, cmd, user)
 	}
 }
 
 func main() {
-	cfg, err := config.Read()
+	if err := run(); err != nil {
+		exitWithError(err)
+	}
+}
+
+func run() error {
+	opts, args, err := parseGlobalOptions(os.Args[1:])
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return err
 	}
 
-	db, err := sql.Open("postgres", cfg.DbUrl)
+	cfg, err := config.Load(config.Options{Path: opts.configPath, Profile: opts.profile})
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return fmt.Errorf("error reading config: %w", err)
 	}
-	dbQueries := database.New(db)
 
 	var s state
 	s.configPointer = &cfg
-	s.db = dbQueries
+	s.output = opts.output
+	defer func() {
+		if s.db != nil {
+			s.db.Close()
+		}
+	}()
 
-	commands := commands{
-		handlers: make(map[string]func(*state, command) error),
-	}
+	commands := newCommands()
 
-	if len(os.Args) < 2 {
-		fmt.Println("No arguments passed to the command")
-		os.Exit(1)
+	if len(args) < 1 {
+		commands.printHelp(os.Stdout)
+		return validationError("no command given")
 	}
 
 	cmd := command{
-		name: os.Args[1],
-		arguments: os.Args[2:],
-	}
-
-	commands.register("login", handlerLogin)
-	commands.register("register", handlerRegister)
-	commands.register("reset", handlerReset)
-	commands.register("users", handlerUsers)
-	commands.register("agg", handlerAgg)
-	commands.register("addfeed", middlewareLoggedIn(handlerAddFeed))
-	commands.register("feeds", handlerFeeds)
-	commands.register("follow", middlewareLoggedIn(handlerFollow))
-	commands.register("following", middlewareLoggedIn(handlerFollowing))
-	commands.register("unfollow", middlewareLoggedIn(handlerUnfollow))
-	commands.register("browse", middlewareLoggedIn(handlerBrowse))
-
-	err = commands.run(&s, cmd)
+		name: args[0],
+		arguments: args[1:],
+	}
+
+	return commands.run(&s, cmd)
+}
+
+// openDatabase connects to the database of the config the first time a
+// command needs it, so that commands such as config and help work before
+// db_url is set right.
+func openDatabase(s *state) error {
+	if s.db != nil {
+		return nil
+	}
+
+	store, err := storage.Open(s.configPointer.DbUrl)
+	if err != nil {
+		return databaseError("error opening database: %w", err)
+	}
+	migrations, err := newMigrator(store)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		store.Close()
+		return fmt.Errorf("error loading migrations: %w", err)
 	}
+	s.db = store
+	s.migrations = migrations
+	return nil
+}
+
+// newCommands returns the registry of every command gator knows.
+func newCommands() *commands {
+	commands := &commands{
+		handlers: make(map[string]commandSpec),
+	}
+
+	commands.register(commandSpec{
+		name: "login",
+		usage: "<username>",
+		description: "Log in as an existing user.",
+		minArgs: 1,
+		maxArgs: 1,
+		complete: completeUsernames,
+		handler: handlerLogin,
+	})
+	commands.register(commandSpec{
+		name: "register",
+		usage: "<username>",
+		description: "Create a new user and log in as them.",
+		minArgs: 1,
+		maxArgs: 1,
+		handler: handlerRegister,
+	})
+	commands.register(commandSpec{
+		name: "reset",
+		usage: "[all|posts|feeds|user <name>]",
+		description: "Delete all users, or only posts, feeds or one user, along with everything that depends on them. Asks for confirmation unless --yes is given.",
+		maxArgs: 2,
+		setFlags: setResetFlags,
+		complete: completeReset,
+		handler: handlerReset,
+	})
+	commands.register(commandSpec{
+		name: "users",
+		description: "List all users, marking the current one.",
+		handler: handlerUsers,
+	})
+	commands.register(commandSpec{
+		name: "whoami",
+		description: "Show the current user and profile, with follow and unread counts.",
+		handler: middlewareLoggedIn(handlerWhoami),
+	})
+	commands.register(commandSpec{
+		name: "logout",
+		description: "Forget the current user.",
+		handler: handlerLogout,
+	})
+	commands.register(commandSpec{
+		name: "renameuser",
+		usage: "<old_name> <new_name>",
+		description: "Rename a user, keeping them logged in if they are the current user.",
+		minArgs: 2,
+		maxArgs: 2,
+		complete: completeUsernames,
+		handler: handlerRenameUser,
+	})
+	commands.register(commandSpec{
+		name: "deleteuser",
+		usage: "<username>",
+		description: "Delete a user along with the feeds they added. Asks for confirmation unless --yes is given.",
+		minArgs: 1,
+		maxArgs: 1,
+		setFlags: setResetFlags,
+		complete: completeUsernames,
+		handler: handlerDeleteUser,
+	})
+	commands.register(commandSpec{
+		name: "agg",
+		usage: "<interval>",
+		description: "Fetch feeds continuously, the next few feeds every interval (e.g. 30s, 1m).",
+		minArgs: 1,
+		maxArgs: 1,
+		setFlags: func(fs *flag.FlagSet) {
+			fs.Int("parallel", defaultAggParallel, "number of feeds to fetch at once each interval")
+		},
+		complete: completeWords(durationExamples...),
+		handler: handlerAgg,
+	})
+	commands.register(commandSpec{
+		name: "addfeed",
+		usage: "<feed_name> <feed_url>",
+		description: "Add a new RSS feed and follow it.",
+		minArgs: 2,
+		maxArgs: 2,
+		handler: middlewareLoggedIn(handlerAddFeed),
+	})
+	commands.register(commandSpec{
+		name: "feeds",
+		description: "List all feeds along with the users who added them.",
+		handler: handlerFeeds,
+	})
+	commands.register(commandSpec{
+		name: "feedinfo",
+		usage: "<feed_url>",
+		description: "Show who added a feed, its followers and posts, and whether it is being fetched.",
+		minArgs: 1,
+		maxArgs: 1,
+		complete: completeFeedURLs,
+		handler: handlerFeedInfo,
+	})
+	commands.register(commandSpec{
+		name: "renamefeed",
+		usage: "<feed_url> <new_name>",
+		description: "Rename a feed you added.",
+		minArgs: 2,
+		maxArgs: 2,
+		complete: completeFeedURLs,
+		handler: middlewareLoggedIn(handlerRenameFeed),
+	})
+	commands.register(commandSpec{
+		name: "setfeedurl",
+		usage: "<feed_url> <new_url>",
+		description: "Change the url of a feed you added, e.g. when the site moved.",
+		minArgs: 2,
+		maxArgs: 2,
+		complete: completeFeedURLs,
+		handler: middlewareLoggedIn(handlerSetFeedURL),
+	})
+	commands.register(commandSpec{
+		name: "fullcontent",
+		usage: "<feed_url> <on|off>",
+		description: "Fetch the full article of new posts of a feed you added, for feeds that only publish teasers.",
+		minArgs: 2,
+		maxArgs: 2,
+		complete: func(s *state, position int) []string {
+			if position == 1 {
+				return []string{"on", "off"}
+			}
+			return completeFeedURLs(s, position)
+		},
+		handler: middlewareLoggedIn(handlerFullContent),
+	})
+	commands.register(commandSpec{
+		name: "deletefeed",
+		usage: "<feed_url>",
+		description: "Delete a feed you added, along with its follows and, unless --keep-posts is given, its posts. Asks for confirmation unless --yes is given.",
+		minArgs: 1,
+		maxArgs: 1,
+		setFlags: func(fs *flag.FlagSet) {
+			setResetFlags(fs)
+			fs.Bool("keep-posts", false, "keep the posts of the feed, to be taken back if it is added again")
+		},
+		complete: completeFeedURLs,
+		handler: middlewareLoggedIn(handlerDeleteFeed),
+	})
+	commands.register(commandSpec{
+		name: "follow",
+		usage: "<feed_url>",
+		description: "Follow an existing feed.",
+		minArgs: 1,
+		maxArgs: 1,
+		complete: completeFeedURLs,
+		handler: middlewareLoggedIn(handlerFollow),
+	})
+	commands.register(commandSpec{
+		name: "following",
+		description: "List the feeds the current user follows, by folder, with their unread posts.",
+		setFlags: func(fs *flag.FlagSet) {
+			fs.String("folder", "", "only list the feeds in this folder")
+		},
+		handler: middlewareLoggedIn(handlerFollowing),
+	})
+	commands.register(commandSpec{
+		name: "folders",
+		usage: "<list|create|rename|delete|move> [args]",
+		description: "Manage the folders you sort followed feeds into.",
+		minArgs: 1,
+		maxArgs: 3,
+		complete: completeWords("list", "create", "rename", "delete", "move"),
+		handler: middlewareLoggedIn(handlerFolders),
+	})
+	commands.register(commandSpec{
+		name: "opml",
+		usage: "<import|export> [file]",
+		description: "Import feeds and folders from an OPML file, or export the feeds you follow to one (default: standard output).",
+		minArgs: 1,
+		maxArgs: 2,
+		complete: completeWords("import", "export"),
+		handler: middlewareLoggedIn(handlerOPML),
+	})
+	commands.register(commandSpec{
+		name: "unfollow",
+		usage: "<feed_url>",
+		description: "Stop following a feed.",
+		minArgs: 1,
+		maxArgs: 1,
+		complete: completeFollowedFeedURLs,
+		handler: middlewareLoggedIn(handlerUnfollow),
+	})
+	commands.register(commandSpec{
+		name: "browse",
+		usage: "[limit]",
+		description: "Show the latest posts from followed feeds (default limit: 2).",
+		maxArgs: 1,
+		setFlags: func(fs *flag.FlagSet) {
+			fs.Bool("unread", false, "only show posts that have not been read")
+			fs.String("folder", "", "only show posts from feeds in this folder")
+			fs.String("tag", "", "only show posts you tagged with this tag")
+		},
+		handler: middlewareLoggedIn(handlerBrowse),
+	})
+	commands.register(commandSpec{
+		name: "show",
+		usage: "<post>",
+		description: "Show the details and text of a post, given by its short id, id or url.",
+		minArgs: 1,
+		maxArgs: 1,
+		handler: middlewareLoggedIn(handlerShow),
+	})
+	commands.register(commandSpec{
+		name: "open",
+		usage: "<post>",
+		description: "Open a post in the browser.",
+		minArgs: 1,
+		maxArgs: 1,
+		handler: middlewareLoggedIn(handlerOpen),
+	})
+	commands.register(commandSpec{
+		name: "tag",
+		usage: "<post> <tag>",
+		description: "Tag a post, given by its short id, id or url, to add it to a reading list.",
+		minArgs: 2,
+		maxArgs: 2,
+		complete: completeTags,
+		handler: middlewareLoggedIn(handlerTag),
+	})
+	commands.register(commandSpec{
+		name: "untag",
+		usage: "<post> <tag>",
+		description: "Remove a tag from a post.",
+		minArgs: 2,
+		maxArgs: 2,
+		complete: completeTags,
+		handler: middlewareLoggedIn(handlerUntag),
+	})
+	commands.register(commandSpec{
+		name: "tags",
+		usage: "[post]",
+		description: "List your tags with the number of posts on each, or the tags of one post.",
+		maxArgs: 1,
+		handler: middlewareLoggedIn(handlerTags),
+	})
+	commands.register(commandSpec{
+		name: "search",
+		usage: "<query>",
+		description: "Find posts from followed feeds whose title or summary contains the query.",
+		minArgs: 1,
+		maxArgs: -1,
+		setFlags: func(fs *flag.FlagSet) {
+			fs.Int("limit", defaultSearchItems, "number of posts to show")
+		},
+		handler: middlewareLoggedIn(handlerSearch),
+	})
+	commands.register(commandSpec{
+		name: "apitoken",
+		description: "Generate a new API token for the current user.",
+		handler: middlewareLoggedIn(handlerAPIToken),
+	})
+	commands.register(commandSpec{
+		name: "serve",
+		usage: "[address]",
+		description: "Serve the Google Reader API and users' rivers over HTTP (default address: :8080).",
+		maxArgs: 1,
+		handler: handlerServe,
+	})
+	commands.register(commandSpec{
+		name: "river",
+		usage: "<rss|atom>",
+		description: "Print the latest posts from followed feeds as an RSS 2.0 or Atom document.",
+		minArgs: 1,
+		maxArgs: 1,
+		setFlags: func(fs *flag.FlagSet) {
+			fs.Int("limit", defaultRiverItems, "number of posts to include")
+			fs.String("feed", "", "only include posts from the feed with this URL")
+			fs.String("folder", "", "only include posts from feeds in this folder")
+			fs.String("tag", "", "only include posts you tagged with this tag")
+			fs.String("url", "", "address the river is published at, used as its link")
+		},
+		complete: completeWords("rss", "atom"),
+		handler: middlewareLoggedIn(handlerRiver),
+	})
+	commands.register(commandSpec{
+		name: "publish",
+		usage: "<outdir>",
+		description: "Render the latest posts from followed feeds into a static HTML site.",
+		minArgs: 1,
+		maxArgs: 1,
+		setFlags: func(fs *flag.FlagSet) {
+			fs.Int("limit", defaultPublishItems, "number of posts to include")
+		},
+		handler: middlewareLoggedIn(handlerPublish),
+	})
+	commands.register(commandSpec{
+		name: "tui",
+		description: "Browse and read posts in an interactive terminal interface.",
+		setFlags: func(fs *flag.FlagSet) {
+			fs.Bool("unread", false, "start with only unread posts shown")
+		},
+		handler: middlewareLoggedIn(handlerTUI),
+	})
+	commands.register(commandSpec{
+		name: "migrate",
+		usage: "<up|down|status|redo>",
+		description: "Apply, roll back or list the database schema migrations.",
+		minArgs: 1,
+		maxArgs: 1,
+		complete: completeWords("up", "down", "status", "redo"),
+		noSchemaCheck: true,
+		handler: handlerMigrate,
+	})
+	commands.register(commandSpec{
+		name: "rules",
+		usage: "<list|add|delete|apply> [args]",
+		description: "Manage rules that mark read, star, tag or hide matching posts.",
+		minArgs: 1,
+		maxArgs: 5,
+		setFlags: func(fs *flag.FlagSet) {
+			fs.String("feed", "", "with add: only apply the rule to the feed with this URL")
+			fs.Bool("regex", false, "with add: the pattern is a regular expression")
+			fs.Bool("dry-run", false, "with apply: only show how many posts each rule matches")
+		},
+		complete: completeWords("list", "add", "delete", "apply"),
+		handler: middlewareLoggedIn(handlerRules),
+	})
+	commands.register(commandSpec{
+		name: "config",
+		usage: "<init|get|set|list> [key] [value]",
+		description: "Create, show or change the settings of the current config profile.",
+		minArgs: 1,
+		maxArgs: 3,
+		setFlags: func(fs *flag.FlagSet) {
+			fs.Bool("skip-check", false, "save db_url without checking that the database can be reached")
+		},
+		complete: completeConfig,
+		noDatabase: true,
+		handler: handlerConfig,
+	})
+	commands.register(commandSpec{
+		name: "help",
+		usage: "[command]",
+		description: "Show the list of commands, or the usage of one command.",
+		maxArgs: 1,
+		complete: func(s *state, position int) []string {
+			return completeWords(commands.visibleNames()...)(s, position)
+		},
+		noDatabase: true,
+		handler: commands.handlerHelp,
+	})
+	commands.register(commandSpec{
+		name: "completion",
+		usage: "<bash|zsh|fish>",
+		description: "Print a shell completion script.",
+		minArgs: 1,
+		maxArgs: 1,
+		complete: completeWords(completionShells...),
+		noDatabase: true,
+		handler: commands.handlerCompletion,
+	})
+	commands.register(commandSpec{
+		name: "__complete",
+		usage: "-- <command> [arguments] <word>",
+		description: "Print completion candidates for the generated shell scripts.",
+		maxArgs: -1,
+		hidden: true,
+		noDatabase: true,
+		handler: commands.handlerComplete,
+	})
+
+	return commands
 }
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/alifoo/blog-aggregator/internal/config"
+	"github.com/alifoo/blog-aggregator/internal/storage"
+	"github.com/pressly/goose/v3"
+)
+
+//go:embed sql/schema/*.sql sql/sqlite/schema/*.sql
+var schemaFiles embed.FS
+
+// newMigrator returns a goose provider for the migrations embedded in the
+// binary for the store's backend. It keeps versions in goose_db_version,
+// the table the goose CLI uses, so databases set up by hand are picked up
+// where they were left.
+func newMigrator(store storage.Store) (*goose.Provider, error) {
+	dialect, dir := goose.DialectPostgres, "sql/schema"
+	if store.Backend() == storage.SQLite {
+		dialect, dir = goose.DialectSQLite3, "sql/sqlite/schema"
+	}
+
+	migrations, err := fs.Sub(schemaFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+	return goose.NewProvider(dialect, store.DB(), migrations)
+}
+
+func handlerMigrate(s *state, cmd command) error {
+	if err := requireDatabase(s); err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch direction := cmd.arguments[0]; direction {
+	case "up":
+		results, err := s.migrations.Up(ctx)
+		if err != nil {
+			return databaseError("error applying migrations: %w", err)
+		}
+		printMigrationResults(results)
+		if len(results) == 0 {
+			fmt.Println("The database schema is already up to date.")
+		}
+	case "down", "redo":
+		result, err := s.migrations.Down(ctx)
+		if errors.Is(err, goose.ErrNoNextVersion) {
+			return notFoundError("there are no applied migrations to roll back")
+		}
+		if err != nil {
+			return databaseError("error rolling back migration: %w", err)
+		}
+		printMigrationResults([]*goose.MigrationResult{result})
+		if direction == "down" {
+			break
+		}
+		result, err = s.migrations.UpByOne(ctx)
+		if err != nil {
+			return databaseError("error reapplying migration: %w", err)
+		}
+		printMigrationResults([]*goose.MigrationResult{result})
+	case "status":
+		statuses, err := s.migrations.Status(ctx)
+		if err != nil {
+			return databaseError("error getting migration status: %w", err)
+		}
+		for _, st := range statuses {
+			appliedAt := "pending"
+			if st.State == goose.StateApplied {
+				appliedAt = st.AppliedAt.Local().Format(time.DateTime)
+			}
+			fmt.Printf("%-20v %v\n", appliedAt, st.Source.Path)
+		}
+	default:
+		return validationError("unknown migrate direction %v, expected up, down, status or redo", direction)
+	}
+
+	return nil
+}
+
+func printMigrationResults(results []*goose.MigrationResult) {
+	for _, r := range results {
+		fmt.Printf("Migrated %v %v (%v)\n", r.Direction, r.Source.Path, r.Duration.Round(time.Millisecond))
+	}
+}
+
+// checkSchema refuses to go on when the database is missing migrations
+// embedded in this binary, instead of failing later on a missing column.
+func checkSchema(s *state) error {
+	if s.schemaChecked {
+		return nil
+	}
+	if err := requireDatabase(s); err != nil {
+		return err
+	}
+	pending, err := s.migrations.HasPending(context.Background())
+	if err != nil {
+		return databaseError("error checking database schema: %w", err)
+	}
+	if pending {
+		return fmt.Errorf("the database schema is out of date, run '%s migrate up' first", programName())
+	}
+	s.schemaChecked = true
+	return nil
+}
+
+// requireDatabase fails early when no db_url is configured, rather than
+// letting the driver fall back to its own defaults.
+func requireDatabase(s *state) error {
+	if s.configPointer.DbUrl == "" {
+		return validationError("no database configured, run '%s config init' or set %v", programName(), config.EnvName(config.KeyDbUrl))
+	}
+	return nil
+}
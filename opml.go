@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/alifoo/blog-aggregator/internal/storage"
+	"github.com/google/uuid"
+)
+
+// OPML is the format feed readers exchange subscription lists in. Feeds
+// are outlines with an xmlUrl, and folders are outlines containing them.
+type opmlDocument struct {
+	XMLName xml.Name      `xml:"opml"`
+	Version string        `xml:"version,attr"`
+	Title   string        `xml:"head>title"`
+	Created string        `xml:"head>dateCreated,omitempty"`
+	Body    []opmlOutline `xml:"body>outline"`
+}
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Category string        `xml:"category,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+func (o opmlOutline) name() string {
+	if o.Title != "" {
+		return strings.TrimSpace(o.Title)
+	}
+	return strings.TrimSpace(o.Text)
+}
+
+// opmlFeed is a feed listed in an OPML file, with the folder it is in.
+type opmlFeed struct {
+	name   string
+	url    string
+	folder string
+}
+
+func handlerOPML(s *state, cmd command, user database.User) error {
+	action, args := cmd.arguments[0], cmd.arguments[1:]
+	switch action {
+	case "export":
+		if len(args) > 1 {
+			return validationError("wrong number of arguments\nusage: %s opml export [file]", programName())
+		}
+		return exportOPML(s, user, args)
+	case "import":
+		if len(args) != 1 {
+			return validationError("wrong number of arguments\nusage: %s opml import <file>", programName())
+		}
+		return importOPML(s, user, args[0])
+	}
+	return validationError("unknown opml action %v, expected import or export", action)
+}
+
+// exportOPML writes the feeds user follows as OPML, to the file given in
+// args or to standard output. Each folder is an outline around its feeds.
+func exportOPML(s *state, user database.User, args []string) error {
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return databaseError("error getting feed follows: %w", err)
+	}
+	folders, err := s.db.GetFoldersForUser(context.Background(), user.ID)
+	if err != nil {
+		return databaseError("error getting folders: %w", err)
+	}
+
+	doc := buildOPML(user, follows, folders, time.Now())
+	if len(args) == 0 {
+		return writeOPML(os.Stdout, doc)
+	}
+
+	f, err := os.Create(args[0])
+	if err != nil {
+		return fmt.Errorf("error creating OPML file: %w", err)
+	}
+	if err := writeOPML(f, doc); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error writing OPML file: %w", err)
+	}
+	fmt.Printf("Exported %v to %v.\n", countFeeds(len(follows)), args[0])
+	return nil
+}
+
+func buildOPML(user database.User, follows []database.GetFeedFollowsForUserRow, folders []database.Folder, created time.Time) opmlDocument {
+	doc := opmlDocument{
+		Version: "2.0",
+		Title:   fmt.Sprintf("%v's feeds", user.Name),
+		Created: created.Format(time.RFC1123Z),
+	}
+
+	index := map[uuid.UUID]int{}
+	var folderOutlines []opmlOutline
+	for _, f := range folders {
+		index[f.ID] = len(folderOutlines)
+		folderOutlines = append(folderOutlines, opmlOutline{Text: f.Name, Title: f.Name})
+	}
+	for _, f := range follows {
+		outline := opmlOutline{Text: f.FeedName, Title: f.FeedName, Type: "rss", XMLURL: f.FeedUrl}
+		if i, ok := index[f.FolderID.UUID]; f.FolderID.Valid && ok {
+			folderOutlines[i].Outlines = append(folderOutlines[i].Outlines, outline)
+			continue
+		}
+		doc.Body = append(doc.Body, outline)
+	}
+	doc.Body = append(doc.Body, folderOutlines...)
+	return doc
+}
+
+func writeOPML(w io.Writer, doc opmlDocument) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("error marshalling OPML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// opmlContents is what an OPML document lists, in order.
+type opmlContents struct {
+	feeds   []opmlFeed
+	folders []string
+}
+
+func parseOPML(body []byte) (opmlContents, error) {
+	var doc opmlDocument
+	if err := newXMLDecoder(toUTF8(body, ""), true).Decode(&doc); err != nil {
+		return opmlContents{}, fmt.Errorf("error parsing OPML: %w", err)
+	}
+	var contents opmlContents
+	contents.collect(doc.Body, "")
+	return contents, nil
+}
+
+// collect walks nested outlines. Outlines without an xmlUrl are folders,
+// and as folders cannot be nested, a feed goes into the innermost one
+// around it. Feeds outside of any folder may name theirs in a category
+// attribute instead, such as "/News" or "News,Tech".
+func (c *opmlContents) collect(outlines []opmlOutline, folder string) {
+	for _, o := range outlines {
+		if o.XMLURL == "" {
+			inner := folder
+			if name := o.name(); name != "" {
+				inner = name
+				if !slices.Contains(c.folders, name) {
+					c.folders = append(c.folders, name)
+				}
+			}
+			c.collect(o.Outlines, inner)
+			continue
+		}
+		feed := opmlFeed{name: o.name(), url: strings.TrimSpace(o.XMLURL), folder: folder}
+		if feed.folder == "" && o.Category != "" {
+			category, _, _ := strings.Cut(o.Category, ",")
+			category = strings.Trim(strings.TrimSpace(category), "/")
+			feed.folder = category[strings.LastIndex(category, "/")+1:]
+		}
+		if feed.name == "" {
+			feed.name = feed.url
+		}
+		c.feeds = append(c.feeds, feed)
+	}
+}
+
+// importOPML follows every feed listed in an OPML file, adding the ones
+// that are not known yet, and puts them in the folders the file lists them
+// in, creating those as needed. Feeds with an invalid url are skipped.
+func importOPML(s *state, user database.User, path string) error {
+	var body []byte
+	var err error
+	if path == "-" {
+		body, err = io.ReadAll(os.Stdin)
+	} else {
+		body, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("error reading OPML file: %w", err)
+	}
+	contents, err := parseOPML(body)
+	if err != nil {
+		return validationError("%v: %w", path, err)
+	}
+
+	// Folders are created up front so that empty ones are kept too.
+	folders := map[string]database.Folder{}
+	for _, name := range contents.folders {
+		folder, err := getOrCreateFolder(s, user, name)
+		if err != nil {
+			return err
+		}
+		folders[name] = folder
+	}
+
+	var added, followed, skipped int
+	for _, f := range contents.feeds {
+		if err := checkFeedURL(f.url); err != nil {
+			fmt.Printf("Skipping %v: %v\n", f.name, err)
+			skipped++
+			continue
+		}
+		feed, created, err := getOrCreateFeed(s, user, f)
+		if err != nil {
+			return err
+		}
+		isNew, err := followFeed(s, user, feed)
+		if err != nil {
+			return err
+		}
+		if created {
+			added++
+		}
+		if isNew {
+			followed++
+		}
+
+		if f.folder == "" {
+			continue
+		}
+		folder, ok := folders[f.folder]
+		if !ok {
+			if folder, err = getOrCreateFolder(s, user, f.folder); err != nil {
+				return err
+			}
+			folders[f.folder] = folder
+		}
+		_, err = s.db.SetFeedFollowFolder(context.Background(), database.SetFeedFollowFolderParams{
+			FolderID: uuid.NullUUID{UUID: folder.ID, Valid: true},
+			UserID:   user.ID,
+			FeedID:   feed.ID,
+		})
+		if err != nil {
+			return databaseError("error moving feed to folder: %w", err)
+		}
+	}
+
+	fmt.Printf("Imported %v from %v: %d added, %d newly followed, %d skipped.\n",
+		countFeeds(len(contents.feeds)), path, added, followed, skipped)
+	return nil
+}
+
+// getOrCreateFeed returns the feed at the url of f, adding it on behalf of
+// user if it is not known yet.
+func getOrCreateFeed(s *state, user database.User, f opmlFeed) (database.Feed, bool, error) {
+	feed, err := s.db.GetFeedByURL(context.Background(), f.url)
+	if err == nil {
+		return feed, false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return database.Feed{}, false, databaseError("error getting feed by url: %w", err)
+	}
+
+	now := time.Now()
+	feed, err = s.db.CreateFeed(context.Background(), database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Name:      f.name,
+		Url:       f.url,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		return database.Feed{}, false, databaseError("error creating feed: %w", err)
+	}
+	fmt.Printf("Added feed %v.\n", feed.Name)
+	return feed, true, nil
+}
+
+// followFeed makes user follow feed and reports whether they did not
+// already.
+func followFeed(s *state, user database.User, feed database.Feed) (bool, error) {
+	now := time.Now()
+	_, err := s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	})
+	if storage.IsUniqueViolation(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, databaseError("error creating feed follow: %w", err)
+	}
+	return true, nil
+}
+
+func getOrCreateFolder(s *state, user database.User, name string) (database.Folder, error) {
+	folder, err := s.db.GetFolderByName(context.Background(), database.GetFolderByNameParams{UserID: user.ID, Name: name})
+	if err == nil {
+		return folder, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return database.Folder{}, databaseError("error getting folder: %w", err)
+	}
+
+	now := time.Now()
+	folder, err = s.db.CreateFolder(context.Background(), database.CreateFolderParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    user.ID,
+		Name:      name,
+	})
+	if err != nil {
+		return database.Folder{}, databaseError("error creating folder: %w", err)
+	}
+	fmt.Printf("Created folder %v.\n", folder.Name)
+	return folder, nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+// writeOPMLFixture writes an OPML fixture with its feed urls pointing at
+// server and returns its path.
+func writeOPMLFixture(t *testing.T, server *feedServer, name string) string {
+	t.Helper()
+
+	body, err := os.ReadFile(filepath.Join("testdata", "opml", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opml := strings.NewReplacer(
+		"{{example}}", server.feedURL("example.rss"),
+		"{{atom}}", server.feedURL("example.atom"),
+		"{{teaser}}", server.feedURL("teaser.rss"),
+	).Replace(string(body))
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(opml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOPMLImport(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+	path := writeOPMLFixture(t, server, "subscriptions.opml")
+
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "addfeed", "Atom by bob", server.feedURL("example.atom"))
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", server.feedURL("example.rss"))
+
+	out := mustRun(t, s, "opml", "import", path)
+	if !strings.Contains(out, "Skipping Not a feed") ||
+		!strings.HasSuffix(out, "Imported 4 feeds from "+path+": 1 added, 2 newly followed, 1 skipped.\n") {
+		t.Errorf("opml import:\n%s", out)
+	}
+
+	// Known feeds keep their name, and the folder of the outline around a
+	// feed wins over its text.
+	out = mustRun(t, s, "following")
+	want := "Current user feeds:\nExample (0 unread)\n" +
+		"Long reads:\n  By category (0 unread)\n" +
+		"Tech:\n  Atom by bob (0 unread)\n"
+	if out != want {
+		t.Errorf("following after import:\n%s\nwant:\n%s", out, want)
+	}
+	if out := mustRun(t, s, "folders", "list"); !strings.Contains(out, "Empty: 0 feeds") {
+		t.Errorf("empty folder was not imported:\n%s", out)
+	}
+
+	// Importing again changes nothing.
+	out = mustRun(t, s, "opml", "import", path)
+	if !strings.HasSuffix(out, "0 added, 0 newly followed, 1 skipped.\n") || strings.Contains(out, "Created folder") {
+		t.Errorf("second opml import:\n%s", out)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.opml")
+	if err := os.WriteFile(bad, []byte("<opml><body>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := runCommand(t, s, "opml", "import", bad)
+	assertKind(t, err, errValidation)
+	_, err = runCommand(t, s, "opml", "import")
+	assertKind(t, err, errValidation)
+	_, err = runCommand(t, s, "opml", "sync", path)
+	assertKind(t, err, errValidation)
+}
+
+func TestOPMLRoundTrip(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", server.feedURL("example.rss"))
+	mustRun(t, s, "addfeed", "Atom", server.feedURL("example.atom"))
+	mustRun(t, s, "folders", "create", "News")
+	mustRun(t, s, "folders", "create", "Later")
+	mustRun(t, s, "folders", "move", server.feedURL("example.atom"), "News")
+
+	path := filepath.Join(t.TempDir(), "feeds.opml")
+	if out := mustRun(t, s, "opml", "export", path); out != "Exported 2 feeds to "+path+".\n" {
+		t.Errorf("opml export:\n%s", out)
+	}
+	exported, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := mustRun(t, s, "opml", "export"); out != string(exported) {
+		t.Errorf("export to standard output differs from the file:\n%s", out)
+	}
+
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "opml", "import", path)
+	out := mustRun(t, s, "following")
+	if want := "Current user feeds:\nExample (0 unread)\nNews:\n  Atom (0 unread)\n"; out != want {
+		t.Errorf("following after round trip:\n%s\nwant:\n%s", out, want)
+	}
+	if out := mustRun(t, s, "folders", "list"); out != "Later: 0 feeds, 0 unread\nNews: 1 feed, 0 unread\n" {
+		t.Errorf("folders after round trip:\n%s", out)
+	}
+}
+
+func TestBuildOPML(t *testing.T) {
+	user := database.User{Name: "alice"}
+	news := database.Folder{ID: uuid.New(), Name: "News"}
+	follows := []database.GetFeedFollowsForUserRow{
+		{FeedName: "A & B", FeedUrl: "https://a.example.com/rss", FolderID: uuid.NullUUID{UUID: news.ID, Valid: true}},
+		{FeedName: "C", FeedUrl: "https://c.example.com/rss?x=1&y=2"},
+	}
+	var b strings.Builder
+	created := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	if err := writeOPML(&b, buildOPML(user, follows, []database.Folder{news}, created)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+  <head>
+    <title>alice&#39;s feeds</title>
+    <dateCreated>Mon, 06 Jan 2025 09:00:00 +0000</dateCreated>
+  </head>
+  <body>
+    <outline text="C" title="C" type="rss" xmlUrl="https://c.example.com/rss?x=1&amp;y=2"></outline>
+    <outline text="News" title="News">
+      <outline text="A &amp; B" title="A &amp; B" type="rss" xmlUrl="https://a.example.com/rss"></outline>
+    </outline>
+  </body>
+</opml>
+`
+	if got := b.String(); got != want {
+		t.Errorf("OPML is\n%s\nwant\n%s", got, want)
+	}
+
+	contents, err := parseOPML([]byte(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contents.feeds) != 2 || contents.feeds[1] != (opmlFeed{name: "A & B", url: "https://a.example.com/rss", folder: "News"}) {
+		t.Errorf("parsed feeds are %+v", contents.feeds)
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+type outputFormat string
+
+const (
+	outputPlain outputFormat = "plain"
+	outputTable outputFormat = "table"
+	outputJSON  outputFormat = "json"
+	outputCSV   outputFormat = "csv"
+
+	tableCellWidth = 60
+)
+
+func parseOutputFormat(value string) (outputFormat, error) {
+	switch format := outputFormat(value); format {
+	case outputPlain, outputTable, outputJSON, outputCSV:
+		return format, nil
+	}
+	return "", validationError("unknown output format %q, expected table, json, csv or plain", value)
+}
+
+// record is a row of listing output that can be rendered as a table or CSV
+// row and marshalled to JSON as-is.
+type record interface {
+	columns() []string
+	values() []string
+}
+
+type userRecord struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Current   bool      `json:"current"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (r userRecord) columns() []string {
+	return []string{"id", "name", "current", "created_at", "updated_at"}
+}
+
+func (r userRecord) values() []string {
+	return []string{r.ID.String(), r.Name, fmt.Sprint(r.Current), formatTime(r.CreatedAt), formatTime(r.UpdatedAt)}
+}
+
+type whoamiRecord struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Profile   string    `json:"profile"`
+	Follows   int       `json:"follows"`
+	Unread    int64     `json:"unread"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (r whoamiRecord) columns() []string {
+	return []string{"id", "name", "profile", "follows", "unread", "created_at"}
+}
+
+func (r whoamiRecord) values() []string {
+	return []string{r.ID.String(), r.Name, r.Profile, fmt.Sprint(r.Follows), fmt.Sprint(r.Unread), formatTime(r.CreatedAt)}
+}
+
+type feedRecord struct {
+	ID            uuid.UUID  `json:"id"`
+	Name          string     `json:"name"`
+	URL           string     `json:"url"`
+	User          string     `json:"user"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	LastFetchedAt *time.Time `json:"last_fetched_at"`
+}
+
+func (r feedRecord) columns() []string {
+	return []string{"id", "name", "url", "user", "created_at", "updated_at", "last_fetched_at"}
+}
+
+func (r feedRecord) values() []string {
+	lastFetched := ""
+	if r.LastFetchedAt != nil {
+		lastFetched = formatTime(*r.LastFetchedAt)
+	}
+	return []string{r.ID.String(), r.Name, r.URL, r.User, formatTime(r.CreatedAt), formatTime(r.UpdatedAt), lastFetched}
+}
+
+type feedInfoRecord struct {
+	ID             uuid.UUID  `json:"id"`
+	Name           string     `json:"name"`
+	URL            string     `json:"url"`
+	User           string     `json:"user"`
+	CreatedAt      time.Time  `json:"created_at"`
+	Followers      int64      `json:"followers"`
+	Posts          int64      `json:"posts"`
+	LastFetchedAt  *time.Time `json:"last_fetched_at"`
+	Health         string     `json:"health"`
+	LastFetchError string     `json:"last_fetch_error"`
+	FullContent    bool       `json:"full_content"`
+}
+
+func (r feedInfoRecord) columns() []string {
+	return []string{"id", "name", "url", "user", "created_at", "followers", "posts", "last_fetched_at", "health", "last_fetch_error", "full_content"}
+}
+
+func (r feedInfoRecord) values() []string {
+	lastFetched := ""
+	if r.LastFetchedAt != nil {
+		lastFetched = formatTime(*r.LastFetchedAt)
+	}
+	return []string{r.ID.String(), r.Name, r.URL, r.User, formatTime(r.CreatedAt), fmt.Sprint(r.Followers), fmt.Sprint(r.Posts), lastFetched, r.Health, r.LastFetchError, fmt.Sprint(r.FullContent)}
+}
+
+type followRecord struct {
+	ID        uuid.UUID `json:"id"`
+	FeedID    uuid.UUID `json:"feed_id"`
+	FeedName  string    `json:"feed_name"`
+	FeedURL   string    `json:"feed_url"`
+	User      string    `json:"user"`
+	Folder    string    `json:"folder"`
+	Unread    int64     `json:"unread"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (r followRecord) columns() []string {
+	return []string{"id", "feed_id", "feed_name", "feed_url", "user", "folder", "unread", "created_at"}
+}
+
+func (r followRecord) values() []string {
+	return []string{r.ID.String(), r.FeedID.String(), r.FeedName, r.FeedURL, r.User, r.Folder, fmt.Sprint(r.Unread), formatTime(r.CreatedAt)}
+}
+
+type folderRecord struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Feeds     int       `json:"feeds"`
+	Unread    int64     `json:"unread"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (r folderRecord) columns() []string {
+	return []string{"id", "name", "feeds", "unread", "created_at"}
+}
+
+func (r folderRecord) values() []string {
+	return []string{r.ID.String(), r.Name, fmt.Sprint(r.Feeds), fmt.Sprint(r.Unread), formatTime(r.CreatedAt)}
+}
+
+type postRecord struct {
+	ShortID     int64     `json:"short_id"`
+	ID          uuid.UUID `json:"id"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Description string    `json:"description"`
+	Summary     string    `json:"summary"`
+	PublishedAt time.Time `json:"published_at"`
+	FeedName    string    `json:"feed_name"`
+	FeedURL     string    `json:"feed_url"`
+	Read        bool      `json:"read"`
+	Starred     bool      `json:"starred"`
+}
+
+func (r postRecord) columns() []string {
+	return []string{"short_id", "id", "title", "url", "description", "summary", "published_at", "feed_name", "feed_url", "read", "starred"}
+}
+
+func (r postRecord) values() []string {
+	return []string{
+		fmt.Sprint(r.ShortID), r.ID.String(), r.Title, r.URL, r.Description, r.Summary, formatTime(r.PublishedAt),
+		r.FeedName, r.FeedURL, fmt.Sprint(r.Read), fmt.Sprint(r.Starred),
+	}
+}
+
+type postInfoRecord struct {
+	ShortID     int64     `json:"short_id"`
+	ID          uuid.UUID `json:"id"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	FeedName    string    `json:"feed_name"`
+	FeedURL     string    `json:"feed_url"`
+	Author      string    `json:"author"`
+	Categories  []string  `json:"categories"`
+	PublishedAt time.Time `json:"published_at"`
+	Read        bool      `json:"read"`
+	Starred     bool      `json:"starred"`
+	Tags        []string  `json:"tags"`
+	Text        string    `json:"text"`
+}
+
+func (r postInfoRecord) columns() []string {
+	return []string{"short_id", "id", "title", "url", "feed_name", "feed_url", "author", "categories", "published_at", "read", "starred", "tags", "text"}
+}
+
+func (r postInfoRecord) values() []string {
+	return []string{
+		fmt.Sprint(r.ShortID), r.ID.String(), r.Title, r.URL, r.FeedName, r.FeedURL, r.Author,
+		strings.Join(r.Categories, ", "), formatTime(r.PublishedAt), fmt.Sprint(r.Read), fmt.Sprint(r.Starred),
+		strings.Join(r.Tags, ", "), r.Text,
+	}
+}
+
+type tagRecord struct {
+	Tag   string `json:"tag"`
+	Posts int64  `json:"posts"`
+}
+
+func (r tagRecord) columns() []string {
+	return []string{"tag", "posts"}
+}
+
+func (r tagRecord) values() []string {
+	return []string{r.Tag, fmt.Sprint(r.Posts)}
+}
+
+type ruleRecord struct {
+	Number  int       `json:"number"`
+	ID      uuid.UUID `json:"id"`
+	Feed    string    `json:"feed"`
+	Field   string    `json:"field"`
+	Pattern string    `json:"pattern"`
+	Regex   bool      `json:"regex"`
+	Action  string    `json:"action"`
+	Tag     string    `json:"tag"`
+}
+
+func (r ruleRecord) columns() []string {
+	return []string{"number", "id", "feed", "field", "pattern", "regex", "action", "tag"}
+}
+
+func (r ruleRecord) values() []string {
+	return []string{fmt.Sprint(r.Number), r.ID.String(), r.Feed, r.Field, r.Pattern, fmt.Sprint(r.Regex), r.Action, r.Tag}
+}
+
+type settingRecord struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+func (r settingRecord) columns() []string {
+	return []string{"key", "value", "source"}
+}
+
+func (r settingRecord) values() []string {
+	return []string{r.Key, r.Value, r.Source}
+}
+
+// writeRecords renders records in one of the machine-readable formats. The
+// plain format is left to each command, which prints its own summary.
+func writeRecords[T record](w io.Writer, format outputFormat, records []T) error {
+	if records == nil {
+		records = []T{}
+	}
+
+	switch format {
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case outputCSV:
+		var zero T
+		cw := csv.NewWriter(w)
+		if err := cw.Write(zero.columns()); err != nil {
+			return err
+		}
+		for _, r := range records {
+			if err := cw.Write(r.values()); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case outputTable:
+		var zero T
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(zero.columns(), "\t")))
+		for _, r := range records {
+			values := r.values()
+			for i, v := range values {
+				values[i] = tableCell(v)
+			}
+			fmt.Fprintln(tw, strings.Join(values, "\t"))
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("output format %q is not supported here", format)
+}
+
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
+
+// tableCell keeps long values such as descriptions on one short line so
+// table columns stay aligned.
+func tableCell(v string) string {
+	v = strings.Join(strings.Fields(v), " ")
+	if utf8.RuneCountInString(v) > tableCellWidth {
+		return string([]rune(v)[:tableCellWidth-1]) + "…"
+	}
+	return v
+}
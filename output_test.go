@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseGlobalOptions(t *testing.T) {
+	for _, c := range []struct {
+		args   []string
+		output outputFormat
+		rest   string
+	}{
+		{[]string{"browse", "5"}, outputPlain, "browse 5"},
+		{[]string{"--output", "json", "browse"}, outputJSON, "browse"},
+		{[]string{"browse", "-o=csv", "5"}, outputCSV, "browse 5"},
+		{[]string{"-o", "table", "search", "--", "-o", "x"}, outputTable, "search -- -o x"},
+	} {
+		opts, rest, err := parseGlobalOptions(c.args)
+		if err != nil || opts.output != c.output || strings.Join(rest, " ") != c.rest {
+			t.Errorf("parseGlobalOptions(%q) = %v, %q, %v", c.args, opts.output, rest, err)
+		}
+	}
+	for _, args := range [][]string{{"-o", "xml", "browse"}, {"browse", "--output"}} {
+		_, _, err := parseGlobalOptions(args)
+		assertKind(t, err, errValidation)
+	}
+}
+
+// newOutputState returns a state where bob, the current user, follows the
+// example feed, fetched once, and alice, who added it, follows it too.
+func newOutputState(t *testing.T) (*state, *feedServer) {
+	t.Helper()
+
+	s, server := newStateWithFeed(t, "example.rss")
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "follow", server.feedURL("example.rss"))
+	return s, server
+}
+
+func TestOutputJSON(t *testing.T) {
+	s, server := newOutputState(t)
+	s.output = outputJSON
+
+	var users []userRecord
+	if err := json.Unmarshal([]byte(mustRun(t, s, "users")), &users); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].Name != "alice" || users[0].Current || !users[1].Current {
+		t.Errorf("users: %+v", users)
+	}
+
+	var posts []map[string]any
+	if err := json.Unmarshal([]byte(mustRun(t, s, "browse", "10")), &posts); err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 2 {
+		t.Fatalf("got %d posts, want 2: %+v", len(posts), posts)
+	}
+	first := posts[0]
+	for key, want := range map[string]any{
+		"title":     "Second post",
+		"url":       "https://example.com/posts/second",
+		"feed_name": "Example",
+		"feed_url":  server.feedURL("example.rss"),
+		"read":      false,
+	} {
+		if first[key] != want {
+			t.Errorf("post %v is %v, want %v", key, first[key], want)
+		}
+	}
+
+	// An empty listing is an empty array, not null.
+	mustRun(t, s, "register", "carol")
+	if out := mustRun(t, s, "browse"); strings.TrimSpace(out) != "[]" {
+		t.Errorf("browse without posts: %s", out)
+	}
+}
+
+func TestOutputCSV(t *testing.T) {
+	s, _ := newOutputState(t)
+	s.output = outputCSV
+
+	rows, err := csv.NewReader(strings.NewReader(mustRun(t, s, "browse", "10"))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want a header and 2 posts: %q", len(rows), rows)
+	}
+	header := rows[0]
+	if strings.Join(header, ",") != strings.Join(postRecord{}.columns(), ",") {
+		t.Errorf("header is %q", header)
+	}
+	column := map[string]int{}
+	for i, name := range header {
+		column[name] = i
+	}
+	// The description has a comma, which must be quoted.
+	if got := rows[1][column["description"]]; got != "Another post, a week later." {
+		t.Errorf("description is %q", got)
+	}
+	if got := rows[2][column["published_at"]]; got != "2025-01-06T09:00:00Z" {
+		t.Errorf("published_at is %q", got)
+	}
+}
+
+func TestOutputTable(t *testing.T) {
+	s, _ := newOutputState(t)
+	s.output = outputTable
+
+	lines := strings.Split(strings.TrimRight(mustRun(t, s, "users"), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want a header and 2 users:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	if fields := strings.Fields(lines[0]); strings.Join(fields, " ") != "ID NAME CURRENT CREATED_AT UPDATED_AT" {
+		t.Errorf("header is %q", lines[0])
+	}
+	// Columns are aligned: every name starts where the NAME header does.
+	at := strings.Index(lines[0], "NAME")
+	for _, line := range lines[1:] {
+		if !strings.HasPrefix(line[at:], "alice") && !strings.HasPrefix(line[at:], "bob") {
+			t.Errorf("user line is not aligned with the header:\n%s\n%s", lines[0], line)
+		}
+	}
+
+	if got := tableCell("a\nlong   description " + strings.Repeat("x", tableCellWidth)); len([]rune(got)) != tableCellWidth || !strings.HasPrefix(got, "a long description x") {
+		t.Errorf("tableCell returned %q", got)
+	}
+}
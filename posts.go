@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/alifoo/blog-aggregator/internal/htmltext"
+	"github.com/google/uuid"
+)
+
+const defaultSearchItems = 20
+
+func handlerSearch(s *state, cmd command, user database.User) error {
+	query := strings.TrimSpace(strings.Join(cmd.arguments, " "))
+	if query == "" {
+		return validationError("search query is empty")
+	}
+	limit := cmd.intFlag("limit")
+	if limit < 1 {
+		return validationError("invalid limit value: %v", limit)
+	}
+
+	rows, err := s.db.SearchPosts(context.Background(), database.SearchPostsParams{
+		UserID:   user.ID,
+		Query:    query,
+		MaxItems: int32(limit),
+	})
+	if err != nil {
+		return databaseError("error searching posts: %w", err)
+	}
+	if len(rows) == 0 && s.output == outputPlain {
+		fmt.Printf("No posts match %q.\n", query)
+		return nil
+	}
+
+	posts := make([]database.GetReaderItemsRow, len(rows))
+	for i, row := range rows {
+		posts[i] = database.GetReaderItemsRow(row)
+	}
+	return writePosts(s, fmt.Sprintf("Posts matching %q:", query), posts)
+}
+
+// writePosts prints posts in the output format chosen by the user. The
+// plain format shows each title, after the short id other commands accept
+// for the post, followed by its summary.
+func writePosts(s *state, header string, posts []database.GetReaderItemsRow) error {
+	if s.output != outputPlain {
+		var records []postRecord
+		for _, p := range posts {
+			records = append(records, postRecord{
+				ShortID:     p.Seq,
+				ID:          p.ID,
+				Title:       p.Title,
+				URL:         p.Url,
+				Description: p.Description.String,
+				Summary:     postSummary(p),
+				PublishedAt: p.PublishedAt,
+				FeedName:    p.FeedName,
+				FeedURL:     p.FeedUrl,
+				Read:        p.Read,
+				Starred:     p.Starred,
+			})
+		}
+		return writeRecords(os.Stdout, s.output, records)
+	}
+
+	fmt.Println(header)
+	for _, p := range posts {
+		fmt.Printf("[%d] %v\n", p.Seq, p.Title)
+		if summary := postSummary(p); summary != "" {
+			fmt.Printf("  %v\n", summary)
+		}
+	}
+	return nil
+}
+
+// postSummary returns the plain-text summary of a post. Posts stored before
+// summaries were added have one made from their description.
+func postSummary(p database.GetReaderItemsRow) string {
+	if p.Summary.Valid {
+		return p.Summary.String
+	}
+	return htmltext.Summary(p.Description.String)
+}
+
+// handlerShow prints everything known about a post: where it comes from,
+// whether the current user read, starred or tagged it, and its text.
+func handlerShow(s *state, cmd command, user database.User) error {
+	item, err := getFollowedPost(s, user, cmd.arguments[0])
+	if err != nil {
+		return err
+	}
+	tags, err := s.db.GetPostTags(context.Background(), database.GetPostTagsParams{UserID: user.ID, PostID: item.ID})
+	if err != nil {
+		return databaseError("error getting tags: %w", err)
+	}
+
+	// Feeds may hold terminal escape sequences, which are stripped like in
+	// the tui before anything reaches the terminal.
+	var categories []string
+	for _, c := range splitCategories(item.Categories) {
+		categories = append(categories, htmltext.StripControl(c))
+	}
+	for i := range tags {
+		tags[i] = htmltext.StripControl(tags[i])
+	}
+	record := postInfoRecord{
+		ShortID:     item.Seq,
+		ID:          item.ID,
+		Title:       htmltext.StripControl(item.Title),
+		URL:         htmltext.StripControl(item.Url),
+		FeedName:    htmltext.StripControl(item.FeedName),
+		FeedURL:     htmltext.StripControl(item.FeedUrl),
+		Author:      htmltext.StripControl(item.Author.String),
+		Categories:  categories,
+		PublishedAt: item.PublishedAt,
+		Read:        item.Read,
+		Starred:     item.Starred,
+		Tags:        tags,
+		Text:        htmltext.StripControl(htmltext.Render(postBody(item))),
+	}
+	if s.output != outputPlain {
+		return writeRecords(os.Stdout, s.output, []postInfoRecord{record})
+	}
+
+	state := "unread"
+	if record.Read {
+		state = "read"
+	}
+	if record.Starred {
+		state += ", starred"
+	}
+	fmt.Printf("[%d] %v\n", record.ShortID, record.Title)
+	fmt.Printf("URL:        %v\n", record.URL)
+	fmt.Printf("Feed:       %v (%v)\n", record.FeedName, record.FeedURL)
+	fmt.Printf("Published:  %v\n", record.PublishedAt.Format("2006-01-02 15:04"))
+	if record.Author != "" {
+		fmt.Printf("Author:     %v\n", record.Author)
+	}
+	if len(record.Categories) > 0 {
+		fmt.Printf("Categories: %v\n", strings.Join(record.Categories, ", "))
+	}
+	fmt.Printf("State:      %v\n", state)
+	if len(record.Tags) > 0 {
+		fmt.Printf("Tags:       %v\n", strings.Join(record.Tags, ", "))
+	}
+	if record.Text != "" {
+		fmt.Printf("\n%v\n", record.Text)
+	}
+	return nil
+}
+
+func handlerOpen(s *state, cmd command, user database.User) error {
+	post, err := getFollowedPost(s, user, cmd.arguments[0])
+	if err != nil {
+		return err
+	}
+	if err := openBrowser(post.Url); err != nil {
+		return fmt.Errorf("error opening browser: %w", err)
+	}
+	fmt.Printf("Opened %v\n", post.Url)
+	return nil
+}
+
+// getPost returns the post ref refers to: its short id as shown by browse,
+// its id or its url.
+func getPost(s *state, ref string) (database.Post, error) {
+	var post database.Post
+	var err error
+	if seq, parseErr := strconv.ParseInt(ref, 10, 64); parseErr == nil {
+		post, err = s.db.GetPostBySeq(context.Background(), seq)
+	} else if id, parseErr := uuid.Parse(ref); parseErr == nil {
+		post, err = s.db.GetPost(context.Background(), id)
+	} else {
+		post, err = s.db.GetPostByURL(context.Background(), ref)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Post{}, notFoundError("no post %v, see '%s browse' for post ids", ref, programName())
+	}
+	if err != nil {
+		return database.Post{}, databaseError("error getting post: %w", err)
+	}
+	return post, nil
+}
+
+// getFollowedPost resolves ref like getPost, but only to posts of feeds
+// user follows, with the user's read and starred state.
+func getFollowedPost(s *state, user database.User, ref string) (database.GetReaderItemsRow, error) {
+	post, err := getPost(s, ref)
+	if err != nil {
+		return database.GetReaderItemsRow{}, err
+	}
+	items, err := s.db.GetReaderItemsBySeq(context.Background(), database.GetReaderItemsBySeqParams{
+		UserID: user.ID,
+		Seqs:   []int64{post.Seq},
+	})
+	if err != nil {
+		return database.GetReaderItemsRow{}, databaseError("error getting post: %w", err)
+	}
+	if len(items) == 0 {
+		return database.GetReaderItemsRow{}, notFoundError("post %v is not from a feed you follow", ref)
+	}
+	return database.GetReaderItemsRow(items[0]), nil
+}
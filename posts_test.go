@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestScrapeSanitizesDescriptions(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "HTML", server.feedURL("html.rss"))
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+
+	user, err := s.db.GetUser(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := s.db.GetReaderItems(context.Background(), database.GetReaderItemsParams{UserID: user.ID, MaxItems: 10})
+	if err != nil || len(items) != 1 {
+		t.Fatalf("reader items: %+v, %v", items, err)
+	}
+
+	// The item link is relative to the feed, and the links in the
+	// description are relative to the item.
+	wantHTML := `<p>Read <a href="` + server.feedURL("about") + `">about us</a> and <a>this</a>.</p>` +
+		`<img src="` + server.feedURL("posts/images/cat.png") + `" alt="A cat">`
+	if got := items[0].Description.String; got != wantHTML {
+		t.Errorf("description is\n%q\nwant\n%q", got, wantHTML)
+	}
+	if got, want := items[0].Summary.String, "Read about us and this."; got != want {
+		t.Errorf("summary is %q, want %q", got, want)
+	}
+
+	out := mustRun(t, s, "browse")
+	if !strings.Contains(out, "Styled post\n  Read about us and this.\n") {
+		t.Errorf("browse does not show the summary:\n%s", out)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", server.feedURL("example.rss"))
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+
+	out := mustRun(t, s, "search", "week", "LATER")
+	if !strings.Contains(out, "Second post") || strings.Contains(out, "First post") {
+		t.Errorf("search by summary:\n%s", out)
+	}
+	out = mustRun(t, s, "search", "--limit", "1", "post")
+	if strings.Count(out, " post\n") != 1 {
+		t.Errorf("search --limit 1:\n%s", out)
+	}
+	out = mustRun(t, s, "search", "nothing")
+	if !strings.Contains(out, `No posts match "nothing"`) {
+		t.Errorf("search without results:\n%s", out)
+	}
+
+	_, err := runCommand(t, s, "search", "--limit", "0", "post")
+	assertKind(t, err, errValidation)
+}
+
+func TestShortIDs(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", server.feedURL("example.rss"))
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+	second := readerItems(t, s, "alice")["Second post"]
+	id := fmt.Sprint(second.Seq)
+
+	out := mustRun(t, s, "browse")
+	if !strings.Contains(out, "["+id+"] Second post\n") {
+		t.Errorf("browse does not show the short id %v:\n%s", id, out)
+	}
+	out = mustRun(t, s, "search", "week")
+	if !strings.Contains(out, "["+id+"] Second post\n") {
+		t.Errorf("search does not show the short id %v:\n%s", id, out)
+	}
+
+	// Every post command accepts the short id, the id and the url.
+	mustRun(t, s, "tag", id, "to-discuss")
+	mustRun(t, s, "tag", second.ID.String(), "go")
+	mustRun(t, s, "tag", second.Url, "later")
+	if out := mustRun(t, s, "tags", id); out != "Second post: go, later, to-discuss\n" {
+		t.Errorf("tags of the post:\n%s", out)
+	}
+	mustRun(t, s, "untag", id, "later")
+
+	out = mustRun(t, s, "show", id)
+	want := "[" + id + "] Second post\n" +
+		"URL:        https://example.com/posts/second\n" +
+		"Feed:       Example (" + server.feedURL("example.rss") + ")\n" +
+		"Published:  2025-01-13 09:00\n" +
+		"State:      unread\n" +
+		"Tags:       go, to-discuss\n" +
+		"\nAnother post, a week later.\n"
+	if out != want {
+		t.Errorf("show:\n%s\nwant:\n%s", out, want)
+	}
+
+	t.Setenv("BROWSER", "true")
+	if out := mustRun(t, s, "open", id); out != "Opened https://example.com/posts/second\n" {
+		t.Errorf("open:\n%s", out)
+	}
+
+	_, err := runCommand(t, s, "show", "99")
+	assertKind(t, err, errNotFound)
+	_, err = runCommand(t, s, "open", "99")
+	assertKind(t, err, errNotFound)
+
+	// show and open need the post to be from a feed the user follows.
+	mustRun(t, s, "register", "bob")
+	_, err = runCommand(t, s, "show", id)
+	assertKind(t, err, errNotFound)
+	_, err = runCommand(t, s, "open", id)
+	assertKind(t, err, errNotFound)
+
+	// A link that is not http or https is not opened.
+	evil := "javascript:alert(1)"
+	now := time.Now()
+	feed, err := s.db.GetFeedByURL(context.Background(), server.feedURL("example.rss"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	post, err := s.db.CreatePost(context.Background(), database.CreatePostParams{
+		ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Title: "Evil", Url: evil,
+		PublishedAt: now, FeedID: uuid.NullUUID{UUID: feed.ID, Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustRun(t, s, "login", "alice")
+	_, err = runCommand(t, s, "open", fmt.Sprint(post.Seq))
+	assertKind(t, err, errValidation)
+}
+
+// TestFeedEscapesAreStripped checks escape sequences hidden in a feed, here
+// as entities decoded on the way in, are dropped before posts are stored,
+// so no listing writes them to the terminal.
+func TestFeedEscapesAreStripped(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Escapes", server.feedURL("escapes.rss"))
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+	evil, ok := readerItems(t, s, "alice")["Evil ]0;pwned title"]
+	if !ok {
+		t.Fatalf("no post titled without its escapes: %v", readerItems(t, s, "alice"))
+	}
+	if evil.Summary.String != "Now [31mred[0m and ›2J gone." || evil.Author.String != "Mallory 2J" || evil.Categories.String != "news2J" {
+		t.Errorf("stored post: summary %q, author %q, categories %q", evil.Summary.String, evil.Author.String, evil.Categories.String)
+	}
+	id := fmt.Sprint(evil.Seq)
+
+	for _, format := range []outputFormat{outputPlain, outputJSON, outputCSV, outputTable} {
+		s.output = format
+		for _, args := range [][]string{{"browse"}, {"search", "evil"}, {"show", id}} {
+			out := mustRun(t, s, args...)
+			if strings.ContainsAny(out, "\x1b\a\u009b") || strings.Contains(out, `\u001b`) || strings.Contains(out, `\u0007`) {
+				t.Errorf("%v --output %v prints control characters: %q", args[0], format, out)
+			}
+			if !strings.Contains(out, "Evil ]0;pwned title") {
+				t.Errorf("%v --output %v does not list the post:\n%s", args[0], format, out)
+			}
+		}
+	}
+
+	s.output = outputPlain
+	out := mustRun(t, s, "show", id)
+	for _, want := range []string{"] Evil ]0;pwned title\n", "Author:     Mallory 2J\n", "Categories: news2J\n", "Now [31mred[0m and"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("show does not print %q:\n%s", want, out)
+		}
+	}
+}
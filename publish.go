@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/alifoo/blog-aggregator/internal/htmltext"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPublishItems = 200
+	dayLayout           = "2006-01-02"
+)
+
+//go:embed templates/planet
+var planetFiles embed.FS
+
+var planetTemplate = template.Must(template.ParseFS(planetFiles, "templates/planet/page.html"))
+
+type planetFeed struct {
+	ID   uuid.UUID
+	Name string
+	URL  string
+}
+
+type planetSection struct {
+	Heading string
+	Posts   []planetPost
+}
+
+// planetPost is a post as the page shows it. Description only ever holds
+// the output of htmltext.Sanitize, so the template can insert it as HTML.
+type planetPost struct {
+	database.GetReaderItemsRow
+	Description template.HTML
+}
+
+func newPlanetPost(p database.GetReaderItemsRow) planetPost {
+	return planetPost{
+		GetReaderItemsRow: p,
+		Description:       template.HTML(htmltext.Sanitize(p.Description.String, p.Url)),
+	}
+}
+
+type planetPage struct {
+	SiteTitle string
+	Title     string
+	Root      string
+	Generated time.Time
+	Sections  []planetSection
+	Feeds     []planetFeed
+	Days      []string
+}
+
+func handlerPublish(s *state, cmd command, user database.User) error {
+	outDir := cmd.arguments[0]
+
+	limit := cmd.intFlag("limit")
+	if limit < 1 {
+		return validationError("invalid limit value: %v", limit)
+	}
+
+	posts, err := getRiverPosts(context.Background(), s, user, riverOptions{limit: int32(limit)})
+	if err != nil {
+		return err
+	}
+
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return databaseError("error getting feed follows for current user: %w", err)
+	}
+
+	pages := buildPlanet(user, follows, posts, time.Now())
+	for path, page := range pages {
+		if err := writePlanetPage(filepath.Join(outDir, path), page); err != nil {
+			return err
+		}
+	}
+
+	css, err := planetFiles.ReadFile("templates/planet/style.css")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "style.css"), css, 0644); err != nil {
+		return fmt.Errorf("error writing stylesheet: %w", err)
+	}
+
+	fmt.Printf("Published %d posts from %d feeds to %v (%d pages).\n", len(posts), len(follows), outDir, len(pages))
+	return nil
+}
+
+// buildPlanet lays out the site as a map of relative output paths to pages:
+// the index, one page per followed feed and one archive page per day.
+func buildPlanet(user database.User, follows []database.GetFeedFollowsForUserRow, posts []database.GetReaderItemsRow, generated time.Time) map[string]planetPage {
+	siteTitle := fmt.Sprintf("%v's planet", user.Name)
+
+	var feeds []planetFeed
+	for _, f := range follows {
+		feeds = append(feeds, planetFeed{ID: f.FeedID, Name: f.FeedName, URL: f.FeedUrl})
+	}
+	sort.Slice(feeds, func(i, j int) bool { return feeds[i].Name < feeds[j].Name })
+
+	byDay := map[string][]planetPost{}
+	byFeed := map[uuid.UUID][]planetPost{}
+	var days []string
+	for _, p := range posts {
+		day := p.PublishedAt.Format(dayLayout)
+		if _, exists := byDay[day]; !exists {
+			days = append(days, day)
+		}
+		post := newPlanetPost(p)
+		byDay[day] = append(byDay[day], post)
+		byFeed[p.FeedID.UUID] = append(byFeed[p.FeedID.UUID], post)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(days)))
+
+	newPage := func(title, root string, sections []planetSection) planetPage {
+		return planetPage{
+			SiteTitle: siteTitle,
+			Title:     title,
+			Root:      root,
+			Generated: generated,
+			Sections:  sections,
+			Feeds:     feeds,
+			Days:      days,
+		}
+	}
+
+	var indexSections []planetSection
+	for _, day := range days {
+		indexSections = append(indexSections, planetSection{Heading: day, Posts: byDay[day]})
+	}
+
+	pages := map[string]planetPage{
+		"index.html": newPage("Latest posts", "", indexSections),
+	}
+	for _, f := range feeds {
+		sections := []planetSection{{Heading: "Posts", Posts: byFeed[f.ID]}}
+		pages[filepath.Join("feeds", f.ID.String()+".html")] = newPage(f.Name, "../", sections)
+	}
+	for _, day := range days {
+		sections := []planetSection{{Heading: day, Posts: byDay[day]}}
+		pages[filepath.Join("archive", day+".html")] = newPage("Archive for "+day, "../", sections)
+	}
+
+	return pages
+}
+
+func writePlanetPage(path string, page planetPage) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating directory for %v: %w", path, err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating %v: %w", path, err)
+	}
+	defer f.Close()
+
+	if err := planetTemplate.Execute(f, page); err != nil {
+		return fmt.Errorf("error rendering %v: %w", path, err)
+	}
+	return f.Close()
+}
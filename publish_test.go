@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestPublish(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "HTML", server.feedURL("html.rss"))
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+	feed, err := s.db.GetFeedByURL(context.Background(), server.feedURL("html.rss"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Posts stored before descriptions were sanitized on the way in are
+	// sanitized when the page is rendered.
+	_, err = s.db.CreatePost(context.Background(), database.CreatePostParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		Title:       "Old <post>",
+		Url:         "https://html.example.com/posts/old",
+		Description: sql.NullString{String: `<p onclick="steal()">Kept</p><script>steal()</script>`, Valid: true},
+		PublishedAt: time.Date(2025, 1, 5, 9, 0, 0, 0, time.UTC),
+		FeedID:      uuid.NullUUID{UUID: feed.ID, Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	mustRun(t, s, "publish", dir)
+	read := func(path string) string {
+		t.Helper()
+		b, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	index := read("index.html")
+	for _, want := range []string{
+		`<title>Latest posts - alice&#39;s planet</title>`,
+		`<h4><a href="` + server.feedURL("posts/styled") + `">Styled post</a></h4>`,
+		`<div class="description"><p>Read <a href="` + server.feedURL("about") + `">about us</a> and <a>this</a>.</p>` +
+			`<img src="` + server.feedURL("posts/images/cat.png") + `" alt="A cat"></div>`,
+		`<h4><a href="https://html.example.com/posts/old">Old &lt;post&gt;</a></h4>`,
+		`<div class="description"><p>Kept</p></div>`,
+		`<a href="archive/2025-01-06.html">2025-01-06</a>`,
+		`<a href="feeds/` + feed.ID.String() + `.html">HTML</a>`,
+	} {
+		if !strings.Contains(index, want) {
+			t.Errorf("index.html does not contain %s:\n%s", want, index)
+		}
+	}
+	for _, unwanted := range []string{"&lt;p&gt;", "<script", "onclick", "javascript:"} {
+		if strings.Contains(index, unwanted) {
+			t.Errorf("index.html contains %s:\n%s", unwanted, index)
+		}
+	}
+
+	archive := read(filepath.Join("archive", "2025-01-05.html"))
+	if !strings.Contains(archive, "Old &lt;post&gt;") || strings.Contains(archive, "Styled post") {
+		t.Errorf("archive page of 2025-01-05:\n%s", archive)
+	}
+	feedPage := read(filepath.Join("feeds", feed.ID.String()+".html"))
+	if !strings.Contains(feedPage, `<link rel="stylesheet" href="../style.css">`) || !strings.Contains(feedPage, "Styled post") {
+		t.Errorf("feed page:\n%s", feedPage)
+	}
+	if css := read("style.css"); !strings.Contains(css, ".description") {
+		t.Errorf("style.css:\n%s", css)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// resetPlan describes what a reset deletes, so it can be summarised before
+// anything is touched.
+type resetPlan struct {
+	target string
+	counts []resetCount
+	run    func(ctx context.Context) error
+}
+
+type resetCount struct {
+	n    int64
+	noun string
+}
+
+func handlerReset(s *state, cmd command) error {
+	plan, err := planReset(s, cmd.arguments)
+	if err != nil {
+		return err
+	}
+	return runReset(s, cmd, plan)
+}
+
+// runReset prints what plan deletes and, unless it is a dry run, deletes it
+// once confirmed. The command must have the --yes and --dry-run flags.
+func runReset(s *state, cmd command, plan resetPlan) error {
+	if plan.empty() {
+		fmt.Printf("Nothing to delete for %v.\n", plan.target)
+		return nil
+	}
+	if cmd.boolFlag("dry-run") {
+		fmt.Printf("Removing %v would delete %v.\n", plan.target, plan.summary())
+		return nil
+	}
+
+	if !cmd.boolFlag("yes") {
+		ok, err := confirm(bufio.NewReader(os.Stdin), fmt.Sprintf("Remove %v, deleting %v?", plan.target, plan.summary()), false)
+		if errors.Is(err, errNoInput) {
+			return validationError("nothing was deleted, pass --yes to go ahead without a prompt")
+		}
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Cancelled, nothing was deleted.")
+			return nil
+		}
+	}
+
+	if err := plan.run(context.Background()); err != nil {
+		return databaseError("error removing %v: %w", plan.target, err)
+	}
+	fmt.Printf("Deleted %v.\n", plan.summary())
+	return forgetDeletedUser(s)
+}
+
+// setResetFlags declares the flags used by runReset.
+func setResetFlags(fs *flag.FlagSet) {
+	fs.Bool("yes", false, "delete without asking for confirmation")
+	fs.Bool("dry-run", false, "only show what would be deleted")
+}
+
+// planReset works out the scope of a reset from its arguments: everything,
+// only posts, only feeds or a single user.
+func planReset(s *state, args []string) (resetPlan, error) {
+	ctx := context.Background()
+
+	scope := "all"
+	if len(args) > 0 {
+		scope = args[0]
+	}
+	wantArgs := 1
+	if scope == "user" {
+		wantArgs = 2
+	}
+	if len(args) > wantArgs || (scope == "user" && len(args) < wantArgs) {
+		return resetPlan{}, validationError("wrong number of arguments\nusage: %s reset [all|posts|feeds|user <name>]", programName())
+	}
+
+	var userID uuid.NullUUID
+	plan := resetPlan{}
+	switch scope {
+	case "all":
+		plan.target = "all users"
+		plan.run = s.db.DeleteAllUsers
+	case "feeds":
+		plan.target = "all feeds"
+		plan.run = s.db.DeleteAllFeeds
+	case "posts":
+		plan.target = "all posts"
+		plan.run = s.db.DeleteAllPosts
+	case "user":
+		name := args[1]
+		user, err := s.db.GetUser(ctx, name)
+		if errors.Is(err, sql.ErrNoRows) {
+			return resetPlan{}, notFoundError("user %v does not exist", name)
+		}
+		if err != nil {
+			return resetPlan{}, databaseError("error getting user %v: %w", name, err)
+		}
+		userID = uuid.NullUUID{UUID: user.ID, Valid: true}
+		plan.target = "user " + name
+		plan.run = func(ctx context.Context) error {
+			return s.db.DeleteUser(ctx, user.ID)
+		}
+	default:
+		return resetPlan{}, validationError("unknown reset scope %v, expected all, posts, feeds or user", scope)
+	}
+
+	counts, err := s.db.GetResetCounts(ctx, userID)
+	if err != nil {
+		return resetPlan{}, databaseError("error counting what to delete: %w", err)
+	}
+	switch scope {
+	case "all", "user":
+		plan.counts = append(plan.counts, resetCount{counts.Users, "user"})
+		fallthrough
+	case "feeds":
+		plan.counts = append(plan.counts,
+			resetCount{counts.Feeds, "feed"},
+			resetCount{counts.FeedFollows, "follow"},
+		)
+		fallthrough
+	case "posts":
+		plan.counts = append(plan.counts, resetCount{counts.Posts, "post"})
+	}
+	return plan, nil
+}
+
+func (p resetPlan) empty() bool {
+	for _, c := range p.counts {
+		if c.n > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// summary lists the counts, e.g. "1 user, 2 feeds, 3 follows and 0 posts".
+func (p resetPlan) summary() string {
+	var parts []string
+	for _, c := range p.counts {
+		noun := c.noun
+		if c.n != 1 {
+			noun += "s"
+		}
+		parts = append(parts, fmt.Sprintf("%d %v", c.n, noun))
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1]
+}
+
+func completeReset(s *state, position int) []string {
+	switch position {
+	case 0:
+		return []string{"all", "posts", "feeds", "user"}
+	case 1:
+		return completeUsernames(s, 0)
+	}
+	return nil
+}
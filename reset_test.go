@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+// withStdin makes input the content of stdin for the rest of the test.
+func withStdin(t *testing.T, input string) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString(input)
+	w.Close()
+
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		r.Close()
+	})
+}
+
+// newResetState returns a state with two users following a feed that has
+// two posts.
+func newResetState(t *testing.T) *state {
+	t.Helper()
+
+	s, server := newStateWithFeed(t, "example.rss")
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "follow", server.feedURL("example.rss"))
+	return s
+}
+
+func resetCounts(t *testing.T, s *state) database.GetResetCountsRow {
+	t.Helper()
+
+	counts, err := s.db.GetResetCounts(context.Background(), uuid.NullUUID{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return counts
+}
+
+func TestResetDryRun(t *testing.T) {
+	s := newResetState(t)
+	before := resetCounts(t, s)
+
+	out := mustRun(t, s, "reset", "--dry-run")
+	if want := "would delete 2 users, 1 feed, 2 follows and 2 posts"; !strings.Contains(out, want) {
+		t.Errorf("dry run output %q does not contain %q", out, want)
+	}
+	out = mustRun(t, s, "reset", "user", "alice", "--dry-run")
+	if want := "would delete 1 user, 1 feed, 2 follows and 2 posts"; !strings.Contains(out, want) {
+		t.Errorf("dry run output %q does not contain %q", out, want)
+	}
+
+	if after := resetCounts(t, s); after != before {
+		t.Fatalf("dry run changed the database from %+v to %+v", before, after)
+	}
+}
+
+func TestResetAsksForConfirmation(t *testing.T) {
+	s := newResetState(t)
+	before := resetCounts(t, s)
+
+	withStdin(t, "n\n")
+	out := mustRun(t, s, "reset")
+	if !strings.Contains(out, "Cancelled") {
+		t.Errorf("declining did not cancel the reset:\n%s", out)
+	}
+
+	withStdin(t, "")
+	_, err := runCommand(t, s, "reset")
+	assertKind(t, err, errValidation)
+
+	if after := resetCounts(t, s); after != before {
+		t.Fatalf("cancelled reset changed the database from %+v to %+v", before, after)
+	}
+
+	withStdin(t, "y\n")
+	mustRun(t, s, "reset", "posts")
+	if counts := resetCounts(t, s); counts.Posts != 0 || counts.Feeds != 1 {
+		t.Fatalf("counts after resetting posts: %+v", counts)
+	}
+}
+
+func TestResetScopes(t *testing.T) {
+	s := newResetState(t)
+
+	mustRun(t, s, "reset", "feeds", "--yes")
+	want := database.GetResetCountsRow{Users: 2}
+	if counts := resetCounts(t, s); counts != want {
+		t.Fatalf("counts after resetting feeds are %+v, want %+v", counts, want)
+	}
+
+	mustRun(t, s, "reset", "user", "alice", "--yes")
+	if _, err := s.db.GetUser(context.Background(), "alice"); err == nil {
+		t.Fatal("alice still exists after reset user alice")
+	}
+	if _, err := s.db.GetUser(context.Background(), "bob"); err != nil {
+		t.Fatalf("bob was deleted along with alice: %v", err)
+	}
+
+	_, err := runCommand(t, s, "reset", "user", "alice", "--yes")
+	assertKind(t, err, errNotFound)
+	_, err = runCommand(t, s, "reset", "everything")
+	assertKind(t, err, errValidation)
+	_, err = runCommand(t, s, "reset", "user")
+	assertKind(t, err, errValidation)
+
+	mustRun(t, s, "reset", "--yes")
+	if counts := resetCounts(t, s); counts != (database.GetResetCountsRow{}) {
+		t.Fatalf("counts after a full reset are %+v", counts)
+	}
+}
+
+// TestResetDeletesKeptPosts checks posts kept by deletefeed --keep-posts
+// are deleted by the resets that count them.
+func TestResetDeletesKeptPosts(t *testing.T) {
+	for _, scope := range []string{"all", "feeds"} {
+		t.Run(scope, func(t *testing.T) {
+			s, server := newStateWithFeed(t, "example.rss")
+			mustRun(t, s, "deletefeed", "--keep-posts", "--yes", server.feedURL("example.rss"))
+
+			out := mustRun(t, s, "reset", scope, "--yes")
+			if !strings.Contains(out, "0 follows and 2 posts") {
+				t.Errorf("reset %v printed %q", scope, out)
+			}
+			for _, url := range []string{"https://example.com/posts/first", "https://example.com/posts/second"} {
+				if _, err := s.db.GetPostByURL(context.Background(), url); !errors.Is(err, sql.ErrNoRows) {
+					t.Errorf("post %v after reset %v returned %v, want sql.ErrNoRows", url, scope, err)
+				}
+			}
+		})
+	}
+}
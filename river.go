@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+const defaultRiverItems = 20
+
+type AtomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  AtomPerson  `xml:"author"`
+	Link    []AtomLink  `xml:"link"`
+	Entry   []AtomEntry `xml:"entry"`
+}
+
+type AtomPerson struct {
+	Name string `xml:"name"`
+}
+
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type AtomEntry struct {
+	ID        string         `xml:"id"`
+	Title     string         `xml:"title"`
+	Updated   string         `xml:"updated"`
+	Published string         `xml:"published"`
+	Link      []AtomLink     `xml:"link"`
+	Summary   AtomText       `xml:"summary"`
+	Content   *AtomText      `xml:"content"`
+	Author    *AtomPerson    `xml:"author,omitempty"`
+	Category  []AtomCategory `xml:"category,omitempty"`
+	Source    AtomSource     `xml:"source"`
+}
+
+type AtomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type AtomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type AtomSource struct {
+	Title string     `xml:"title"`
+	Link  []AtomLink `xml:"link"`
+}
+
+// riverOptions selects which of a user's followed posts go into a river:
+// all of them, or those of one feed, one folder or one tag.
+type riverOptions struct {
+	limit    int32
+	feedID   uuid.NullUUID
+	folderID uuid.NullUUID
+	tag      sql.NullString
+}
+
+func handlerRiver(s *state, cmd command, user database.User) error {
+	format := cmd.arguments[0]
+
+	limit := cmd.intFlag("limit")
+	if limit < 1 {
+		return validationError("invalid limit value: %v", limit)
+	}
+	opts := riverOptions{limit: int32(limit)}
+	if feedURL := cmd.stringFlag("feed"); feedURL != "" {
+		feed, err := s.db.GetFeedByURL(context.Background(), feedURL)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return notFoundError("no feed with url %v", feedURL)
+			}
+			return databaseError("error getting feed by url: %w", err)
+		}
+		opts.feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	if name := cmd.stringFlag("folder"); name != "" {
+		folder, err := getFolder(s, user, name)
+		if err != nil {
+			return err
+		}
+		opts.folderID = uuid.NullUUID{UUID: folder.ID, Valid: true}
+	}
+	if tag := strings.TrimSpace(cmd.stringFlag("tag")); tag != "" {
+		opts.tag = sql.NullString{String: tag, Valid: true}
+	}
+
+	posts, err := getRiverPosts(context.Background(), s, user, opts)
+	if err != nil {
+		return err
+	}
+
+	// RSS needs a link for the river itself, so without --url it links to
+	// where serve publishes the river by default.
+	link := cmd.stringFlag("url")
+	if link == "" {
+		link = fmt.Sprintf("http://localhost%s/river/%s/%s", defaultServeAddr, url.PathEscape(user.Name), format)
+	}
+	return writeRiver(os.Stdout, format, user, posts, link)
+}
+
+// handleRiverRequest serves a user's river to that user only: like the
+// Google Reader API, it needs their API token, which feed readers can send
+// as the password of HTTP basic auth or in a token parameter.
+func handleRiverRequest(s *state) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := riverUser(s, r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="gator"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		opts := riverOptions{limit: defaultRiverItems}
+		if n := r.FormValue("n"); n != "" {
+			parsedLimit, err := strconv.Atoi(n)
+			if err != nil || parsedLimit < 1 {
+				http.Error(w, "invalid item count", http.StatusBadRequest)
+				return
+			}
+			opts.limit = int32(min(parsedLimit, greaderMaxItems))
+		}
+		if feedURL := r.FormValue("feed"); feedURL != "" {
+			feed, err := s.db.GetFeedByURL(r.Context(), feedURL)
+			if err != nil {
+				http.Error(w, "unknown feed", http.StatusBadRequest)
+				return
+			}
+			opts.feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+		}
+		if name := r.FormValue("folder"); name != "" {
+			folder, err := s.db.GetFolderByName(r.Context(), database.GetFolderByNameParams{UserID: user.ID, Name: name})
+			if err != nil {
+				http.Error(w, "unknown folder", http.StatusBadRequest)
+				return
+			}
+			opts.folderID = uuid.NullUUID{UUID: folder.ID, Valid: true}
+		}
+		if tag := strings.TrimSpace(r.FormValue("tag")); tag != "" {
+			opts.tag = sql.NullString{String: tag, Valid: true}
+		}
+
+		posts, err := getRiverPosts(r.Context(), s, user, opts)
+		if err != nil {
+			http.Error(w, "error getting posts", http.StatusInternalServerError)
+			return
+		}
+
+		format := r.PathValue("format")
+		switch format {
+		case "rss":
+			w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		case "atom":
+			w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		// The river links to the address it was requested at, without the
+		// token.
+		query := r.URL.Query()
+		query.Del("token")
+		self := url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path, RawQuery: query.Encode()}
+		selfURL := self.String()
+		if err := writeRiver(w, format, user, posts, selfURL); err != nil {
+			fmt.Printf("error writing river: %v\n", err)
+		}
+	}
+}
+
+// riverUser returns the user whose river is requested, if the request
+// carries their API token.
+func riverUser(s *state, r *http.Request) (database.User, bool) {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "GoogleLogin auth=")
+	if !found {
+		_, token, found = r.BasicAuth()
+	}
+	if !found {
+		token = r.URL.Query().Get("token")
+	}
+	if token == "" {
+		return database.User{}, false
+	}
+
+	hash := sql.NullString{String: hashAPIToken(token), Valid: true}
+	user, err := s.db.GetUserByAPITokenHash(r.Context(), hash)
+	if err != nil || user.Name != r.PathValue("name") {
+		return database.User{}, false
+	}
+	return user, true
+}
+
+func getRiverPosts(ctx context.Context, s *state, user database.User, opts riverOptions) ([]database.GetReaderItemsRow, error) {
+	params := database.GetReaderItemsParams{
+		UserID:   user.ID,
+		FeedID:   opts.feedID,
+		FolderID: opts.folderID,
+		Tag:      opts.tag,
+		MaxItems: opts.limit,
+	}
+	posts, err := s.db.GetReaderItems(ctx, params)
+	if err != nil {
+		return nil, databaseError("error getting posts for river: %w", err)
+	}
+	return posts, nil
+}
+
+// writeRiver writes posts as an RSS or Atom document published at selfURL.
+func writeRiver(w io.Writer, format string, user database.User, posts []database.GetReaderItemsRow, selfURL string) error {
+	var doc any
+	switch format {
+	case "rss":
+		doc = newRiverRSS(user, posts, selfURL)
+	case "atom":
+		doc = newRiverAtom(user, posts, selfURL)
+	default:
+		return validationError("unknown river format %v, expected rss or atom", format)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("error marshalling river: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func newRiverRSS(user database.User, posts []database.GetReaderItemsRow, selfURL string) RSSFeed {
+	var feed RSSFeed
+	feed.XMLName = xml.Name{Local: "rss"}
+	feed.Version = "2.0"
+	feed.Channel.Title = fmt.Sprintf("%v's river", user.Name)
+	feed.Channel.Link = selfURL
+	feed.Channel.Description = fmt.Sprintf("Posts from the feeds %v follows, aggregated by gator.", user.Name)
+
+	for _, p := range posts {
+		feed.Channel.Item = append(feed.Channel.Item, RSSItem{
+			Title:       p.Title,
+			Link:        p.Url,
+			Description: p.Description.String,
+			PubDate:     p.PublishedAt.Format(time.RFC1123Z),
+		})
+	}
+
+	return feed
+}
+
+func newRiverAtom(user database.User, posts []database.GetReaderItemsRow, selfURL string) AtomFeed {
+	updated := user.UpdatedAt
+	for _, p := range posts {
+		if p.PublishedAt.After(updated) {
+			updated = p.PublishedAt
+		}
+	}
+
+	feed := AtomFeed{
+		ID:      "urn:uuid:" + user.ID.String(),
+		Title:   fmt.Sprintf("%v's river", user.Name),
+		Updated: updated.UTC().Format(time.RFC3339),
+		Author:  AtomPerson{Name: user.Name},
+	}
+	feed.Link = []AtomLink{{Href: selfURL, Rel: "self", Type: "application/atom+xml"}}
+
+	for _, p := range posts {
+		feed.Entry = append(feed.Entry, AtomEntry{
+			ID:        "urn:uuid:" + p.ID.String(),
+			Title:     p.Title,
+			Updated:   p.UpdatedAt.UTC().Format(time.RFC3339),
+			Published: p.PublishedAt.UTC().Format(time.RFC3339),
+			Link:      []AtomLink{{Href: p.Url, Rel: "alternate"}},
+			Summary:   AtomText{Type: "html", Body: p.Description.String},
+			Source: AtomSource{
+				Title: p.FeedName,
+				Link:  []AtomLink{{Href: p.FeedUrl, Rel: "self"}},
+			},
+		})
+	}
+
+	return feed
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// checkRiverRSS decodes an RSS river and checks it has every element RSS
+// 2.0 requires, returning the titles of its items.
+func checkRiverRSS(t *testing.T, doc string) (RSSFeed, []string) {
+	t.Helper()
+
+	var feed RSSFeed
+	if err := xml.Unmarshal([]byte(doc), &feed); err != nil {
+		t.Fatalf("river is not valid XML: %v\n%s", err, doc)
+	}
+	if feed.XMLName.Local != "rss" || feed.Version != "2.0" {
+		t.Errorf("root element is <%v version=%q>, want <rss version=\"2.0\">", feed.XMLName.Local, feed.Version)
+	}
+	if feed.Channel.Title == "" || feed.Channel.Link == "" || feed.Channel.Description == "" {
+		t.Errorf("channel lacks a title, link or description: %+v", feed.Channel)
+	}
+	var titles []string
+	for _, item := range feed.Channel.Item {
+		if item.Title == "" || item.Link == "" {
+			t.Errorf("item lacks a title or link: %+v", item)
+		}
+		if _, err := time.Parse(time.RFC1123Z, item.PubDate); err != nil {
+			t.Errorf("item %q has an invalid pubDate: %v", item.Title, err)
+		}
+		titles = append(titles, item.Title)
+	}
+	return feed, titles
+}
+
+// checkRiverAtom decodes an Atom river and checks it has every element Atom
+// requires, returning the titles of its entries.
+func checkRiverAtom(t *testing.T, doc string) (AtomFeed, []string) {
+	t.Helper()
+
+	var feed AtomFeed
+	if err := xml.Unmarshal([]byte(doc), &feed); err != nil {
+		t.Fatalf("river is not valid Atom: %v\n%s", err, doc)
+	}
+	if feed.ID == "" || feed.Title == "" || feed.Author.Name == "" {
+		t.Errorf("feed lacks an id, title or author: %+v", feed)
+	}
+	if _, err := time.Parse(time.RFC3339, feed.Updated); err != nil {
+		t.Errorf("feed has an invalid updated date: %v", err)
+	}
+	if len(feed.Link) != 1 || feed.Link[0].Rel != "self" || feed.Link[0].Href == "" {
+		t.Errorf("feed links: %+v", feed.Link)
+	}
+	var titles []string
+	for _, entry := range feed.Entry {
+		if entry.ID == "" || entry.Title == "" || atomLink(entry.Link) == "" {
+			t.Errorf("entry lacks an id, title or link: %+v", entry)
+		}
+		for _, date := range []string{entry.Updated, entry.Published} {
+			if _, err := time.Parse(time.RFC3339, date); err != nil {
+				t.Errorf("entry %q has an invalid date: %v", entry.Title, err)
+			}
+		}
+		titles = append(titles, entry.Title)
+	}
+	return feed, titles
+}
+
+// newRiverState returns a state where alice follows two feeds, one in the
+// folder "tech", and tagged one post "to-discuss".
+func newRiverState(t *testing.T) *state {
+	t.Helper()
+
+	s, server := newStateWithFeed(t, "example.rss")
+	mustRun(t, s, "addfeed", "Tagged", server.feedURL("categories.rss"))
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+	mustRun(t, s, "folders", "create", "tech")
+	mustRun(t, s, "folders", "move", server.feedURL("categories.rss"), "tech")
+	mustRun(t, s, "tag", "https://example.com/posts/first", "to-discuss")
+	return s
+}
+
+func TestRiver(t *testing.T) {
+	s := newRiverState(t)
+
+	_, titles := checkRiverRSS(t, mustRun(t, s, "river", "rss"))
+	if len(titles) != 5 {
+		t.Errorf("river has %d items, want 5: %v", len(titles), titles)
+	}
+	_, titles = checkRiverAtom(t, mustRun(t, s, "river", "--limit", "2", "atom"))
+	if len(titles) != 2 {
+		t.Errorf("river --limit 2 has %d entries: %v", len(titles), titles)
+	}
+
+	_, titles = checkRiverRSS(t, mustRun(t, s, "river", "--tag", "to-discuss", "rss"))
+	if strings.Join(titles, ",") != "First post" {
+		t.Errorf("river --tag to-discuss: %v", titles)
+	}
+	_, titles = checkRiverAtom(t, mustRun(t, s, "river", "--folder", "tech", "atom"))
+	if len(titles) != 3 || strings.Contains(strings.Join(titles, ","), "First post") {
+		t.Errorf("river --folder tech: %v", titles)
+	}
+	_, err := runCommand(t, s, "river", "--folder", "news", "rss")
+	assertKind(t, err, errNotFound)
+
+	// A river without posts is still a valid RSS document.
+	mustRun(t, s, "register", "bob")
+	feed, titles := checkRiverRSS(t, mustRun(t, s, "river", "rss"))
+	if len(titles) != 0 || feed.Channel.Link != "http://localhost:8080/river/bob/rss" {
+		t.Errorf("empty river links to %q with items %v", feed.Channel.Link, titles)
+	}
+	feed, _ = checkRiverRSS(t, mustRun(t, s, "river", "--url", "https://gator.example.com/river/bob/rss", "rss"))
+	if feed.Channel.Link != "https://gator.example.com/river/bob/rss" {
+		t.Errorf("river --url links to %q", feed.Channel.Link)
+	}
+}
+
+func TestRiverServer(t *testing.T) {
+	s := newRiverState(t)
+	aliceToken := strings.Split(mustRun(t, s, "apitoken"), "\n")[1]
+	mustRun(t, s, "register", "bob")
+	bobToken := strings.Split(mustRun(t, s, "apitoken"), "\n")[1]
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /river/{name}/{format}", handleRiverRequest(s))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	get := func(path string, setAuth func(*http.Request)) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if setAuth != nil {
+			setAuth(req)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(body)
+	}
+	basicAuth := func(user, token string) func(*http.Request) {
+		return func(r *http.Request) { r.SetBasicAuth(user, token) }
+	}
+
+	// Rivers are only served to their user.
+	for _, c := range []struct {
+		path    string
+		setAuth func(*http.Request)
+	}{
+		{"/river/alice/rss", nil},
+		{"/river/alice/rss?token=wrong", nil},
+		{"/river/alice/rss?token=" + bobToken, nil},
+		{"/river/alice/rss", basicAuth("bob", bobToken)},
+		{"/river/nobody/rss?token=" + aliceToken, nil},
+	} {
+		if status, _ := get(c.path, c.setAuth); status != http.StatusUnauthorized {
+			t.Errorf("%v returned %v, want 401", c.path, status)
+		}
+	}
+
+	status, body := get("/river/alice/rss?n=2&token="+aliceToken, nil)
+	if status != http.StatusOK {
+		t.Fatalf("river with a token: %v %s", status, body)
+	}
+	feed, titles := checkRiverRSS(t, body)
+	if len(titles) != 2 {
+		t.Errorf("river?n=2 has %d items", len(titles))
+	}
+	if want := server.URL + "/river/alice/rss?n=2"; feed.Channel.Link != want {
+		t.Errorf("river links to %q, want %q without the token", feed.Channel.Link, want)
+	}
+
+	status, body = get("/river/alice/atom?tag=to-discuss", basicAuth("alice", aliceToken))
+	if status != http.StatusOK {
+		t.Fatalf("river with basic auth: %v %s", status, body)
+	}
+	if _, titles := checkRiverAtom(t, body); strings.Join(titles, ",") != "First post" {
+		t.Errorf("river?tag=to-discuss: %v", titles)
+	}
+
+	status, body = get("/river/alice/rss?folder=tech", func(r *http.Request) {
+		r.Header.Set("Authorization", "GoogleLogin auth="+aliceToken)
+	})
+	if status != http.StatusOK {
+		t.Fatalf("river with a GoogleLogin token: %v %s", status, body)
+	}
+	if _, titles := checkRiverRSS(t, body); len(titles) != 3 {
+		t.Errorf("river?folder=tech: %v", titles)
+	}
+
+	query := url.Values{"token": {aliceToken}, "folder": {"news"}}
+	if status, _ := get("/river/alice/rss?"+query.Encode(), nil); status != http.StatusBadRequest {
+		t.Errorf("river of an unknown folder returned %v, want 400", status)
+	}
+	if status, _ := get("/river/alice/json?token="+aliceToken, nil); status != http.StatusNotFound {
+		t.Errorf("river in an unknown format returned %v, want 404", status)
+	}
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/alifoo/blog-aggregator/internal/htmltext"
+	"github.com/google/uuid"
+)
+
+// The parts of a post a rule can match.
+const (
+	ruleFieldTitle       = "title"
+	ruleFieldDescription = "description"
+	ruleFieldAuthor      = "author"
+	ruleFieldCategory    = "category"
+)
+
+// What a rule does to the posts it matches, for the user who defined it.
+const (
+	ruleActionRead = "mark-read"
+	ruleActionStar = "star"
+	ruleActionTag  = "tag"
+	ruleActionHide = "hide"
+)
+
+var (
+	ruleFields  = []string{ruleFieldTitle, ruleFieldDescription, ruleFieldAuthor, ruleFieldCategory}
+	ruleActions = []string{ruleActionRead, ruleActionStar, ruleActionTag, ruleActionHide}
+)
+
+// categorySeparator joins the categories of a post in the database.
+const categorySeparator = "\n"
+
+func joinCategories(categories []string) string {
+	var cleaned []string
+	for _, c := range categories {
+		if c = strings.TrimSpace(c); c != "" {
+			cleaned = append(cleaned, c)
+		}
+	}
+	return strings.Join(cleaned, categorySeparator)
+}
+
+func splitCategories(categories sql.NullString) []string {
+	if !categories.Valid || categories.String == "" {
+		return nil
+	}
+	return strings.Split(categories.String, categorySeparator)
+}
+
+// rule is a stored rule ready to be matched against posts.
+type rule struct {
+	database.Rule
+	regex *regexp.Regexp
+}
+
+func compileRule(r database.Rule) (rule, error) {
+	compiled := rule{Rule: r}
+	if r.IsRegex {
+		regex, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return rule{}, validationError("invalid regular expression %q: %w", r.Pattern, err)
+		}
+		compiled.regex = regex
+	}
+	return compiled, nil
+}
+
+// matchedPost holds the parts of a post rules are matched against.
+type matchedPost struct {
+	feedID      uuid.UUID
+	title       string
+	description string
+	author      string
+	categories  []string
+}
+
+func newMatchedPost(feedID uuid.UUID, title string, description, author, categories sql.NullString) matchedPost {
+	return matchedPost{
+		feedID:      feedID,
+		title:       title,
+		description: htmltext.Render(description.String),
+		author:      author.String,
+		categories:  splitCategories(categories),
+	}
+}
+
+// matches reports whether r applies to p. Keywords match any part of the
+// title, description or author ignoring case, and a whole category.
+func (r rule) matches(p matchedPost) bool {
+	if r.FeedID.Valid && r.FeedID.UUID != p.feedID {
+		return false
+	}
+
+	match := func(text string) bool {
+		if r.regex != nil {
+			return r.regex.MatchString(text)
+		}
+		return strings.Contains(strings.ToLower(text), strings.ToLower(r.Pattern))
+	}
+	switch r.Field {
+	case ruleFieldTitle:
+		return match(p.title)
+	case ruleFieldDescription:
+		return match(p.description)
+	case ruleFieldAuthor:
+		return match(p.author)
+	case ruleFieldCategory:
+		for _, category := range p.categories {
+			if r.regex != nil && r.regex.MatchString(category) || r.regex == nil && strings.EqualFold(category, r.Pattern) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// apply performs the action of r on the post for the user who owns r.
+func (r rule) apply(ctx context.Context, s *state, postID uuid.UUID) error {
+	var err error
+	switch r.Action {
+	case ruleActionRead:
+		err = s.db.SetPostRead(ctx, database.SetPostReadParams{UserID: r.UserID, PostID: postID, Read: true})
+	case ruleActionStar:
+		err = s.db.SetPostStarred(ctx, database.SetPostStarredParams{UserID: r.UserID, PostID: postID, Starred: true})
+	case ruleActionTag:
+		err = s.db.AddPostTag(ctx, database.AddPostTagParams{UserID: r.UserID, PostID: postID, Tag: r.Tag.String})
+	case ruleActionHide:
+		err = s.db.SetPostHidden(ctx, database.SetPostHiddenParams{UserID: r.UserID, PostID: postID, Hidden: true})
+	}
+	if err != nil {
+		return databaseError("error applying rule: %w", err)
+	}
+	return nil
+}
+
+func (r rule) describe(feedURL sql.NullString) string {
+	how := "contains"
+	if r.IsRegex {
+		how = "matches"
+	} else if r.Field == ruleFieldCategory {
+		how = "is"
+	}
+	action := r.Action
+	if r.Action == ruleActionTag {
+		action += " " + r.Tag.String
+	}
+	scope := "all feeds"
+	if feedURL.Valid {
+		scope = feedURL.String
+	}
+	return fmt.Sprintf("%v %v %q: %v (%v)", r.Field, how, r.Pattern, action, scope)
+}
+
+// feedRules returns the compiled rules applying to new posts of a feed.
+// Rules that no longer compile are reported and skipped.
+func feedRules(s *state, feedID uuid.UUID) ([]rule, error) {
+	stored, err := s.db.GetRulesForFeed(context.Background(), feedID)
+	if err != nil {
+		return nil, databaseError("error getting rules for feed: %w", err)
+	}
+	var rules []rule
+	for _, r := range stored {
+		compiled, err := compileRule(r)
+		if err != nil {
+			fmt.Printf("Skipping rule %v: %v\n", r.ID, err)
+			continue
+		}
+		rules = append(rules, compiled)
+	}
+	return rules, nil
+}
+
+// applyRules runs the rules of every follower of a feed on one of its new
+// posts.
+func applyRules(s *state, rules []rule, post database.Post) error {
+	matched := newMatchedPost(post.FeedID.UUID, post.Title, post.Description, post.Author, post.Categories)
+	for _, r := range rules {
+		if !r.matches(matched) {
+			continue
+		}
+		if err := r.apply(context.Background(), s, post.ID); err != nil {
+			return err
+		}
+		fmt.Printf("Post %v: %v by a rule.\n", post.Title, ruleActionPast(r))
+	}
+	return nil
+}
+
+func ruleActionPast(r rule) string {
+	switch r.Action {
+	case ruleActionRead:
+		return "marked read"
+	case ruleActionStar:
+		return "starred"
+	case ruleActionTag:
+		return "tagged " + r.Tag.String
+	}
+	return "hidden"
+}
+
+func handlerRules(s *state, cmd command, user database.User) error {
+	action, args := cmd.arguments[0], cmd.arguments[1:]
+	if action != "add" && (cmd.stringFlag("feed") != "" || cmd.boolFlag("regex")) {
+		return validationError("--feed and --regex can only be used with rules add")
+	}
+	if action != "apply" && cmd.boolFlag("dry-run") {
+		return validationError("--dry-run can only be used with rules apply")
+	}
+
+	switch action {
+	case "list":
+		if len(args) != 0 {
+			return validationError("wrong number of arguments\nusage: %s rules list", programName())
+		}
+		return listRules(s, user)
+	case "add":
+		if len(args) < 3 || len(args) > 4 {
+			return validationError("wrong number of arguments\nusage: %s rules add [--feed feed_url] [--regex] <field> <pattern> <action> [tag]", programName())
+		}
+		return addRule(s, cmd, user, args)
+	case "delete":
+		if len(args) != 1 {
+			return validationError("wrong number of arguments\nusage: %s rules delete <number>", programName())
+		}
+		return deleteRule(s, user, args[0])
+	case "apply":
+		if len(args) != 0 {
+			return validationError("wrong number of arguments\nusage: %s rules apply [--dry-run]", programName())
+		}
+		return applyUserRules(s, user, cmd.boolFlag("dry-run"))
+	}
+	return validationError("unknown rules action %v, expected list, add, delete or apply", action)
+}
+
+func addRule(s *state, cmd command, user database.User, args []string) error {
+	field, pattern, action := args[0], args[1], args[2]
+	if !slices.Contains(ruleFields, field) {
+		return validationError("unknown field %v, expected one of %v", field, strings.Join(ruleFields, ", "))
+	}
+	if !slices.Contains(ruleActions, action) {
+		return validationError("unknown action %v, expected one of %v", action, strings.Join(ruleActions, ", "))
+	}
+	if pattern == "" {
+		return validationError("the pattern of a rule cannot be empty")
+	}
+
+	var tag sql.NullString
+	switch {
+	case action == ruleActionTag && len(args) == 4 && strings.TrimSpace(args[3]) != "":
+		tag = sql.NullString{String: strings.TrimSpace(args[3]), Valid: true}
+	case action == ruleActionTag:
+		return validationError("the tag action needs a tag")
+	case len(args) == 4:
+		return validationError("only the tag action takes a tag")
+	}
+
+	var feedID uuid.NullUUID
+	var feedURL sql.NullString
+	if url := cmd.stringFlag("feed"); url != "" {
+		feed, err := getFeed(s, url)
+		if err != nil {
+			return err
+		}
+		feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+		feedURL = sql.NullString{String: feed.Url, Valid: true}
+	}
+
+	now := time.Now()
+	params := database.CreateRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    user.ID,
+		FeedID:    feedID,
+		Field:     field,
+		Pattern:   pattern,
+		IsRegex:   cmd.boolFlag("regex"),
+		Action:    action,
+		Tag:       tag,
+	}
+	compiled, err := compileRule(database.Rule(params))
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.CreateRule(context.Background(), params); err != nil {
+		return databaseError("error creating rule: %w", err)
+	}
+	fmt.Printf("Added rule: %v\n", compiled.describe(feedURL))
+	fmt.Printf("Run '%s rules apply' to apply it to posts already fetched.\n", programName())
+	return nil
+}
+
+func listRules(s *state, user database.User) error {
+	rules, err := s.db.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return databaseError("error getting rules: %w", err)
+	}
+
+	if s.output != outputPlain {
+		var records []ruleRecord
+		for i, r := range rules {
+			records = append(records, ruleRecord{
+				Number:  i + 1,
+				ID:      r.ID,
+				Feed:    r.FeedUrl.String,
+				Field:   r.Field,
+				Pattern: r.Pattern,
+				Regex:   r.IsRegex,
+				Action:  r.Action,
+				Tag:     r.Tag.String,
+			})
+		}
+		return writeRecords(os.Stdout, s.output, records)
+	}
+
+	if len(rules) == 0 {
+		fmt.Printf("No rules, add one with '%s rules add'.\n", programName())
+		return nil
+	}
+	for i, r := range rules {
+		fmt.Printf("%d. %v\n", i+1, rule{Rule: ruleFromRow(r)}.describe(r.FeedUrl))
+	}
+	return nil
+}
+
+// deleteRule deletes a rule by its number in rules list.
+func deleteRule(s *state, user database.User, number string) error {
+	rules, err := s.db.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return databaseError("error getting rules: %w", err)
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return validationError("invalid rule number %v", number)
+	}
+	if n < 1 || n > len(rules) {
+		return notFoundError("no rule number %v, see '%s rules list'", n, programName())
+	}
+
+	r := rules[n-1]
+	if err := s.db.DeleteRule(context.Background(), r.ID); err != nil {
+		return databaseError("error deleting rule: %w", err)
+	}
+	fmt.Printf("Deleted rule: %v\n", rule{Rule: ruleFromRow(r)}.describe(r.FeedUrl))
+	return nil
+}
+
+// applyUserRules runs the rules of user on the posts already stored for
+// the feeds they follow, and reports how many posts each rule matched.
+func applyUserRules(s *state, user database.User, dryRun bool) error {
+	ctx := context.Background()
+	rows, err := s.db.GetRulesForUser(ctx, user.ID)
+	if err != nil {
+		return databaseError("error getting rules: %w", err)
+	}
+	if len(rows) == 0 {
+		fmt.Printf("No rules, add one with '%s rules add'.\n", programName())
+		return nil
+	}
+	var rules []rule
+	for _, row := range rows {
+		compiled, err := compileRule(ruleFromRow(row))
+		if err != nil {
+			return err
+		}
+		rules = append(rules, compiled)
+	}
+
+	posts, err := s.db.GetReaderItems(ctx, database.GetReaderItemsParams{UserID: user.ID, MaxItems: math.MaxInt32})
+	if err != nil {
+		return databaseError("error getting posts: %w", err)
+	}
+
+	matches := make([]int, len(rules))
+	for _, p := range posts {
+		matched := newMatchedPost(p.FeedID.UUID, p.Title, p.Description, p.Author, p.Categories)
+		for i, r := range rules {
+			if !r.matches(matched) {
+				continue
+			}
+			matches[i]++
+			if dryRun {
+				continue
+			}
+			if err := r.apply(ctx, s, p.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	verb := "applied to"
+	if dryRun {
+		verb = "would apply to"
+	}
+	for i, r := range rules {
+		noun := "posts"
+		if matches[i] == 1 {
+			noun = "post"
+		}
+		fmt.Printf("%d. %v: %v %d %v\n", i+1, r.describe(rows[i].FeedUrl), verb, matches[i], noun)
+	}
+	return nil
+}
+
+func ruleFromRow(r database.GetRulesForUserRow) database.Rule {
+	return database.Rule{
+		ID:        r.ID,
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+		UserID:    r.UserID,
+		FeedID:    r.FeedID,
+		Field:     r.Field,
+		Pattern:   r.Pattern,
+		IsRegex:   r.IsRegex,
+		Action:    r.Action,
+		Tag:       r.Tag,
+	}
+}
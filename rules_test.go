@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+)
+
+// readerItems returns the visible posts of user by title.
+func readerItems(t *testing.T, s *state, user string) map[string]database.GetReaderItemsRow {
+	t.Helper()
+
+	u, err := s.db.GetUser(context.Background(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := s.db.GetReaderItems(context.Background(), database.GetReaderItemsParams{UserID: u.ID, MaxItems: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byTitle := map[string]database.GetReaderItemsRow{}
+	for _, item := range items {
+		byTitle[item.Title] = item
+	}
+	return byTitle
+}
+
+func TestRulesAddListDelete(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+	url := server.feedURL("categories.rss")
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Tagged", url)
+
+	for _, args := range [][]string{
+		{"rules", "add", "body", "go", "hide"},
+		{"rules", "add", "title", "go", "delete"},
+		{"rules", "add", "title", "", "hide"},
+		{"rules", "add", "title", "go", "tag"},
+		{"rules", "add", "title", "go", "star", "extra"},
+		{"rules", "add", "--regex", "title", "(go", "hide"},
+		{"rules", "list", "--regex"},
+		{"rules", "add", "--dry-run", "title", "go", "hide"},
+		{"rules", "remove"},
+		{"rules", "delete", "one"},
+	} {
+		_, err := runCommand(t, s, args...)
+		assertKind(t, err, errValidation)
+	}
+	_, err := runCommand(t, s, "rules", "add", "--feed", server.feedURL("unknown.rss"), "title", "go", "hide")
+	assertKind(t, err, errNotFound)
+	_, err = runCommand(t, s, "rules", "delete", "1")
+	assertKind(t, err, errNotFound)
+
+	if out := mustRun(t, s, "rules", "list"); !strings.Contains(out, "No rules") {
+		t.Errorf("rules list without rules:\n%s", out)
+	}
+	mustRun(t, s, "rules", "add", "category", "sponsored", "hide")
+	mustRun(t, s, "rules", "add", "--feed", url, "--regex", "title", `^Release`, "tag", "releases")
+
+	out := mustRun(t, s, "rules", "list")
+	want := "1. category is \"sponsored\": hide (all feeds)\n" +
+		"2. title matches \"^Release\": tag releases (" + url + ")\n"
+	if out != want {
+		t.Errorf("rules list:\n%s\nwant:\n%s", out, want)
+	}
+
+	// Rules are private to their user.
+	mustRun(t, s, "register", "bob")
+	if out := mustRun(t, s, "rules", "list"); !strings.Contains(out, "No rules") {
+		t.Errorf("bob sees the rules of alice:\n%s", out)
+	}
+
+	mustRun(t, s, "login", "alice")
+	mustRun(t, s, "rules", "delete", "1")
+	out = mustRun(t, s, "rules", "list")
+	if strings.Contains(out, "sponsored") || !strings.Contains(out, "1. title matches") {
+		t.Errorf("rules list after delete:\n%s", out)
+	}
+}
+
+func TestScrapeAppliesRules(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Tagged", server.feedURL("categories.rss"))
+	mustRun(t, s, "addfeed", "Example", server.feedURL("example.rss"))
+	mustRun(t, s, "rules", "add", "category", "SPONSORED", "hide")
+	mustRun(t, s, "rules", "add", "author", "jane", "star")
+	mustRun(t, s, "rules", "add", "description", "rust", "mark-read")
+	mustRun(t, s, "rules", "add", "--feed", server.feedURL("example.rss"), "title", "post", "tag", "example")
+	// Descriptions are matched without their markup.
+	mustRun(t, s, "rules", "add", "description", "the new release", "tag", "news")
+	mustRun(t, s, "login", "bob")
+	mustRun(t, s, "follow", server.feedURL("categories.rss"))
+
+	for range 2 {
+		if err := scrapeFeeds(s); err != nil {
+			t.Fatalf("scrapeFeeds: %v", err)
+		}
+	}
+
+	alice := readerItems(t, s, "alice")
+	if _, ok := alice["Sponsored: buy our course"]; ok {
+		t.Error("sponsored post was not hidden")
+	}
+	if !alice["Release notes for 2.0"].Starred {
+		t.Error("post by Jane Doe was not starred")
+	}
+	if !alice["Weekly links"].Read || alice["Release notes for 2.0"].Read {
+		t.Errorf("posts marked read: %+v", alice)
+	}
+
+	// The rules of alice do not change the posts of bob.
+	bob := readerItems(t, s, "bob")
+	if len(bob) != 3 || bob["Release notes for 2.0"].Starred || bob["Weekly links"].Read {
+		t.Errorf("posts of bob: %+v", bob)
+	}
+}
+
+func TestRulesApply(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Tagged", server.feedURL("categories.rss"))
+	mustRun(t, s, "addfeed", "Example", server.feedURL("example.rss"))
+	for range 2 {
+		if err := scrapeFeeds(s); err != nil {
+			t.Fatalf("scrapeFeeds: %v", err)
+		}
+	}
+
+	if out := mustRun(t, s, "rules", "apply"); !strings.Contains(out, "No rules") {
+		t.Errorf("rules apply without rules:\n%s", out)
+	}
+	mustRun(t, s, "rules", "add", "title", "POST", "mark-read")
+	mustRun(t, s, "rules", "add", "--feed", server.feedURL("categories.rss"), "description", "release", "tag", "releases")
+	mustRun(t, s, "rules", "add", "--regex", "category", "^(Go|Rust)$", "star")
+
+	out := mustRun(t, s, "rules", "apply", "--dry-run")
+	want := "1. title contains \"POST\": mark-read (all feeds): would apply to 2 posts\n" +
+		"2. description contains \"release\": tag releases (" + server.feedURL("categories.rss") + "): would apply to 1 post\n" +
+		"3. category matches \"^(Go|Rust)$\": star (all feeds): would apply to 1 post\n"
+	if out != want {
+		t.Errorf("rules apply --dry-run:\n%s\nwant:\n%s", out, want)
+	}
+	if items := readerItems(t, s, "alice"); items["First post"].Read || items["Release notes for 2.0"].Starred {
+		t.Error("rules apply --dry-run changed posts")
+	}
+
+	out = mustRun(t, s, "rules", "apply")
+	if !strings.Contains(out, "mark-read (all feeds): applied to 2 posts") {
+		t.Errorf("rules apply:\n%s", out)
+	}
+	items := readerItems(t, s, "alice")
+	if !items["First post"].Read || !items["Second post"].Read || items["Weekly links"].Read {
+		t.Errorf("posts marked read: %+v", items)
+	}
+	if !items["Release notes for 2.0"].Starred {
+		t.Error("post in the Go category was not starred")
+	}
+
+	mustRun(t, s, "rules", "add", "title", "weekly", "hide")
+	mustRun(t, s, "rules", "apply")
+	if _, ok := readerItems(t, s, "alice")["Weekly links"]; ok {
+		t.Error("rules apply did not hide the post")
+	}
+}
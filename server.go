@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const defaultServeAddr = ":8080"
+
+func handlerServe(s *state, cmd command) error {
+	addr := defaultServeAddr
+	if len(cmd.arguments) > 0 {
+		addr = cmd.arguments[0]
+	}
+
+	mux := http.NewServeMux()
+	greader := greaderServer{s: s}
+	greader.register(mux)
+	mux.HandleFunc("GET /river/{name}/{format}", handleRiverRequest(s))
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	fmt.Printf("Serving the Google Reader API and rivers on %v\n", addr)
+	return server.ListenAndServe()
+}
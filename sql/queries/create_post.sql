@@ -7,7 +7,10 @@ INSERT INTO posts (
     url,
     description,
     published_at,
-    feed_id
+    feed_id,
+    summary,
+    author,
+    categories
 )
 VALUES (
     $1,
@@ -17,7 +20,12 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10,
+    $11
 )
-ON CONFLICT (url) DO NOTHING
+ON CONFLICT (url) DO UPDATE
+SET feed_id = excluded.feed_id, updated_at = excluded.updated_at
+WHERE posts.feed_id IS NULL
 RETURNING *;
\ No newline at end of file
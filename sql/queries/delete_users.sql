@@ -1,3 +1,6 @@
 -- name: DeleteAllUsers :exec
-
-DELETE FROM users;
\ No newline at end of file
+-- Posts kept without a feed belong to no user and go too.
+WITH kept_posts AS (
+    DELETE FROM posts WHERE feed_id IS NULL
+)
+DELETE FROM users;
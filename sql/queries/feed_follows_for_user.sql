@@ -1,7 +1,9 @@
 -- name: GetFeedFollowsForUser :many
-SELECT feed_follows.*, feeds.name AS feed_name, users.name AS user_name FROM feed_follows
+SELECT feed_follows.*, feeds.name AS feed_name, feeds.url AS feed_url, users.name AS user_name, folders.name AS folder_name FROM feed_follows
 INNER JOIN feeds
 ON feed_follows.feed_id = feeds.id
 INNER JOIN users
 ON feed_follows.user_id = users.id
+LEFT JOIN folders
+ON feed_follows.folder_id = folders.id
 WHERE feed_follows.user_id = $1;
\ No newline at end of file
@@ -0,0 +1,58 @@
+-- name: RenameFeed :one
+UPDATE feeds
+SET name = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetFeedURL :one
+-- A feed given a new url is fetched again even if the old one was gone.
+UPDATE feeds
+SET url = $2, updated_at = NOW(), dead_at = NULL
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
+
+-- name: DetachFeedPosts :exec
+UPDATE posts
+SET feed_id = NULL
+WHERE feed_id = $1;
+
+-- name: SetFeedFetchError :exec
+UPDATE feeds
+SET last_fetch_error = $2
+WHERE id = $1;
+
+-- name: GetFeedStats :one
+SELECT
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = $1) AS followers,
+    (SELECT COUNT(*) FROM posts WHERE posts.feed_id = $1) AS posts;
+
+-- name: MarkFeedDead :exec
+UPDATE feeds
+SET dead_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: MoveFeedFollows :exec
+-- Moves follows to another feed, except those of users who already follow
+-- it; they are left behind and go when the old feed is deleted.
+UPDATE feed_follows
+SET feed_id = sqlc.arg(to_feed_id), updated_at = NOW()
+WHERE feed_follows.feed_id = sqlc.arg(from_feed_id)
+AND feed_follows.user_id NOT IN (
+    SELECT existing.user_id FROM feed_follows AS existing
+    WHERE existing.feed_id = sqlc.arg(to_feed_id)
+);
+
+-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = sqlc.arg(to_feed_id), updated_at = NOW()
+WHERE posts.feed_id = sqlc.arg(from_feed_id);
+
+-- name: SetFeedFullContent :one
+UPDATE feeds
+SET fetch_full_content = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
\ No newline at end of file
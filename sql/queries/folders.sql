@@ -0,0 +1,42 @@
+-- name: CreateFolder :one
+INSERT INTO folders (
+    id,
+    created_at,
+    updated_at,
+    user_id,
+    name
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: GetFoldersForUser :many
+SELECT * FROM folders
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: GetFolderByName :one
+SELECT * FROM folders
+WHERE user_id = $1 AND name = $2;
+
+-- name: RenameFolder :one
+UPDATE folders
+SET name = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteFolder :exec
+-- The feeds in the folder are kept, outside of any folder.
+DELETE FROM folders
+WHERE id = $1;
+
+-- name: SetFeedFollowFolder :one
+UPDATE feed_follows
+SET folder_id = sqlc.narg(folder_id), updated_at = NOW()
+WHERE user_id = sqlc.arg(user_id) AND feed_id = sqlc.arg(feed_id)
+RETURNING *;
\ No newline at end of file
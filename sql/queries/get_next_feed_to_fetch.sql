@@ -1,4 +1,5 @@
 -- name: GetNextFeedToFetch :one
 SELECT * FROM feeds
+WHERE dead_at IS NULL
 ORDER BY last_fetched_at NULLS FIRST
 LIMIT 1;
\ No newline at end of file
@@ -0,0 +1,4 @@
+-- name: SetPostContent :exec
+UPDATE posts
+SET content = $2, updated_at = NOW()
+WHERE id = $1;
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- name: SetPostRead :exec
+INSERT INTO post_states (user_id, post_id, read, updated_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read = EXCLUDED.read, updated_at = NOW();
+
+-- name: SetPostStarred :exec
+INSERT INTO post_states (user_id, post_id, starred, updated_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET starred = EXCLUDED.starred, updated_at = NOW();
+
+-- name: SetPostHidden :exec
+INSERT INTO post_states (user_id, post_id, hidden, updated_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET hidden = EXCLUDED.hidden, updated_at = NOW();
\ No newline at end of file
@@ -0,0 +1,22 @@
+-- name: AddPostTag :exec
+INSERT INTO post_tags (user_id, post_id, tag, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (user_id, post_id, tag) DO NOTHING;
+
+-- name: RemovePostTag :one
+DELETE FROM post_tags
+WHERE user_id = $1 AND post_id = $2 AND tag = $3
+RETURNING *;
+
+-- name: GetPostTags :many
+SELECT tag FROM post_tags
+WHERE user_id = $1 AND post_id = $2
+ORDER BY tag;
+
+-- name: GetTagsForUser :many
+-- Returns every tag of a user with the number of posts it is on, for the
+-- tag cloud.
+SELECT tag, COUNT(*) AS posts FROM post_tags
+WHERE user_id = $1
+GROUP BY tag
+ORDER BY tag;
\ No newline at end of file
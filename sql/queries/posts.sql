@@ -0,0 +1,11 @@
+-- name: GetPost :one
+SELECT * FROM posts
+WHERE id = $1;
+
+-- name: GetPostByURL :one
+SELECT * FROM posts
+WHERE url = $1;
+
+-- name: GetPostBySeq :one
+SELECT * FROM posts
+WHERE seq = $1;
\ No newline at end of file
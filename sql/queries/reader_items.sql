@@ -0,0 +1,63 @@
+-- name: GetReaderItems :many
+SELECT
+    posts.*,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    COALESCE(post_states.read, FALSE)::boolean AS read,
+    COALESCE(post_states.starred, FALSE)::boolean AS starred
+FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id AND feed_follows.user_id = sqlc.arg(user_id)
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_states
+ON posts.id = post_states.post_id AND post_states.user_id = sqlc.arg(user_id)
+WHERE (sqlc.narg(feed_id)::uuid IS NULL OR posts.feed_id = sqlc.narg(feed_id))
+AND (sqlc.narg(folder_id)::uuid IS NULL OR feed_follows.folder_id = sqlc.narg(folder_id))
+AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+    SELECT 1 FROM post_tags
+    WHERE post_tags.user_id = sqlc.arg(user_id) AND post_tags.post_id = posts.id AND post_tags.tag = sqlc.narg(tag)
+))
+AND NOT COALESCE(post_states.hidden, FALSE)
+AND (NOT sqlc.arg(exclude_read)::boolean OR NOT COALESCE(post_states.read, FALSE))
+AND (NOT sqlc.arg(only_read)::boolean OR COALESCE(post_states.read, FALSE))
+AND (NOT sqlc.arg(only_starred)::boolean OR COALESCE(post_states.starred, FALSE))
+AND (sqlc.narg(newer_than)::timestamp IS NULL OR posts.published_at >= sqlc.narg(newer_than))
+AND (sqlc.narg(older_than)::timestamp IS NULL OR posts.published_at <= sqlc.narg(older_than))
+ORDER BY
+    CASE WHEN sqlc.arg(oldest_first)::boolean THEN posts.published_at END ASC,
+    posts.published_at DESC
+LIMIT sqlc.arg(max_items)
+OFFSET sqlc.arg(skip_items);
+
+-- name: GetReaderItemsBySeq :many
+SELECT
+    posts.*,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    COALESCE(post_states.read, FALSE)::boolean AS read,
+    COALESCE(post_states.starred, FALSE)::boolean AS starred
+FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id AND feed_follows.user_id = sqlc.arg(user_id)
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_states
+ON posts.id = post_states.post_id AND post_states.user_id = sqlc.arg(user_id)
+WHERE posts.seq = ANY(sqlc.arg(seqs)::bigint[])
+ORDER BY posts.published_at DESC;
+
+-- name: GetUnreadCountsForUser :many
+SELECT
+    posts.feed_id,
+    COUNT(posts.id) AS unread,
+    MAX(posts.published_at)::timestamp AS newest
+FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+LEFT JOIN post_states
+ON posts.id = post_states.post_id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND NOT COALESCE(post_states.read, FALSE)
+AND NOT COALESCE(post_states.hidden, FALSE)
+GROUP BY posts.feed_id;
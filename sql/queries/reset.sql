@@ -0,0 +1,31 @@
+-- name: DeleteAllFeeds :exec
+-- Posts kept without a feed go too, like the posts of the feeds.
+WITH kept_posts AS (
+    DELETE FROM posts WHERE feed_id IS NULL
+)
+DELETE FROM feeds;
+
+-- name: DeleteAllPosts :exec
+DELETE FROM posts;
+
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = $1;
+
+-- name: GetResetCounts :one
+-- Counts the rows a reset would delete, for every user or only the one
+-- given: their feeds, the posts of those feeds and every follow of them.
+-- Posts kept without a feed only count for every user.
+SELECT
+    (SELECT COUNT(*) FROM users
+     WHERE sqlc.narg('user_id')::uuid IS NULL OR users.id = sqlc.narg('user_id')) AS users,
+    (SELECT COUNT(*) FROM feeds
+     WHERE sqlc.narg('user_id')::uuid IS NULL OR feeds.user_id = sqlc.narg('user_id')) AS feeds,
+    (SELECT COUNT(*) FROM feed_follows
+     INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+     WHERE sqlc.narg('user_id')::uuid IS NULL
+     OR feed_follows.user_id = sqlc.narg('user_id')
+     OR feeds.user_id = sqlc.narg('user_id')) AS feed_follows,
+    (SELECT COUNT(*) FROM posts
+     LEFT JOIN feeds ON posts.feed_id = feeds.id
+     WHERE sqlc.narg('user_id')::uuid IS NULL OR feeds.user_id = sqlc.narg('user_id')) AS posts;
\ No newline at end of file
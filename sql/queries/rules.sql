@@ -0,0 +1,51 @@
+-- name: CreateRule :one
+INSERT INTO rules (
+    id,
+    created_at,
+    updated_at,
+    user_id,
+    feed_id,
+    field,
+    pattern,
+    is_regex,
+    action,
+    tag
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10
+)
+RETURNING *;
+
+-- name: GetRulesForUser :many
+SELECT rules.*, feeds.url AS feed_url FROM rules
+LEFT JOIN feeds
+ON rules.feed_id = feeds.id
+WHERE rules.user_id = $1
+ORDER BY rules.created_at, rules.id;
+
+-- name: GetRulesForFeed :many
+-- Returns the rules that apply to new posts of a feed: those of every user
+-- following it, for all feeds or for this one.
+SELECT rules.* FROM rules
+INNER JOIN feed_follows
+ON rules.user_id = feed_follows.user_id AND feed_follows.feed_id = sqlc.arg(feed_id)
+WHERE rules.feed_id IS NULL OR rules.feed_id = sqlc.arg(feed_id)
+ORDER BY rules.created_at, rules.id;
+
+-- name: DeleteRule :exec
+DELETE FROM rules
+WHERE id = $1;
+
+-- name: MoveFeedRules :exec
+UPDATE rules
+SET feed_id = sqlc.arg(to_feed_id)::uuid, updated_at = NOW()
+WHERE rules.feed_id = sqlc.arg(from_feed_id)::uuid;
\ No newline at end of file
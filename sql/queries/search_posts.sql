@@ -0,0 +1,20 @@
+-- name: SearchPosts :many
+-- Posts stored before summaries were added only have their description.
+SELECT
+    posts.*,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    COALESCE(post_states.read, FALSE)::boolean AS read,
+    COALESCE(post_states.starred, FALSE)::boolean AS starred
+FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id AND feed_follows.user_id = sqlc.arg(user_id)
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_states
+ON posts.id = post_states.post_id AND post_states.user_id = sqlc.arg(user_id)
+WHERE (strpos(lower(posts.title), lower(sqlc.arg(query)::text)) > 0
+OR strpos(lower(COALESCE(posts.summary, posts.description, '')), lower(sqlc.arg(query)::text)) > 0)
+AND NOT COALESCE(post_states.hidden, FALSE)
+ORDER BY posts.published_at DESC
+LIMIT sqlc.arg(max_items);
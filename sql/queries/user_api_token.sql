@@ -0,0 +1,9 @@
+-- name: SetUserAPITokenHash :exec
+UPDATE users
+SET api_token_hash = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetUserByAPITokenHash :one
+SELECT * FROM users
+WHERE api_token_hash = $1
+LIMIT 1;
@@ -0,0 +1,5 @@
+-- name: RenameUser :one
+UPDATE users
+SET name = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
\ No newline at end of file
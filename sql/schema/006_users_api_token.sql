@@ -0,0 +1,6 @@
+-- +goose Up
+ALTER TABLE users
+ADD api_token_hash TEXT NULL UNIQUE;
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN api_token_hash;
\ No newline at end of file
@@ -0,0 +1,6 @@
+-- +goose Up
+ALTER TABLE posts
+ADD seq BIGSERIAL NOT NULL UNIQUE;
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN seq;
\ No newline at end of file
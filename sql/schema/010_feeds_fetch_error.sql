@@ -0,0 +1,6 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD last_fetch_error TEXT;
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN last_fetch_error;
\ No newline at end of file
@@ -0,0 +1,6 @@
+-- +goose Up
+ALTER TABLE posts
+ADD summary TEXT;
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN summary;
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD fetch_full_content BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE posts
+ADD content TEXT;
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN content;
+ALTER TABLE feeds
+DROP COLUMN fetch_full_content;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- Posts kept by deletefeed --keep-posts have no feed.
+-- +goose Up
+ALTER TABLE posts
+ALTER COLUMN feed_id DROP NOT NULL;
+-- +goose Down
+DELETE FROM posts
+WHERE feed_id IS NULL;
+ALTER TABLE posts
+ALTER COLUMN feed_id SET NOT NULL;
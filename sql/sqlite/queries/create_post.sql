@@ -0,0 +1,33 @@
+-- name: CreatePost :one
+INSERT INTO posts (
+    id,
+    created_at,
+    updated_at,
+    title,
+    url,
+    description,
+    published_at,
+    feed_id,
+    seq,
+    summary,
+    author,
+    categories
+)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    (SELECT seq + 1 FROM post_seq),
+    ?,
+    ?,
+    ?
+)
+ON CONFLICT (url) DO UPDATE
+SET feed_id = excluded.feed_id, updated_at = excluded.updated_at
+WHERE posts.feed_id IS NULL
+RETURNING *;
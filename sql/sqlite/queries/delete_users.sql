@@ -0,0 +1,2 @@
+-- name: DeleteAllUsers :exec
+DELETE FROM users;
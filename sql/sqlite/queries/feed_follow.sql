@@ -0,0 +1,24 @@
+-- name: CreateFeedFollow :one
+INSERT INTO feed_follows (
+    id,
+    created_at,
+    updated_at,
+    user_id,
+    feed_id
+)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?
+)
+RETURNING *;
+
+-- name: GetFeedFollow :one
+SELECT feed_follows.*, feeds.name AS feed_name, users.name AS user_name FROM feed_follows
+INNER JOIN users
+ON feed_follows.user_id = users.id
+INNER JOIN feeds
+ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.id = ?;
@@ -0,0 +1,6 @@
+-- name: DeleteFeedFollow :exec
+DELETE FROM feed_follows
+WHERE feed_follows.user_id = ? AND feed_follows.feed_id = (
+    SELECT id FROM feeds
+    WHERE url = ?
+);
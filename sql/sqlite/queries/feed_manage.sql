@@ -0,0 +1,58 @@
+-- name: RenameFeed :one
+UPDATE feeds
+SET name = sqlc.arg(name), updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: SetFeedURL :one
+-- A feed given a new url is fetched again even if the old one was gone.
+UPDATE feeds
+SET url = sqlc.arg(url), updated_at = sqlc.arg(updated_at), dead_at = NULL
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = ?;
+
+-- name: DetachFeedPosts :exec
+UPDATE posts
+SET feed_id = NULL
+WHERE feed_id = ?;
+
+-- name: SetFeedFetchError :exec
+UPDATE feeds
+SET last_fetch_error = sqlc.arg(last_fetch_error)
+WHERE id = sqlc.arg(id);
+
+-- name: GetFeedStats :one
+SELECT
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = sqlc.arg(feed_id)) AS followers,
+    (SELECT COUNT(*) FROM posts WHERE posts.feed_id = sqlc.arg(feed_id)) AS posts;
+
+-- name: MarkFeedDead :exec
+UPDATE feeds
+SET dead_at = sqlc.arg(now), updated_at = sqlc.arg(now)
+WHERE id = sqlc.arg(id);
+
+-- name: MoveFeedFollows :exec
+-- Moves follows to another feed, except those of users who already follow
+-- it; they are left behind and go when the old feed is deleted.
+UPDATE feed_follows
+SET feed_id = sqlc.arg(to_feed_id), updated_at = sqlc.arg(updated_at)
+WHERE feed_follows.feed_id = sqlc.arg(from_feed_id)
+AND feed_follows.user_id NOT IN (
+    SELECT existing.user_id FROM feed_follows AS existing
+    WHERE existing.feed_id = sqlc.arg(to_feed_id)
+);
+
+-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = sqlc.arg(to_feed_id), updated_at = sqlc.arg(updated_at)
+WHERE posts.feed_id = sqlc.arg(from_feed_id);
+
+-- name: SetFeedFullContent :one
+UPDATE feeds
+SET fetch_full_content = sqlc.arg(fetch_full_content), updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
\ No newline at end of file
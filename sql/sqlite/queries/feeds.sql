@@ -0,0 +1,18 @@
+-- name: CreateFeed :one
+INSERT INTO feeds (
+    id,
+    created_at,
+    updated_at,
+    name,
+    url,
+    user_id
+)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?
+)
+RETURNING *;
@@ -0,0 +1,4 @@
+-- name: GetFeedByURL :one
+SELECT * FROM feeds
+WHERE url = ?
+LIMIT 1;
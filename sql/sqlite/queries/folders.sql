@@ -0,0 +1,42 @@
+-- name: CreateFolder :one
+INSERT INTO folders (
+    id,
+    created_at,
+    updated_at,
+    user_id,
+    name
+)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?
+)
+RETURNING *;
+
+-- name: GetFoldersForUser :many
+SELECT * FROM folders
+WHERE user_id = ?
+ORDER BY name;
+
+-- name: GetFolderByName :one
+SELECT * FROM folders
+WHERE user_id = ? AND name = ?;
+
+-- name: RenameFolder :one
+UPDATE folders
+SET name = sqlc.arg(name), updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: DeleteFolder :exec
+-- The feeds in the folder are kept, outside of any folder.
+DELETE FROM folders
+WHERE id = ?;
+
+-- name: SetFeedFollowFolder :one
+UPDATE feed_follows
+SET folder_id = sqlc.narg(folder_id), updated_at = sqlc.arg(updated_at)
+WHERE user_id = sqlc.arg(user_id) AND feed_id = sqlc.arg(feed_id)
+RETURNING *;
\ No newline at end of file
@@ -0,0 +1,7 @@
+-- name: GetNextFeedToFetch :one
+-- SQLite sorts NULLs first in ascending order, so feeds never fetched
+-- come first as they do with NULLS FIRST in PostgreSQL.
+SELECT * FROM feeds
+WHERE dead_at IS NULL
+ORDER BY last_fetched_at
+LIMIT 1;
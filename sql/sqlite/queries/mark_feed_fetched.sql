@@ -0,0 +1,4 @@
+-- name: MarkFeedFetched :exec
+UPDATE feeds
+SET last_fetched_at = sqlc.arg(now), updated_at = sqlc.arg(now)
+WHERE feeds.id = sqlc.arg(id);
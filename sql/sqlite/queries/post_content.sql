@@ -0,0 +1,4 @@
+-- name: SetPostContent :exec
+UPDATE posts
+SET content = sqlc.arg(content), updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id);
\ No newline at end of file
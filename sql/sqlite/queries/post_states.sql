@@ -0,0 +1,17 @@
+-- name: SetPostRead :exec
+INSERT INTO post_states (user_id, post_id, read, updated_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read = excluded.read, updated_at = excluded.updated_at;
+
+-- name: SetPostStarred :exec
+INSERT INTO post_states (user_id, post_id, starred, updated_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET starred = excluded.starred, updated_at = excluded.updated_at;
+
+-- name: SetPostHidden :exec
+INSERT INTO post_states (user_id, post_id, hidden, updated_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET hidden = excluded.hidden, updated_at = excluded.updated_at;
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- name: GetPost :one
+SELECT * FROM posts
+WHERE id = ?;
+
+-- name: GetPostByURL :one
+SELECT * FROM posts
+WHERE url = ?;
+
+-- name: GetPostBySeq :one
+SELECT * FROM posts
+WHERE seq = ?;
\ No newline at end of file
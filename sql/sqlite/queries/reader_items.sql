@@ -0,0 +1,69 @@
+-- name: GetReaderItems :many
+-- sqlc does not bind parameters in the ORDER BY clause of SQLite queries,
+-- so the sort order is passed in through a one-row CTE.
+WITH options AS (
+    SELECT CAST(sqlc.arg(oldest_first) AS BOOLEAN) AS oldest_first
+)
+SELECT
+    posts.*,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    CAST(COALESCE(post_states.read, FALSE) AS BOOLEAN) AS read,
+    CAST(COALESCE(post_states.starred, FALSE) AS BOOLEAN) AS starred
+FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id AND feed_follows.user_id = sqlc.arg(user_id)
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_states
+ON posts.id = post_states.post_id AND post_states.user_id = sqlc.arg(user_id)
+CROSS JOIN options
+WHERE (sqlc.narg(feed_id) IS NULL OR posts.feed_id = sqlc.narg(feed_id))
+AND (sqlc.narg(folder_id) IS NULL OR feed_follows.folder_id = sqlc.narg(folder_id))
+AND (sqlc.narg(tag) IS NULL OR EXISTS (
+    SELECT 1 FROM post_tags
+    WHERE post_tags.user_id = sqlc.arg(user_id) AND post_tags.post_id = posts.id AND post_tags.tag = sqlc.narg(tag)
+))
+AND COALESCE(post_states.hidden, FALSE) = FALSE
+AND (CAST(sqlc.arg(exclude_read) AS BOOLEAN) = FALSE OR COALESCE(post_states.read, FALSE) = FALSE)
+AND (CAST(sqlc.arg(only_read) AS BOOLEAN) = FALSE OR COALESCE(post_states.read, FALSE) = TRUE)
+AND (CAST(sqlc.arg(only_starred) AS BOOLEAN) = FALSE OR COALESCE(post_states.starred, FALSE) = TRUE)
+AND (sqlc.narg(newer_than) IS NULL OR posts.published_at >= sqlc.narg(newer_than))
+AND (sqlc.narg(older_than) IS NULL OR posts.published_at <= sqlc.narg(older_than))
+ORDER BY
+    CASE WHEN options.oldest_first THEN posts.published_at END ASC,
+    posts.published_at DESC
+LIMIT sqlc.arg(max_items)
+OFFSET sqlc.arg(skip_items);
+
+-- name: GetReaderItemsBySeq :many
+SELECT
+    posts.*,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    CAST(COALESCE(post_states.read, FALSE) AS BOOLEAN) AS read,
+    CAST(COALESCE(post_states.starred, FALSE) AS BOOLEAN) AS starred
+FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id AND feed_follows.user_id = sqlc.arg(user_id)
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_states
+ON posts.id = post_states.post_id AND post_states.user_id = sqlc.arg(user_id)
+WHERE posts.seq IN (sqlc.slice(seqs))
+ORDER BY posts.published_at DESC;
+
+-- name: GetUnreadCountsForUser :many
+SELECT
+    posts.feed_id,
+    COUNT(posts.id) AS unread,
+    MAX(posts.published_at) AS newest
+FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+LEFT JOIN post_states
+ON posts.id = post_states.post_id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = ?
+AND NOT COALESCE(post_states.read, FALSE)
+AND NOT COALESCE(post_states.hidden, FALSE)
+GROUP BY posts.feed_id;
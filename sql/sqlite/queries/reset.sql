@@ -0,0 +1,34 @@
+-- name: DeleteAllFeeds :exec
+DELETE FROM feeds;
+
+-- name: DeleteKeptPosts :exec
+-- SQLite has no DELETE in WITH, so unlike on PostgreSQL the posts kept
+-- without a feed are deleted on their own, in the same transaction as
+-- DeleteAllUsers or DeleteAllFeeds.
+DELETE FROM posts
+WHERE feed_id IS NULL;
+
+-- name: DeleteAllPosts :exec
+DELETE FROM posts;
+
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = ?;
+
+-- name: GetResetCounts :one
+-- Counts the rows a reset would delete, for every user or only the one
+-- given: their feeds, the posts of those feeds and every follow of them.
+-- Posts kept without a feed only count for every user.
+SELECT
+    (SELECT COUNT(*) FROM users
+     WHERE sqlc.narg('user_id') IS NULL OR users.id = sqlc.narg('user_id')) AS users,
+    (SELECT COUNT(*) FROM feeds
+     WHERE sqlc.narg('user_id') IS NULL OR feeds.user_id = sqlc.narg('user_id')) AS feeds,
+    (SELECT COUNT(*) FROM feed_follows
+     INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+     WHERE sqlc.narg('user_id') IS NULL
+     OR feed_follows.user_id = sqlc.narg('user_id')
+     OR feeds.user_id = sqlc.narg('user_id')) AS feed_follows,
+    (SELECT COUNT(*) FROM posts
+     LEFT JOIN feeds ON posts.feed_id = feeds.id
+     WHERE sqlc.narg('user_id') IS NULL OR feeds.user_id = sqlc.narg('user_id')) AS posts;
\ No newline at end of file
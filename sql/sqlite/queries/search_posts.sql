@@ -0,0 +1,21 @@
+-- name: SearchPosts :many
+-- Posts stored before summaries were added only have their description.
+-- SQLite only lowercases ASCII letters, so other letters match exactly.
+SELECT
+    posts.*,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    CAST(COALESCE(post_states.read, FALSE) AS BOOLEAN) AS read,
+    CAST(COALESCE(post_states.starred, FALSE) AS BOOLEAN) AS starred
+FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id AND feed_follows.user_id = sqlc.arg(user_id)
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_states
+ON posts.id = post_states.post_id AND post_states.user_id = sqlc.arg(user_id)
+WHERE (instr(lower(posts.title), lower(sqlc.arg(query))) > 0
+OR instr(lower(COALESCE(posts.summary, posts.description, '')), lower(sqlc.arg(query))) > 0)
+AND COALESCE(post_states.hidden, FALSE) = FALSE
+ORDER BY posts.published_at DESC
+LIMIT sqlc.arg(max_items);
@@ -0,0 +1,9 @@
+-- name: SetUserAPITokenHash :exec
+UPDATE users
+SET api_token_hash = sqlc.arg(api_token_hash), updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id);
+
+-- name: GetUserByAPITokenHash :one
+SELECT * FROM users
+WHERE api_token_hash = ?
+LIMIT 1;
@@ -0,0 +1,4 @@
+-- name: GetUserById :one
+SELECT * FROM users
+WHERE id = ?
+LIMIT 1;
@@ -0,0 +1,5 @@
+-- name: RenameUser :one
+UPDATE users
+SET name = sqlc.arg(name), updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- name: CreateUser :one
+INSERT INTO users (id, created_at, updated_at, name)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?
+)
+RETURNING *;
@@ -0,0 +1,43 @@
+-- +goose NO TRANSACTION
+-- SQLite cannot change a foreign key in place, so posts is rebuilt. Foreign
+-- keys are off meanwhile so dropping the old table keeps post_states.
+-- +goose Up
+PRAGMA foreign_keys = OFF;
+BEGIN;
+CREATE TABLE posts_new (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    description TEXT,
+    published_at TIMESTAMP NOT NULL,
+    feed_id UUID NOT NULL,
+    seq INTEGER NOT NULL UNIQUE,
+    FOREIGN KEY(feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+);
+INSERT INTO posts_new SELECT * FROM posts;
+DROP TABLE posts;
+ALTER TABLE posts_new RENAME TO posts;
+COMMIT;
+PRAGMA foreign_keys = ON;
+-- +goose Down
+PRAGMA foreign_keys = OFF;
+BEGIN;
+CREATE TABLE posts_old (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    description TEXT,
+    published_at TIMESTAMP NOT NULL,
+    feed_id UUID NOT NULL,
+    seq INTEGER NOT NULL UNIQUE,
+    FOREIGN KEY(feed_id) REFERENCES feeds (id)
+);
+INSERT INTO posts_old SELECT * FROM posts;
+DROP TABLE posts;
+ALTER TABLE posts_old RENAME TO posts;
+COMMIT;
+PRAGMA foreign_keys = ON;
\ No newline at end of file
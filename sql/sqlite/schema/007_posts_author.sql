@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE posts
+ADD author TEXT;
+ALTER TABLE posts
+ADD categories TEXT;
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN categories;
+ALTER TABLE posts
+DROP COLUMN author;
\ No newline at end of file
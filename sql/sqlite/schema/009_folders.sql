@@ -0,0 +1,35 @@
+-- +goose NO TRANSACTION
+-- SQLite cannot drop a column used by a foreign key, so going down
+-- rebuilds feed_follows. Foreign keys are off meanwhile.
+-- +goose Up
+CREATE TABLE folders (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    FOREIGN KEY(user_id) REFERENCES users (id) ON DELETE CASCADE,
+    UNIQUE(user_id, name)
+);
+
+ALTER TABLE feed_follows
+ADD folder_id UUID REFERENCES folders (id) ON DELETE SET NULL;
+-- +goose Down
+PRAGMA foreign_keys = OFF;
+BEGIN;
+CREATE TABLE feed_follows_old (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    feed_id UUID NOT NULL,
+    FOREIGN KEY(user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY(feed_id) REFERENCES feeds (id) ON DELETE CASCADE,
+    UNIQUE(user_id, feed_id)
+);
+INSERT INTO feed_follows_old SELECT id, created_at, updated_at, user_id, feed_id FROM feed_follows;
+DROP TABLE feed_follows;
+ALTER TABLE feed_follows_old RENAME TO feed_follows;
+DROP TABLE folders;
+COMMIT;
+PRAGMA foreign_keys = ON;
\ No newline at end of file
@@ -0,0 +1,53 @@
+-- +goose NO TRANSACTION
+-- Posts kept by deletefeed --keep-posts have no feed. SQLite cannot drop
+-- NOT NULL in place, so posts is rebuilt. Foreign keys are off meanwhile so
+-- dropping the old table keeps post_states and post_tags.
+-- +goose Up
+PRAGMA foreign_keys = OFF;
+BEGIN;
+CREATE TABLE posts_new (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    description TEXT,
+    published_at TIMESTAMP NOT NULL,
+    feed_id UUID,
+    seq INTEGER NOT NULL UNIQUE,
+    summary TEXT,
+    content TEXT,
+    author TEXT,
+    categories TEXT,
+    FOREIGN KEY(feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+);
+INSERT INTO posts_new SELECT * FROM posts;
+DROP TABLE posts;
+ALTER TABLE posts_new RENAME TO posts;
+COMMIT;
+PRAGMA foreign_keys = ON;
+-- +goose Down
+DELETE FROM posts WHERE feed_id IS NULL;
+PRAGMA foreign_keys = OFF;
+BEGIN;
+CREATE TABLE posts_old (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    description TEXT,
+    published_at TIMESTAMP NOT NULL,
+    feed_id UUID NOT NULL,
+    seq INTEGER NOT NULL UNIQUE,
+    summary TEXT,
+    content TEXT,
+    author TEXT,
+    categories TEXT,
+    FOREIGN KEY(feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+);
+INSERT INTO posts_old SELECT * FROM posts;
+DROP TABLE posts;
+ALTER TABLE posts_old RENAME TO posts;
+COMMIT;
+PRAGMA foreign_keys = ON;
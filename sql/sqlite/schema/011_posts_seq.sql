@@ -0,0 +1,19 @@
+-- Short post ids were allocated as one more than the largest stored seq,
+-- so deleting the newest posts handed their ids, which users and Google
+-- Reader clients may have kept, to the next posts fetched. post_seq keeps
+-- the largest seq ever allocated, like the sequence behind BIGSERIAL on
+-- PostgreSQL.
+-- +goose Up
+CREATE TABLE post_seq (
+    seq INTEGER NOT NULL
+);
+INSERT INTO post_seq (seq) SELECT COALESCE(MAX(seq), 0) FROM posts;
+-- +goose StatementBegin
+CREATE TRIGGER posts_seq AFTER INSERT ON posts
+BEGIN
+    UPDATE post_seq SET seq = NEW.seq WHERE NEW.seq > seq;
+END;
+-- +goose StatementEnd
+-- +goose Down
+DROP TRIGGER posts_seq;
+DROP TABLE post_seq;
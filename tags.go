@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+)
+
+// handlerTag adds one of the current user's labels to a post of a feed
+// they follow, to build reading lists such as "to-discuss" that browse
+// --tag shows.
+func handlerTag(s *state, cmd command, user database.User) error {
+	post, err := getFollowedPost(s, user, cmd.arguments[0])
+	if err != nil {
+		return err
+	}
+	tag, err := checkTag(cmd.arguments[1])
+	if err != nil {
+		return err
+	}
+
+	err = s.db.AddPostTag(context.Background(), database.AddPostTagParams{UserID: user.ID, PostID: post.ID, Tag: tag})
+	if err != nil {
+		return databaseError("error tagging post: %w", err)
+	}
+	fmt.Printf("Tagged %v %v.\n", post.Title, tag)
+	return nil
+}
+
+// handlerUntag looks the post up among every post, so that tags stay
+// removable after the user unfollowed its feed.
+func handlerUntag(s *state, cmd command, user database.User) error {
+	post, err := getPost(s, cmd.arguments[0])
+	if err != nil {
+		return err
+	}
+	tag := strings.TrimSpace(cmd.arguments[1])
+
+	_, err = s.db.RemovePostTag(context.Background(), database.RemovePostTagParams{UserID: user.ID, PostID: post.ID, Tag: tag})
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFoundError("post %v is not tagged %v", post.Title, tag)
+	}
+	if err != nil {
+		return databaseError("error untagging post: %w", err)
+	}
+	fmt.Printf("Removed tag %v from %v.\n", tag, post.Title)
+	return nil
+}
+
+// handlerTags lists the tags of a post, or without one, the tag cloud of
+// the current user: every tag with the number of posts it is on, most used
+// first.
+func handlerTags(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) == 1 {
+		return listPostTags(s, user, cmd.arguments[0])
+	}
+
+	tags, err := s.db.GetTagsForUser(context.Background(), user.ID)
+	if err != nil {
+		return databaseError("error getting tags: %w", err)
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].Posts > tags[j].Posts })
+
+	if s.output != outputPlain {
+		var records []tagRecord
+		for _, t := range tags {
+			records = append(records, tagRecord{Tag: t.Tag, Posts: t.Posts})
+		}
+		return writeRecords(os.Stdout, s.output, records)
+	}
+
+	if len(tags) == 0 {
+		fmt.Printf("No tags, tag a post with '%s tag'.\n", programName())
+		return nil
+	}
+	for _, t := range tags {
+		noun := "posts"
+		if t.Posts == 1 {
+			noun = "post"
+		}
+		fmt.Printf("%v: %d %v\n", t.Tag, t.Posts, noun)
+	}
+	return nil
+}
+
+func listPostTags(s *state, user database.User, ref string) error {
+	post, err := getFollowedPost(s, user, ref)
+	if err != nil {
+		return err
+	}
+	tags, err := s.db.GetPostTags(context.Background(), database.GetPostTagsParams{UserID: user.ID, PostID: post.ID})
+	if err != nil {
+		return databaseError("error getting tags: %w", err)
+	}
+
+	if s.output != outputPlain {
+		var records []tagRecord
+		for _, t := range tags {
+			records = append(records, tagRecord{Tag: t, Posts: 1})
+		}
+		return writeRecords(os.Stdout, s.output, records)
+	}
+
+	if len(tags) == 0 {
+		fmt.Printf("%v has no tags.\n", post.Title)
+		return nil
+	}
+	fmt.Printf("%v: %v\n", post.Title, strings.Join(tags, ", "))
+	return nil
+}
+
+func checkTag(tag string) (string, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return "", validationError("tag is empty")
+	}
+	return tag, nil
+}
+
+// completeTags completes the tag argument of tag and untag with the tags
+// the current user already uses.
+func completeTags(s *state, position int) []string {
+	if position != 1 {
+		return nil
+	}
+	user, err := s.db.GetUser(context.Background(), s.configPointer.CurrentUserName)
+	if err != nil {
+		return nil
+	}
+	tags, err := s.db.GetTagsForUser(context.Background(), user.ID)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, t := range tags {
+		names = append(names, t.Tag)
+	}
+	return names
+}
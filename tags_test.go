@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", server.feedURL("example.rss"))
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+	first := readerItems(t, s, "alice")["First post"]
+
+	if out := mustRun(t, s, "tags"); !strings.Contains(out, "No tags") {
+		t.Errorf("tags without tags:\n%s", out)
+	}
+
+	mustRun(t, s, "tag", "https://example.com/posts/first", "to-discuss")
+	mustRun(t, s, "tag", first.ID.String(), "go")
+	mustRun(t, s, "tag", "https://example.com/posts/second", " to-discuss ")
+
+	out := mustRun(t, s, "tags")
+	if want := "to-discuss: 2 posts\ngo: 1 post\n"; out != want {
+		t.Errorf("tags:\n%s\nwant:\n%s", out, want)
+	}
+	out = mustRun(t, s, "tags", "https://example.com/posts/first")
+	if want := "First post: go, to-discuss\n"; out != want {
+		t.Errorf("tags of the first post:\n%s\nwant:\n%s", out, want)
+	}
+
+	out = mustRun(t, s, "browse", "--tag", "go")
+	if !strings.Contains(out, "First post") || strings.Contains(out, "Second post") {
+		t.Errorf("browse --tag go:\n%s", out)
+	}
+	out = mustRun(t, s, "browse", "--tag", "to-discuss")
+	if !strings.Contains(out, "First post") || !strings.Contains(out, "Second post") {
+		t.Errorf("browse --tag to-discuss:\n%s", out)
+	}
+
+	mustRun(t, s, "untag", "https://example.com/posts/first", "go")
+	if out := mustRun(t, s, "browse", "--tag", "go"); strings.Contains(out, "First post") {
+		t.Errorf("browse --tag go after untag:\n%s", out)
+	}
+
+	// Tags are private to their user.
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "follow", server.feedURL("example.rss"))
+	if out := mustRun(t, s, "tags"); !strings.Contains(out, "No tags") {
+		t.Errorf("bob sees the tags of alice:\n%s", out)
+	}
+
+	_, err := runCommand(t, s, "tag", "https://example.com/posts/first", " ")
+	assertKind(t, err, errValidation)
+	_, err = runCommand(t, s, "tag", "https://example.com/posts/third", "go")
+	assertKind(t, err, errNotFound)
+	_, err = runCommand(t, s, "untag", "https://example.com/posts/first", "go")
+	assertKind(t, err, errNotFound)
+	_, err = runCommand(t, s, "tags", "https://example.com/posts/third")
+	assertKind(t, err, errNotFound)
+
+	// Only posts of followed feeds can be tagged, but tags stay removable
+	// after unfollowing.
+	mustRun(t, s, "tag", "https://example.com/posts/second", "later")
+	mustRun(t, s, "unfollow", server.feedURL("example.rss"))
+	_, err = runCommand(t, s, "tag", "https://example.com/posts/first", "go")
+	assertKind(t, err, errNotFound)
+	_, err = runCommand(t, s, "tag", fmt.Sprint(first.Seq), "go")
+	assertKind(t, err, errNotFound)
+	_, err = runCommand(t, s, "tags", "https://example.com/posts/second")
+	assertKind(t, err, errNotFound)
+	mustRun(t, s, "untag", "https://example.com/posts/second", "later")
+	if out := mustRun(t, s, "tags"); !strings.Contains(out, "No tags") {
+		t.Errorf("bob's tags after untagging:\n%s", out)
+	}
+}
@@ -0,0 +1,446 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/alifoo/blog-aggregator/internal/htmltext"
+	"github.com/google/uuid"
+	"golang.org/x/term"
+)
+
+const tuiMaxPosts = 500
+
+type tuiPane int
+
+const (
+	paneFeeds tuiPane = iota
+	panePosts
+	paneBody
+)
+
+type tui struct {
+	s    *state
+	user database.User
+	out  io.Writer
+
+	feeds []database.GetFeedFollowsForUserRow
+	posts []database.GetReaderItemsRow
+	body  []string
+
+	focus      tuiPane
+	feedIndex  int
+	postIndex  int
+	bodyOffset int
+	unreadOnly bool
+	status     string
+	width      int
+	height     int
+}
+
+func handlerTUI(s *state, cmd command, user database.User) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return validationError("the tui command needs an interactive terminal")
+	}
+
+	t := &tui{s: s, user: user, out: os.Stdout, unreadOnly: cmd.boolFlag("unread")}
+	if err := t.loadFeeds(); err != nil {
+		return err
+	}
+	if err := t.loadPosts(); err != nil {
+		return err
+	}
+
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("error switching terminal to raw mode: %w", err)
+	}
+	// Alternate screen buffer and hidden cursor, restored on the way out.
+	fmt.Fprint(t.out, "\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Fprint(t.out, "\x1b[?25h\x1b[?1049l")
+		term.Restore(fd, oldState)
+	}()
+
+	buf := make([]byte, 16)
+	for {
+		t.width, t.height, err = term.GetSize(int(os.Stdout.Fd()))
+		if err != nil {
+			t.width, t.height = 80, 24
+		}
+		t.draw()
+
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return err
+		}
+		if quit := t.handleKey(string(buf[:n])); quit {
+			return nil
+		}
+	}
+}
+
+func (t *tui) loadFeeds() error {
+	feeds, err := t.s.db.GetFeedFollowsForUser(context.Background(), t.user.ID)
+	if err != nil {
+		return databaseError("error getting feed follows for current user: %w", err)
+	}
+	t.feeds = feeds
+	return nil
+}
+
+func (t *tui) loadPosts() error {
+	params := database.GetReaderItemsParams{
+		UserID:      t.user.ID,
+		ExcludeRead: t.unreadOnly,
+		MaxItems:    tuiMaxPosts,
+	}
+	// Index 0 of the feeds pane is the combined "All feeds" entry.
+	if t.feedIndex > 0 {
+		params.FeedID = uuid.NullUUID{UUID: t.feeds[t.feedIndex-1].FeedID, Valid: true}
+	}
+
+	posts, err := t.s.db.GetReaderItems(context.Background(), params)
+	if err != nil {
+		return databaseError("error getting posts: %w", err)
+	}
+	t.posts = posts
+	t.postIndex = 0
+	t.body = nil
+	t.bodyOffset = 0
+	return nil
+}
+
+func (t *tui) selectedPost() *database.GetReaderItemsRow {
+	if t.postIndex < 0 || t.postIndex >= len(t.posts) {
+		return nil
+	}
+	return &t.posts[t.postIndex]
+}
+
+// handleKey applies one key press and reports whether the user asked to quit.
+func (t *tui) handleKey(key string) bool {
+	t.status = ""
+
+	switch key {
+	case "q", "\x03":
+		return true
+	case "\t", "l", "\x1b[C":
+		if t.focus == panePosts {
+			t.openBody()
+		}
+		t.focus = min(t.focus+1, paneBody)
+	case "\x1b[Z", "h", "\x1b[D":
+		t.focus = max(t.focus-1, paneFeeds)
+	case "j", "\x1b[B":
+		t.move(1)
+	case "k", "\x1b[A":
+		t.move(-1)
+	case " ", "\x1b[6~":
+		t.move(t.height / 2)
+	case "b", "\x1b[5~":
+		t.move(-t.height / 2)
+	case "\r", "\n":
+		switch t.focus {
+		case paneFeeds:
+			t.focus = panePosts
+		case panePosts:
+			t.openBody()
+			t.focus = paneBody
+		}
+	case "r":
+		t.toggleRead()
+	case "s":
+		t.toggleStarred()
+	case "o":
+		t.openInBrowser()
+	case "u":
+		t.unreadOnly = !t.unreadOnly
+		t.reload()
+	case "R":
+		if err := t.loadFeeds(); err != nil {
+			t.status = err.Error()
+			return false
+		}
+		t.feedIndex = min(t.feedIndex, len(t.feeds))
+		t.reload()
+	}
+
+	return false
+}
+
+func (t *tui) move(delta int) {
+	switch t.focus {
+	case paneFeeds:
+		next := clamp(t.feedIndex+delta, 0, len(t.feeds))
+		if next != t.feedIndex {
+			t.feedIndex = next
+			t.reload()
+		}
+	case panePosts:
+		next := clamp(t.postIndex+delta, 0, len(t.posts)-1)
+		if next != t.postIndex {
+			t.postIndex = next
+			t.body = nil
+		}
+	case paneBody:
+		t.bodyOffset = clamp(t.bodyOffset+delta, 0, len(t.body)-1)
+	}
+}
+
+func (t *tui) reload() {
+	if err := t.loadPosts(); err != nil {
+		t.status = err.Error()
+	}
+}
+
+// openBody renders the selected post into the body pane and marks it read,
+// like opening a message in a mail client.
+func (t *tui) openBody() {
+	post := t.selectedPost()
+	if post == nil {
+		return
+	}
+
+	text := post.Title + "\n" + post.Url + "\n" + post.PublishedAt.Format("Mon, 02 Jan 2006 15:04") + "\n\n"
+	text += htmltext.Render(postBody(*post))
+	t.body = wrapText(text, t.bodyWidth())
+	t.bodyOffset = 0
+
+	if !post.Read {
+		t.setRead(post, true)
+	}
+}
+
+func (t *tui) toggleRead() {
+	if post := t.selectedPost(); post != nil {
+		t.setRead(post, !post.Read)
+	}
+}
+
+func (t *tui) setRead(post *database.GetReaderItemsRow, read bool) {
+	params := database.SetPostReadParams{UserID: t.user.ID, PostID: post.ID, Read: read}
+	if err := t.s.db.SetPostRead(context.Background(), params); err != nil {
+		t.status = fmt.Sprintf("error updating post: %v", err)
+		return
+	}
+	post.Read = read
+}
+
+func (t *tui) toggleStarred() {
+	post := t.selectedPost()
+	if post == nil {
+		return
+	}
+
+	params := database.SetPostStarredParams{UserID: t.user.ID, PostID: post.ID, Starred: !post.Starred}
+	if err := t.s.db.SetPostStarred(context.Background(), params); err != nil {
+		t.status = fmt.Sprintf("error updating post: %v", err)
+		return
+	}
+	post.Starred = !post.Starred
+}
+
+func (t *tui) openInBrowser() {
+	post := t.selectedPost()
+	if post == nil {
+		return
+	}
+	if err := openBrowser(post.Url); err != nil {
+		t.status = fmt.Sprintf("error opening browser: %v", err)
+		return
+	}
+	t.status = "Opened " + post.Url
+}
+
+// openBrowser launches $BROWSER, falling back to the platform's default
+// URL handler. Links come from feeds, so only absolute http and https urls
+// are passed on: anything else could name a local file, a script or an
+// option of the launcher.
+func openBrowser(link string) error {
+	u, err := url.Parse(link)
+	if err != nil || !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return validationError("refusing to open %q, expected an http or https url", link)
+	}
+
+	var cmd *exec.Cmd
+	if browser := os.Getenv("BROWSER"); browser != "" {
+		cmd = exec.Command(browser, link)
+	} else {
+		switch runtime.GOOS {
+		case "darwin":
+			cmd = exec.Command("open", link)
+		case "windows":
+			cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", link)
+		default:
+			cmd = exec.Command("xdg-open", link)
+		}
+	}
+	return cmd.Start()
+}
+
+func (t *tui) feedsWidth() int {
+	return max(t.width/5, 12)
+}
+
+func (t *tui) postsWidth() int {
+	return max(t.width*2/5, 20)
+}
+
+func (t *tui) bodyWidth() int {
+	return max(t.width-t.feedsWidth()-t.postsWidth()-2, 10)
+}
+
+func (t *tui) draw() {
+	rows := max(t.height-1, 1)
+
+	feedLines := []string{"All feeds"}
+	for _, f := range t.feeds {
+		feedLines = append(feedLines, f.FeedName)
+	}
+
+	var postLines []string
+	for _, p := range t.posts {
+		marker := " "
+		if !p.Read {
+			marker = "●"
+		}
+		if p.Starred {
+			marker = "★"
+		}
+		postLines = append(postLines, fmt.Sprintf("%v [%d] %v", marker, p.Seq, p.Title))
+	}
+
+	if t.body == nil && t.focus != paneBody {
+		if post := t.selectedPost(); post != nil {
+			t.body = wrapText(post.Title+"\n\n"+htmltext.Render(postBody(*post)), t.bodyWidth())
+		}
+	}
+
+	feedsCol := paneColumn(feedLines, t.feedIndex, rows, t.feedsWidth(), t.focus == paneFeeds)
+	postsCol := paneColumn(postLines, t.postIndex, rows, t.postsWidth(), t.focus == panePosts)
+
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	for i := 0; i < rows; i++ {
+		bodyLine := ""
+		if i+t.bodyOffset < len(t.body) {
+			bodyLine = t.body[i+t.bodyOffset]
+		}
+		b.WriteString(feedsCol[i])
+		b.WriteString("│")
+		b.WriteString(postsCol[i])
+		b.WriteString("│")
+		b.WriteString(fit(bodyLine, t.bodyWidth()))
+		b.WriteString("\r\n")
+	}
+
+	filter := "all posts"
+	if t.unreadOnly {
+		filter = "unread only"
+	}
+	help := fmt.Sprintf("[%s] tab/h/l panes  j/k move  enter open  r read  s star  o browser  u unread  R reload  q quit", filter)
+	if t.status != "" {
+		help = t.status
+	}
+	b.WriteString("\x1b[7m")
+	b.WriteString(fit(help, t.width))
+	b.WriteString("\x1b[0m")
+
+	fmt.Fprint(t.out, b.String())
+}
+
+// paneColumn renders a scrolled list, keeping the selected line visible and
+// highlighting it when the pane has focus.
+func paneColumn(lines []string, selected, rows, width int, focused bool) []string {
+	top := 0
+	if selected >= rows {
+		top = selected - rows + 1
+	}
+
+	col := make([]string, rows)
+	for i := range col {
+		idx := top + i
+		if idx >= len(lines) {
+			col[i] = strings.Repeat(" ", width)
+			continue
+		}
+		line := fit(lines[idx], width)
+		if idx == selected {
+			if focused {
+				line = "\x1b[7m" + line + "\x1b[0m"
+			} else {
+				line = "\x1b[1m" + line + "\x1b[0m"
+			}
+		}
+		col[i] = line
+	}
+	return col
+}
+
+// fit truncates or pads s to exactly width runes, on a single line.
+func fit(s string, width int) string {
+	s = strings.NewReplacer("\t", " ", "\n", " ").Replace(htmltext.StripControl(s))
+	n := utf8.RuneCountInString(s)
+	if n > width {
+		runes := []rune(s)
+		if width > 1 {
+			return string(runes[:width-1]) + "…"
+		}
+		return string(runes[:width])
+	}
+	return s + strings.Repeat(" ", width-n)
+}
+
+// wrapText breaks text into lines of at most width runes on word boundaries,
+// keeping existing line breaks.
+func wrapText(text string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(htmltext.StripControl(text), "\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+
+		line := ""
+		for _, word := range words {
+			for utf8.RuneCountInString(word) > width {
+				runes := []rune(word)
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				lines = append(lines, string(runes[:width]))
+				word = string(runes[width:])
+			}
+			switch {
+			case line == "":
+				line = word
+			case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func clamp(v, lo, hi int) int {
+	if hi < lo {
+		return lo
+	}
+	return max(lo, min(v, hi))
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+// TestTUIStripsEscapes checks that escape sequences in posts stored before
+// fetches dropped them never reach the terminal.
+func TestTUIStripsEscapes(t *testing.T) {
+	s := newTestState(t)
+	ctx := context.Background()
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Escapes", "https://escapes.example.com/rss")
+	feed, err := s.db.GetFeedByURL(ctx, "https://escapes.example.com/rss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	_, err = s.db.CreatePost(ctx, database.CreatePostParams{
+		ID:          uuid.New(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Title:       "Evil \x1b]0;pwned\a title",
+		Url:         "https://escapes.example.com/posts/evil",
+		Description: sql.NullString{String: "<p>Now \x1b[31mred\x1b[0m and \u009b2J gone.</p>", Valid: true},
+		PublishedAt: now,
+		FeedID:      uuid.NullUUID{UUID: feed.ID, Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := s.db.GetUser(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	ui := &tui{s: s, user: user, out: &out, width: 120, height: 10}
+	if err := ui.loadFeeds(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ui.loadPosts(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ui.posts) != 1 {
+		t.Fatalf("the tui lists %d posts, want 1", len(ui.posts))
+	}
+
+	ui.focus = panePosts
+	ui.handleKey("\r")
+	ui.draw()
+
+	screen := out.String()
+	// The only escape sequences are the ones the tui writes itself.
+	for _, own := range []string{"\x1b[H", "\x1b[2J", "\x1b[7m", "\x1b[1m", "\x1b[0m"} {
+		screen = strings.ReplaceAll(screen, own, "")
+	}
+	if strings.ContainsAny(screen, "\x1b\a\u009b") {
+		t.Errorf("the screen contains control characters: %q", screen)
+	}
+	for _, want := range []string{fmt.Sprintf("[%d] Evil ]0;pwned title", ui.posts[0].Seq), "Now [31mred[0m and"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("the screen does not show %q: %q", want, screen)
+		}
+	}
+}
+
+func TestOpenBrowserRejectsLinks(t *testing.T) {
+	dir := t.TempDir()
+	opened := filepath.Join(dir, "opened")
+	browser := filepath.Join(dir, "browser")
+	script := "#!/bin/sh\nprintf '%s\\n' \"$1\" >> '" + opened + "'\n"
+	if err := os.WriteFile(browser, []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BROWSER", browser)
+
+	for _, link := range []string{
+		"file:///etc/passwd",
+		"javascript:alert(1)",
+		"gator-custom://open",
+		"--new-window",
+		"-https://example.com/",
+		"/posts/first",
+		"https:///no-host",
+		"",
+	} {
+		assertKind(t, openBrowser(link), errValidation)
+	}
+
+	if err := openBrowser("https://example.com/posts/first"); err != nil {
+		t.Fatalf("openBrowser: %v", err)
+	}
+	// The browser runs in the background.
+	var data []byte
+	for i := 0; i < 100; i++ {
+		data, _ = os.ReadFile(opened)
+		if len(data) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if string(data) != "https://example.com/posts/first\n" {
+		t.Errorf("the browser opened %q", data)
+	}
+}
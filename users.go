@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/alifoo/blog-aggregator/internal/config"
+	"github.com/alifoo/blog-aggregator/internal/database"
+	"github.com/alifoo/blog-aggregator/internal/storage"
+)
+
+func handlerDeleteUser(s *state, cmd command) error {
+	plan, err := planReset(s, []string{"user", cmd.arguments[0]})
+	if err != nil {
+		return err
+	}
+	return runReset(s, cmd, plan)
+}
+
+func handlerRenameUser(s *state, cmd command) error {
+	oldName, newName := cmd.arguments[0], cmd.arguments[1]
+
+	user, err := s.db.GetUser(context.Background(), oldName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFoundError("user '%s' does not exist", oldName)
+	}
+	if err != nil {
+		return databaseError("error getting user: %w", err)
+	}
+
+	_, err = s.db.RenameUser(context.Background(), database.RenameUserParams{ID: user.ID, Name: newName})
+	if storage.IsUniqueViolation(err) {
+		return conflictError("user '%s' already exists", newName)
+	}
+	if err != nil {
+		return databaseError("error renaming user: %w", err)
+	}
+	fmt.Printf("Renamed user %v to %v.\n", oldName, newName)
+
+	if s.configPointer.CurrentUserName == oldName {
+		if err := setCurrentUser(s, newName); err != nil {
+			return err
+		}
+		fmt.Printf("You are now logged in as %v.\n", newName)
+	}
+	return nil
+}
+
+func handlerWhoami(s *state, cmd command, user database.User) error {
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return databaseError("error getting feed follows: %w", err)
+	}
+	counts, err := s.db.GetUnreadCountsForUser(context.Background(), user.ID)
+	if err != nil {
+		return databaseError("error getting unread counts: %w", err)
+	}
+	var unread int64
+	for _, c := range counts {
+		unread += c.Unread
+	}
+
+	cfg := s.configPointer
+	if s.output != outputPlain {
+		return writeRecords(os.Stdout, s.output, []whoamiRecord{{
+			ID:        user.ID,
+			Name:      user.Name,
+			Profile:   cfg.Profile(),
+			Follows:   len(follows),
+			Unread:    unread,
+			CreatedAt: user.CreatedAt,
+		}})
+	}
+
+	fmt.Println(user.Name)
+	fmt.Printf("Profile:    %v (%v)\n", cfg.Profile(), cfg.Path())
+	fmt.Printf("Registered: %v\n", user.CreatedAt.Format("2006-01-02 15:04"))
+	fmt.Printf("Following:  %v feeds\n", len(follows))
+	fmt.Printf("Unread:     %v posts\n", unread)
+	return nil
+}
+
+func handlerLogout(s *state, cmd command) error {
+	name := s.configPointer.CurrentUserName
+	if name == "" {
+		fmt.Println("No user is logged in.")
+		return nil
+	}
+	if err := setCurrentUser(s, ""); err != nil {
+		return err
+	}
+	fmt.Printf("Logged out %v.\n", name)
+	return nil
+}
+
+// forgetDeletedUser logs out the current user if they no longer exist, so
+// the config does not keep pointing at a deleted user.
+func forgetDeletedUser(s *state) error {
+	name := s.configPointer.CurrentUserName
+	if name == "" {
+		return nil
+	}
+	_, err := s.db.GetUser(context.Background(), name)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err := setCurrentUser(s, ""); err != nil {
+		return err
+	}
+	fmt.Printf("Logged out %v, who was deleted.\n", name)
+	return nil
+}
+
+// setCurrentUser saves name as the current user, warning when the
+// environment overrides the config file.
+func setCurrentUser(s *state, name string) error {
+	if err := s.configPointer.SetUser(name); err != nil {
+		return fmt.Errorf("error saving current user to config: %w", err)
+	}
+	if env := config.EnvName(config.KeyCurrentUserName); os.Getenv(env) != "" {
+		fmt.Printf("Note: %v is set and takes precedence over the config file.\n", env)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenameUser(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "register", "alice")
+
+	_, err := runCommand(t, s, "renameuser", "alice", "bob")
+	assertKind(t, err, errConflict)
+	_, err = runCommand(t, s, "renameuser", "carol", "dave")
+	assertKind(t, err, errNotFound)
+
+	mustRun(t, s, "renameuser", "alice", "alicia")
+	if s.configPointer.CurrentUserName != "alicia" {
+		t.Fatalf("current user is %q after renaming them, want alicia", s.configPointer.CurrentUserName)
+	}
+	mustRun(t, s, "renameuser", "bob", "robert")
+	if s.configPointer.CurrentUserName != "alicia" {
+		t.Fatalf("renaming another user changed the current user to %q", s.configPointer.CurrentUserName)
+	}
+
+	out := mustRun(t, s, "users")
+	if !strings.Contains(out, "alicia (current)") || !strings.Contains(out, "robert") {
+		t.Errorf("users does not show the new names:\n%s", out)
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "register", "alice")
+
+	mustRun(t, s, "deleteuser", "bob", "--yes")
+	if s.configPointer.CurrentUserName != "alice" {
+		t.Fatalf("deleting another user changed the current user to %q", s.configPointer.CurrentUserName)
+	}
+
+	out := mustRun(t, s, "deleteuser", "alice", "--yes")
+	if !strings.Contains(out, "Logged out alice") {
+		t.Errorf("deleting the current user did not log them out:\n%s", out)
+	}
+	if s.configPointer.CurrentUserName != "" {
+		t.Fatalf("current user is still %q after deleting them", s.configPointer.CurrentUserName)
+	}
+
+	_, err := runCommand(t, s, "deleteuser", "alice", "--yes")
+	assertKind(t, err, errNotFound)
+}
+
+func TestWhoamiAndLogout(t *testing.T) {
+	s := newTestState(t)
+	server := newFeedServer(t)
+
+	_, err := runCommand(t, s, "whoami")
+	assertKind(t, err, errNotFound)
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", server.feedURL("example.rss"))
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatal(err)
+	}
+
+	out := mustRun(t, s, "whoami")
+	for _, want := range []string{"alice", "Profile:    default", "Following:  1 feeds", "Unread:     2 posts"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("whoami output does not contain %q:\n%s", want, out)
+		}
+	}
+
+	mustRun(t, s, "logout")
+	if s.configPointer.CurrentUserName != "" {
+		t.Fatalf("current user is still %q after logout", s.configPointer.CurrentUserName)
+	}
+	_, err = runCommand(t, s, "whoami")
+	assertKind(t, err, errNotFound)
+
+	out = mustRun(t, s, "logout")
+	if !strings.Contains(out, "No user is logged in") {
+		t.Errorf("second logout printed %q", out)
+	}
+}